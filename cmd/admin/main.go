@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 	"tritontube/internal/proto"
 
@@ -30,17 +31,51 @@ func main() {
 
 	switch cmd {
 	case "add":
-		if len(os.Args) != 4 {
-			fmt.Println("Usage: add <server_address> <node_address>")
+		if len(os.Args) != 4 && len(os.Args) != 5 {
+			fmt.Println("Usage: add <server_address> <node_address> [weight]")
 			os.Exit(1)
 		}
-		addNode(client, os.Args[3])
+		var weight uint32
+		if len(os.Args) == 5 {
+			weight = parseWeight(os.Args[4])
+		}
+		addNode(client, os.Args[3], weight)
 	case "remove":
 		if len(os.Args) != 4 {
 			fmt.Println("Usage: remove <server_address> <node_address>")
 			os.Exit(1)
 		}
 		removeNode(client, os.Args[3])
+	case "reweight":
+		if len(os.Args) != 5 {
+			fmt.Println("Usage: reweight <server_address> <node_address> <weight>")
+			os.Exit(1)
+		}
+		reweightNode(client, os.Args[3], parseWeight(os.Args[4]))
+	case "resume":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: resume <server_address>")
+			os.Exit(1)
+		}
+		resumeMigration(client)
+	case "abandon":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: abandon <server_address>")
+			os.Exit(1)
+		}
+		abandonMigration(client)
+	case "delete":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: delete <server_address> <video_id>")
+			os.Exit(1)
+		}
+		deleteVideo(client, os.Args[3])
+	case "scrub-status":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: scrub-status <storage_address>")
+			os.Exit(1)
+		}
+		scrubStatus(proto.NewVideoContentStorageServiceClient(conn))
 	case "list":
 		if len(os.Args) != 3 {
 			fmt.Println("Usage: list <server_address>")
@@ -59,16 +94,31 @@ func durationMilliseconds(duration time.Duration) float64 {
 
 func printUsageAndExit() {
 	fmt.Println("Usage:")
-	fmt.Println("  add <server_address> <node_address>     - Add a node to the cluster")
-	fmt.Println("  remove <server_address> <node_address>  - Remove a node from the cluster")
-	fmt.Println("  list <server_address>                   - List all nodes in the cluster")
+	fmt.Println("  add <server_address> <node_address> [weight]       - Add a node to the cluster")
+	fmt.Println("  remove <server_address> <node_address>             - Remove a node from the cluster")
+	fmt.Println("  reweight <server_address> <node_address> <weight>  - Change a node's share of the ring")
+	fmt.Println("  resume <server_address>                            - Finish a node change that failed part way")
+	fmt.Println("  abandon <server_address>                           - Give up a failed node change and delete its copies")
+	fmt.Println("  delete <server_address> <video_id>                 - Delete a video and all of its files")
+	fmt.Println("  list <server_address>                              - List all nodes in the cluster")
+	fmt.Println("  scrub-status <storage_address>                     - Show a storage node's scrubber results")
 	os.Exit(1)
 }
 
-func addNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
+func parseWeight(value string) uint32 {
+	weight, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || weight == 0 {
+		fmt.Printf("Invalid weight %q: must be a positive integer\n", value)
+		os.Exit(1)
+	}
+	return uint32(weight)
+}
+
+func addNode(client proto.VideoContentAdminServiceClient, nodeAddr string, weight uint32) {
 	start := time.Now()
 	response, err := client.AddNode(context.Background(), &proto.AddNodeRequest{
 		NodeAddress: nodeAddr,
+		Weight:      weight,
 	})
 	if err != nil {
 		log.Fatalf("AddNode RPC failed: %v", err)
@@ -92,6 +142,51 @@ func removeNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
 	fmt.Printf("Number of files migrated: %d\n", response.MigratedFileCount)
 }
 
+func reweightNode(client proto.VideoContentAdminServiceClient, nodeAddr string, weight uint32) {
+	response, err := client.ReweightNode(context.Background(), &proto.ReweightNodeRequest{
+		NodeAddress: nodeAddr,
+		Weight:      weight,
+	})
+	if err != nil {
+		log.Fatalf("ReweightNode RPC failed: %v", err)
+	}
+
+	fmt.Printf("Successfully reweighted node %s to %d\n", nodeAddr, weight)
+	fmt.Printf("Number of files migrated: %d\n", response.MigratedFileCount)
+}
+
+func resumeMigration(client proto.VideoContentAdminServiceClient) {
+	response, err := client.ResumeMigration(context.Background(), &proto.ResumeMigrationRequest{})
+	if err != nil {
+		log.Fatalf("ResumeMigration RPC failed, run the command again to continue: %v", err)
+	}
+
+	fmt.Printf("Successfully finished migration to %s node: %s\n", response.Operation, response.NodeAddress)
+	fmt.Printf("Number of files migrated: %d\n", response.MigratedFileCount)
+}
+
+func abandonMigration(client proto.VideoContentAdminServiceClient) {
+	response, err := client.AbandonMigration(context.Background(), &proto.AbandonMigrationRequest{})
+	if err != nil {
+		log.Fatalf("AbandonMigration RPC failed, run the command again to finish: %v", err)
+	}
+
+	fmt.Printf("Abandoned migration to %s node: %s\n", response.Operation, response.NodeAddress)
+	fmt.Printf("Number of copies deleted: %d\n", response.DeletedFileCount)
+}
+
+func deleteVideo(client proto.VideoContentAdminServiceClient, videoID string) {
+	response, err := client.DeleteVideo(context.Background(), &proto.DeleteVideoRequest{
+		VideoId: videoID,
+	})
+	if err != nil {
+		log.Fatalf("DeleteVideo RPC failed, run the command again to finish: %v", err)
+	}
+
+	fmt.Printf("Successfully deleted video: %s\n", videoID)
+	fmt.Printf("Number of files deleted: %d\n", response.DeletedFileCount)
+}
+
 func listNodes(client proto.VideoContentAdminServiceClient) {
 	response, err := client.ListNodes(context.Background(), &proto.ListNodesRequest{})
 	if err != nil {
@@ -103,7 +198,40 @@ func listNodes(client proto.VideoContentAdminServiceClient) {
 		fmt.Println("  No nodes in cluster")
 	} else {
 		for _, node := range response.Nodes {
-			fmt.Printf("  - %s\n", node)
+			fmt.Printf("  - %s (weight %d)\n", node, max(response.Weights[node], 1))
+		}
+	}
+}
+
+func scrubStatus(client proto.VideoContentStorageServiceClient) {
+	response, err := client.ScrubStatus(context.Background(), &proto.ScrubStatusRequest{})
+	if err != nil {
+		log.Fatalf("ScrubStatus RPC failed: %v", err)
+	}
+
+	switch {
+	case response.Running:
+		fmt.Printf("Scrub running since %s\n", formatUnix(response.LastPassStarted))
+	case response.LastPassFinished == 0:
+		fmt.Println("No scrub has finished yet")
+	}
+	if response.LastPassFinished != 0 {
+		fmt.Printf("Last scrub finished %s: %d files checked, %d checksums recorded, %d files quarantined\n",
+			formatUnix(response.LastPassFinished), response.FilesChecked, response.ChecksumsRecorded, response.FilesQuarantined)
+		if response.LastError != "" {
+			fmt.Printf("Last scrub stopped early: %s\n", response.LastError)
 		}
 	}
+
+	fmt.Println("Files needing repair:")
+	if len(response.Quarantined) == 0 {
+		fmt.Println("  None")
+	}
+	for _, file := range response.Quarantined {
+		fmt.Printf("  - %s/%s (quarantined %s)\n", file.VideoId, file.Filename, formatUnix(file.QuarantinedAt))
+	}
+}
+
+func formatUnix(seconds int64) string {
+	return time.Unix(seconds, 0).Format(time.RFC3339)
 }
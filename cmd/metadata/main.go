@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"tritontube/internal/web"
+)
+
+type metadataStore interface {
+	web.VideoMetadataService
+	io.Closer
+}
+
+var etcdPrefix = flag.String("etcd-prefix", web.DefaultEtcdVideoPrefix, "etcd key prefix of video metadata records")
+
+func main() {
+	flag.Usage = printUsageAndExit
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 {
+		printUsageAndExit()
+	}
+
+	switch args[0] {
+	case "copy":
+		if len(args) != 5 {
+			fmt.Println("Usage: copy <from_type> <from_options> <to_type> <to_options>")
+			os.Exit(1)
+		}
+		copyMetadata(args[1], args[2], args[3], args[4])
+	case "migrate-prefix":
+		if len(args) != 2 {
+			fmt.Println("Usage: migrate-prefix <etcd_endpoints>")
+			os.Exit(1)
+		}
+		migratePrefix(args[1])
+	default:
+		fmt.Printf("Unknown command: %s\n", args[0])
+		printUsageAndExit()
+	}
+}
+
+func printUsageAndExit() {
+	fmt.Println("Usage: metadata [OPTIONS] COMMAND")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  copy <from_type> <from_options> <to_type> <to_options>  - Copy all video and job records between stores")
+	fmt.Println("  migrate-prefix <etcd_endpoints>                         - Move video records stored without a prefix under -etcd-prefix")
+	fmt.Println()
+	fmt.Println("Store types:")
+	fmt.Println("  etcd <endpoints>  - Comma-separated etcd endpoints, e.g. localhost:2379")
+	fmt.Println("  bolt <path>       - Path of a bbolt database file, created if missing")
+	fmt.Println()
+	fmt.Println("Options:")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func openStore(storeType, options string) (metadataStore, error) {
+	switch storeType {
+	case "etcd":
+		return web.NewEtcdVideoMetadataService(strings.Split(options, ","), web.WithEtcdVideoPrefix(*etcdPrefix))
+	case "bolt":
+		return web.NewBoltVideoMetadataService(options)
+	default:
+		return nil, fmt.Errorf("unknown metadata store type %q; supported: etcd, bolt", storeType)
+	}
+}
+
+func copyMetadata(fromType, fromOptions, toType, toOptions string) {
+	if fromType == toType && fromOptions == toOptions {
+		log.Fatalf("Source and destination are the same %s store", fromType)
+	}
+
+	source, err := openStore(fromType, fromOptions)
+	if err != nil {
+		log.Fatalf("Failed to open source: %v", err)
+	}
+	defer source.Close()
+
+	// Records stored without a prefix are not listed, so copying would drop
+	// them.
+	if etcdSource, ok := source.(*web.EtcdVideoMetadataService); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		unprefixed, err := etcdSource.UnprefixedVideoIDs(ctx)
+		cancel()
+		if err != nil {
+			source.Close()
+			log.Fatalf("Failed to check the source for records without a prefix: %v", err)
+		}
+		if len(unprefixed) > 0 {
+			source.Close()
+			log.Fatalf("Source holds %d video records without a prefix, e.g. %s; run migrate-prefix on it first",
+				len(unprefixed), unprefixed[0])
+		}
+	}
+
+	destination, err := openStore(toType, toOptions)
+	if err != nil {
+		source.Close()
+		log.Fatalf("Failed to open destination: %v", err)
+	}
+	defer destination.Close()
+
+	videos, jobs, err := web.CopyMetadata(destination, source)
+	if err != nil {
+		destination.Close()
+		source.Close()
+		log.Fatalf("Copy failed after %d videos and %d jobs, run the command again to finish: %v", videos, jobs, err)
+	}
+
+	fmt.Printf("Successfully copied metadata from %s to %s\n", fromType, toType)
+	fmt.Printf("Number of videos copied: %d\n", videos)
+	fmt.Printf("Number of jobs copied: %d\n", jobs)
+}
+
+func migratePrefix(endpoints string) {
+	store, err := web.NewEtcdVideoMetadataService(strings.Split(endpoints, ","), web.WithEtcdVideoPrefix(*etcdPrefix))
+	if err != nil {
+		log.Fatalf("Failed to open etcd: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	moved, err := store.MigrateUnprefixedKeys(ctx)
+	if err != nil {
+		store.Close()
+		log.Fatalf("Migration failed after moving %d records, run the command again to finish: %v", moved, err)
+	}
+
+	fmt.Printf("Successfully moved video records under %s\n", *etcdPrefix)
+	fmt.Printf("Number of records moved: %d\n", moved)
+}
@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 	"tritontube/internal/proto"
 	"tritontube/internal/storage"
 
@@ -26,11 +27,16 @@ func main() {
 func run() error {
 	host := flag.String("host", "localhost", "Host address for the server")
 	port := flag.Int("port", 8090, "Port number for the server")
+	scrubInterval := flag.Duration("scrub-interval", 24*time.Hour, "Time between scrubber passes over stored files, 0 disables the scrubber")
+	scrubRate := flag.Int64("scrub-rate", 8<<20, "Maximum bytes per second the scrubber reads, 0 for no limit")
 	flag.Parse()
 
 	if *port <= 0 {
 		return errors.New("port number must be positive")
 	}
+	if *scrubInterval < 0 || *scrubRate < 0 {
+		return errors.New("scrub interval and rate must not be negative")
+	}
 	if flag.NArg() < 1 {
 		return errors.New("usage: storage [OPTIONS] <baseDir>: base directory is required")
 	}
@@ -40,6 +46,11 @@ func run() error {
 	fmt.Printf("Host: %s\n", *host)
 	fmt.Printf("Port: %d\n", *port)
 	fmt.Printf("Base Directory: %s\n", baseDir)
+	if *scrubInterval > 0 {
+		fmt.Printf("Scrub Interval: %s\n", *scrubInterval)
+	} else {
+		fmt.Println("Scrubber: disabled")
+	}
 
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(proto.MaxMessageSize),
@@ -63,6 +74,10 @@ func run() error {
 	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stopSignals()
 
+	if *scrubInterval > 0 {
+		go server.RunScrubber(signalCtx, *scrubInterval, *scrubRate)
+	}
+
 	go func() {
 		<-signalCtx.Done()
 		fmt.Println("Stopping storage server...")
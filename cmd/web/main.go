@@ -11,6 +11,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 	"tritontube/internal/proto"
 	"tritontube/internal/web"
 
@@ -22,15 +23,15 @@ func printUsage() {
 	fmt.Println("Usage: ./program [OPTIONS] METADATA_TYPE METADATA_OPTIONS CONTENT_TYPE CONTENT_OPTIONS")
 	fmt.Println()
 	fmt.Println("Arguments:")
-	fmt.Println("  METADATA_TYPE         Metadata service type (etcd)")
-	fmt.Println("  METADATA_OPTIONS      Options for metadata service (e.g., db path)")
+	fmt.Println("  METADATA_TYPE         Metadata service type (etcd, bolt)")
+	fmt.Println("  METADATA_OPTIONS      Options for metadata service (e.g., etcd endpoints, db path)")
 	fmt.Println("  CONTENT_TYPE          Content service type (fs, nw)")
 	fmt.Println("  CONTENT_OPTIONS       Options for content service (e.g., base dir, network addresses)")
 	fmt.Println()
 	fmt.Println("Options:")
 	flag.PrintDefaults()
 	fmt.Println()
-	fmt.Println("Example: ./program etcd db.db fs /path/to/videos")
+	fmt.Println("Example: ./program bolt tritontube.db fs /path/to/videos")
 }
 
 func main() {
@@ -43,6 +44,13 @@ func main() {
 func run() error {
 	port := flag.Int("port", 8080, "Port number for the web server")
 	host := flag.String("host", "localhost", "Host address for the web server")
+	replicas := flag.Int("replicas", 1, "Number of storage nodes that hold a copy of each file")
+	vnodes := flag.Int("vnodes", 1, "Number of virtual ring tokens per storage node")
+	cleanupGrace := flag.Duration("cleanup-grace", 5*time.Minute, "How long nodes keep files they no longer own after a ring change; negative keeps them")
+	transcodeWorkers := flag.Int("transcode-workers", 2, "Number of uploads transcoded at the same time")
+	etcdPrefix := flag.String("etcd-prefix", web.DefaultEtcdVideoPrefix, "etcd key prefix of video metadata records")
+	instance := flag.String("instance", "", "Name of this web instance, unique among instances sharing metadata and kept across restarts (default HOST:PORT)")
+	ladder := flag.String("ladder", "", "Rendition ladder as HEIGHT:KBPS pairs, e.g. 1080:5000,720:3000 (default 1080:5000,720:3000,480:1500,240:400)")
 
 	flag.Usage = printUsage
 
@@ -60,29 +68,78 @@ func run() error {
 	if *port <= 0 {
 		return fmt.Errorf("invalid port number: %d", *port)
 	}
+	if *replicas <= 0 {
+		return fmt.Errorf("invalid replication factor: %d", *replicas)
+	}
+	if *vnodes <= 0 {
+		return fmt.Errorf("invalid virtual node count: %d", *vnodes)
+	}
+	if *transcodeWorkers <= 0 {
+		return fmt.Errorf("invalid transcode worker count: %d", *transcodeWorkers)
+	}
+	if *instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("name this web instance: %w", err)
+		}
+		*instance = fmt.Sprintf("%s:%d", hostname, *port)
+	}
+	serverOptions := []web.ServerOption{
+		web.WithTranscodeWorkers(*transcodeWorkers),
+		web.WithInstanceName(*instance),
+	}
+	if *ladder != "" {
+		renditions, err := web.ParseRenditionLadder(*ladder)
+		if err != nil {
+			return fmt.Errorf("invalid rendition ladder: %w", err)
+		}
+		serverOptions = append(serverOptions, web.WithRenditionLadder(renditions))
+	}
 	var err error
 
 	var metadataService web.VideoMetadataService
+	var membershipStore web.MembershipStore
+	var migrationStore web.MigrationStore
 	fmt.Println("Creating metadata service of type", metadataServiceType, "with options", metadataServiceOptions)
 	switch metadataServiceType {
 	case "etcd":
 		nodes := strings.Split(metadataServiceOptions, ",")
-		etcdService, createErr := web.NewEtcdVideoMetadataService(nodes)
+		etcdService, createErr := web.NewEtcdVideoMetadataService(nodes, web.WithEtcdVideoPrefix(*etcdPrefix))
 
 		if createErr != nil {
 			return fmt.Errorf("create metadata service: %w", createErr)
 		}
 		defer etcdService.Close()
+
 		metadataService = etcdService
+		ringStore := web.NewEtcdMembershipStore(etcdService.Client())
+		membershipStore = ringStore
+		migrationStore = ringStore
+
+	case "bolt":
+		boltService, createErr := web.NewBoltVideoMetadataService(metadataServiceOptions)
+		if createErr != nil {
+			return fmt.Errorf("create metadata service: %w", createErr)
+		}
+		defer boltService.Close()
+		metadataService = boltService
 
 	default:
-		return fmt.Errorf("unknown metadata service type %q; supported: etcd", metadataServiceType)
+		return fmt.Errorf("unknown metadata service type %q; supported: etcd, bolt", metadataServiceType)
 	}
 
 	var contentService web.VideoContentService
-	var grpcServer *grpc.Server
+	var networkService *web.NetworkVideoContentService
+	var adminAddr string
 	fmt.Println("Creating content service of type", contentServiceType, "with options", contentServiceOptions)
 	switch contentServiceType {
+	case "fs":
+		fsService, createErr := web.NewFSVideoContentService(contentServiceOptions)
+		if createErr != nil {
+			return fmt.Errorf("create content service: %w", createErr)
+		}
+		contentService = fsService
+
 	case "nw":
 		nodes := strings.Split(contentServiceOptions, ",")
 
@@ -90,29 +147,79 @@ func run() error {
 			return errors.New("content options require one admin address and at least one storage node")
 		}
 
-		contentService = web.NewNetworkVideoContentService(nodes[1:])
+		options := []web.NetworkOption{
+			web.WithReplicationFactor(*replicas),
+			web.WithVirtualNodes(*vnodes),
+			web.WithCleanupGracePeriod(*cleanupGrace),
+		}
+		if membershipStore != nil {
+			options = append(options, web.WithMembershipStore(membershipStore))
+		}
+		if migrationStore != nil {
+			options = append(options, web.WithMigrationStore(migrationStore))
+		}
+		adminAddr = nodes[0]
+		networkService = web.NewNetworkVideoContentService(nodes[1:], options...)
+		defer networkService.Close()
+
+		// Storage nodes on the command line only seed the ring on first start;
+		// afterwards the stored membership wins and admin changes persist.
+		loadCtx, cancelLoad := context.WithTimeout(context.Background(), 10*time.Second)
+		err := networkService.LoadMembership(loadCtx)
+		if err != nil {
+			cancelLoad()
+			return fmt.Errorf("load storage ring: %w", err)
+		}
+		// Cleanups left by ring changes survive restarts of the instance
+		// that scheduled them.
+		resumed, err := networkService.ResumeCleanups(loadCtx)
+		cancelLoad()
+		if err != nil {
+			return fmt.Errorf("resume ring change cleanups: %w", err)
+		}
+		if resumed > 0 {
+			fmt.Printf("Scheduled %d pending ring change cleanups\n", resumed)
+		}
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go networkService.WatchMembership(watchCtx)
+		contentService = networkService
 
-		grpcServer = grpc.NewServer()
-		proto.RegisterVideoContentAdminServiceServer(grpcServer, contentService.(*web.NetworkVideoContentService))
+	default:
+		return fmt.Errorf("unknown content service type %q; supported: fs, nw", contentServiceType)
+	}
+
+	server := web.NewServer(metadataService, contentService, serverOptions...)
 
-		lis, err := net.Listen("tcp", nodes[0])
+	// Jobs this instance was running when it last stopped will never finish.
+	recovered, err := server.RecoverJobs()
+	if err != nil {
+		return fmt.Errorf("recover transcode jobs: %w", err)
+	}
+	if recovered > 0 {
+		fmt.Printf("Marked %d unfinished transcode jobs of %s as failed\n", recovered, *instance)
+	}
+
+	// The admin service deletes videos through the web server, so it is
+	// started once the server exists. It manages the storage ring, so a
+	// single-host fs setup has none.
+	var grpcServer *grpc.Server
+	if networkService != nil {
+		grpcServer = grpc.NewServer()
+		proto.RegisterVideoContentAdminServiceServer(grpcServer, web.NewAdminService(networkService, server))
+		adminLis, err := net.Listen("tcp", adminAddr)
 		if err != nil {
-			return fmt.Errorf("listen for admin gRPC on %s: %w", nodes[0], err)
+			return fmt.Errorf("listen for admin gRPC on %s: %w", adminAddr, err)
 		}
-		defer lis.Close()
-		fmt.Printf("Admin server %s is running...\n", nodes[0])
+		defer adminLis.Close()
+		fmt.Printf("Admin server %s is running...\n", adminAddr)
 
 		go func() {
-			if err := grpcServer.Serve(lis); err != nil {
+			if err := grpcServer.Serve(adminLis); err != nil {
 				fmt.Fprintln(os.Stderr, "admin gRPC server:", err)
 			}
 		}()
-
-	default:
-		return fmt.Errorf("unknown content service type %q; supported: nw", contentServiceType)
 	}
-
-	server := web.NewServer(metadataService, contentService)
 	listenAddr := fmt.Sprintf("%s:%d", *host, *port)
 	lis, err := net.Listen("tcp", listenAddr)
 	if err != nil {
@@ -133,7 +240,9 @@ func run() error {
 			fmt.Fprintln(os.Stderr, "HTTP shutdown:", err)
 		}
 
-		grpcServer.GracefulStop()
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
 	}()
 
 	fmt.Println("Starting web server on", listenAddr)
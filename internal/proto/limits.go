@@ -3,3 +3,7 @@ package proto
 // MaxMessageSize permits individual DASH segments larger than gRPC's 4 MiB
 // default while keeping a bounded limit for single-file transfers.
 const MaxMessageSize = 16 * 1024 * 1024
+
+// StreamChunkSize bounds the data carried by one FileChunk in the streaming
+// file RPCs.
+const StreamChunkSize = 1024 * 1024
@@ -22,10 +22,13 @@ const (
 )
 
 type WriteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	VideoId       string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	VideoId  string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Data     []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// SHA-256 of data computed by the sender. The storage node rejects data
+	// that does not match and keeps the checksum to verify later reads.
+	Sha256        []byte `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,6 +84,13 @@ func (x *WriteRequest) GetData() []byte {
 	return nil
 }
 
+func (x *WriteRequest) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
 type WriteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -118,10 +128,15 @@ func (*WriteResponse) Descriptor() ([]byte, []int) {
 }
 
 type FileEntry struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	VideoId       string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	VideoId  string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Data     []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// Size is set by ListFiles so callers can stream large files.
+	Size int64 `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	// SHA-256 of data. Writes are verified against it and reads return the
+	// stored checksum; it is empty for files stored before checksums existed.
+	Sha256        []byte `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -177,6 +192,97 @@ func (x *FileEntry) GetData() []byte {
 	return nil
 }
 
+func (x *FileEntry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileEntry) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+type FileChunk struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	VideoId  string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Data     []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Size     int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	// SHA-256 of the whole file, carried by the first chunk.
+	Sha256        []byte `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_proto_storage_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FileChunk) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
+func (x *FileChunk) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *FileChunk) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileChunk) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
 type BatchWriteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Entries       []*FileEntry           `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
@@ -186,7 +292,7 @@ type BatchWriteRequest struct {
 
 func (x *BatchWriteRequest) Reset() {
 	*x = BatchWriteRequest{}
-	mi := &file_proto_storage_proto_msgTypes[3]
+	mi := &file_proto_storage_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -198,7 +304,7 @@ func (x *BatchWriteRequest) String() string {
 func (*BatchWriteRequest) ProtoMessage() {}
 
 func (x *BatchWriteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[3]
+	mi := &file_proto_storage_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -211,7 +317,7 @@ func (x *BatchWriteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchWriteRequest.ProtoReflect.Descriptor instead.
 func (*BatchWriteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{3}
+	return file_proto_storage_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *BatchWriteRequest) GetEntries() []*FileEntry {
@@ -230,7 +336,7 @@ type BatchWriteResponse struct {
 
 func (x *BatchWriteResponse) Reset() {
 	*x = BatchWriteResponse{}
-	mi := &file_proto_storage_proto_msgTypes[4]
+	mi := &file_proto_storage_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -242,7 +348,7 @@ func (x *BatchWriteResponse) String() string {
 func (*BatchWriteResponse) ProtoMessage() {}
 
 func (x *BatchWriteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[4]
+	mi := &file_proto_storage_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -255,7 +361,7 @@ func (x *BatchWriteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchWriteResponse.ProtoReflect.Descriptor instead.
 func (*BatchWriteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{4}
+	return file_proto_storage_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *BatchWriteResponse) GetCnt() uint32 {
@@ -275,7 +381,7 @@ type ReadRequest struct {
 
 func (x *ReadRequest) Reset() {
 	*x = ReadRequest{}
-	mi := &file_proto_storage_proto_msgTypes[5]
+	mi := &file_proto_storage_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -287,7 +393,7 @@ func (x *ReadRequest) String() string {
 func (*ReadRequest) ProtoMessage() {}
 
 func (x *ReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[5]
+	mi := &file_proto_storage_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -300,7 +406,7 @@ func (x *ReadRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
 func (*ReadRequest) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{5}
+	return file_proto_storage_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ReadRequest) GetVideoId() string {
@@ -318,15 +424,17 @@ func (x *ReadRequest) GetFilename() string {
 }
 
 type ReadResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Data  []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Stored SHA-256 of data, empty for files stored without one.
+	Sha256        []byte `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ReadResponse) Reset() {
 	*x = ReadResponse{}
-	mi := &file_proto_storage_proto_msgTypes[6]
+	mi := &file_proto_storage_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -338,7 +446,7 @@ func (x *ReadResponse) String() string {
 func (*ReadResponse) ProtoMessage() {}
 
 func (x *ReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[6]
+	mi := &file_proto_storage_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -351,7 +459,7 @@ func (x *ReadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReadResponse.ProtoReflect.Descriptor instead.
 func (*ReadResponse) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{6}
+	return file_proto_storage_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ReadResponse) GetData() []byte {
@@ -361,18 +469,160 @@ func (x *ReadResponse) GetData() []byte {
 	return nil
 }
 
+func (x *ReadResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+type ReadRangeRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	VideoId  string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	// A negative offset selects the last -offset bytes of the file.
+	Offset int64 `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// A negative length reads through the end of the file.
+	Length        int64 `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadRangeRequest) Reset() {
+	*x = ReadRangeRequest{}
+	mi := &file_proto_storage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRangeRequest) ProtoMessage() {}
+
+func (x *ReadRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRangeRequest.ProtoReflect.Descriptor instead.
+func (*ReadRangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReadRangeRequest) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
+func (x *ReadRangeRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ReadRangeRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadRangeRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type ReadRangeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Data is empty when the range starts at or past the end of the file.
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Size is the length of the whole file.
+	Size int64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// Stored SHA-256 of the whole file, empty for files stored without one.
+	Sha256        []byte `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadRangeResponse) Reset() {
+	*x = ReadRangeResponse{}
+	mi := &file_proto_storage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRangeResponse) ProtoMessage() {}
+
+func (x *ReadRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRangeResponse.ProtoReflect.Descriptor instead.
+func (*ReadRangeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReadRangeResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ReadRangeResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ReadRangeResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
 type BatchReadRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Empty requests preserve the original behavior and read every stored
 	// file. Supplying requests bounds the batch to the named files.
-	Requests      []*ReadRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	Requests []*ReadRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// When set, ListFiles only returns the files of this video.
+	VideoId       string `protobuf:"bytes,2,opt,name=videoId,proto3" json:"videoId,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BatchReadRequest) Reset() {
 	*x = BatchReadRequest{}
-	mi := &file_proto_storage_proto_msgTypes[7]
+	mi := &file_proto_storage_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -384,7 +634,7 @@ func (x *BatchReadRequest) String() string {
 func (*BatchReadRequest) ProtoMessage() {}
 
 func (x *BatchReadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[7]
+	mi := &file_proto_storage_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -397,7 +647,7 @@ func (x *BatchReadRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchReadRequest.ProtoReflect.Descriptor instead.
 func (*BatchReadRequest) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{7}
+	return file_proto_storage_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *BatchReadRequest) GetRequests() []*ReadRequest {
@@ -407,6 +657,13 @@ func (x *BatchReadRequest) GetRequests() []*ReadRequest {
 	return nil
 }
 
+func (x *BatchReadRequest) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
 type BatchReadResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Entries       []*FileEntry           `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
@@ -416,7 +673,7 @@ type BatchReadResponse struct {
 
 func (x *BatchReadResponse) Reset() {
 	*x = BatchReadResponse{}
-	mi := &file_proto_storage_proto_msgTypes[8]
+	mi := &file_proto_storage_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -428,7 +685,7 @@ func (x *BatchReadResponse) String() string {
 func (*BatchReadResponse) ProtoMessage() {}
 
 func (x *BatchReadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_storage_proto_msgTypes[8]
+	mi := &file_proto_storage_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -441,7 +698,7 @@ func (x *BatchReadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchReadResponse.ProtoReflect.Descriptor instead.
 func (*BatchReadResponse) Descriptor() ([]byte, []int) {
-	return file_proto_storage_proto_rawDescGZIP(), []int{8}
+	return file_proto_storage_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *BatchReadResponse) GetEntries() []*FileEntry {
@@ -451,41 +708,594 @@ func (x *BatchReadResponse) GetEntries() []*FileEntry {
 	return nil
 }
 
-var File_proto_storage_proto protoreflect.FileDescriptor
+type ListFilesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only keys after this "videoId/filename" key are listed; empty starts
+	// from the beginning.
+	Cursor string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Maximum number of entries in the page. Zero or less uses the node's
+	// default, and larger values are capped.
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// When set, only videos whose ID starts with this prefix are listed.
+	VideoIdPrefix string `protobuf:"bytes,3,opt,name=videoIdPrefix,proto3" json:"videoIdPrefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_storage_proto_rawDesc = "" +
-	"\n" +
-	"\x13proto/storage.proto\x12\n" +
-	"tritontube\"X\n" +
-	"\fWriteRequest\x12\x18\n" +
-	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\fR\x04data\"\x0f\n" +
-	"\rWriteResponse\"U\n" +
-	"\tFileEntry\x12\x18\n" +
-	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\fR\x04data\"D\n" +
-	"\x11BatchWriteRequest\x12/\n" +
-	"\aentries\x18\x01 \x03(\v2\x15.tritontube.FileEntryR\aentries\"&\n" +
-	"\x12BatchWriteResponse\x12\x10\n" +
-	"\x03cnt\x18\x01 \x01(\rR\x03cnt\"C\n" +
-	"\vReadRequest\x12\x18\n" +
-	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\"\"\n" +
-	"\fReadResponse\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data\"G\n" +
-	"\x10BatchReadRequest\x123\n" +
-	"\brequests\x18\x01 \x03(\v2\x17.tritontube.ReadRequestR\brequests\"D\n" +
-	"\x11BatchReadResponse\x12/\n" +
-	"\aentries\x18\x01 \x03(\v2\x15.tritontube.FileEntryR\aentries2\xfe\x02\n" +
-	"\x1aVideoContentStorageService\x12@\n" +
-	"\tWriteFile\x12\x18.tritontube.WriteRequest\x1a\x19.tritontube.WriteResponse\x12K\n" +
-	"\n" +
-	"WriteFiles\x12\x1d.tritontube.BatchWriteRequest\x1a\x1e.tritontube.BatchWriteResponse\x12=\n" +
-	"\bReadFile\x12\x17.tritontube.ReadRequest\x1a\x18.tritontube.ReadResponse\x12H\n" +
-	"\tReadFiles\x12\x1c.tritontube.BatchReadRequest\x1a\x1d.tritontube.BatchReadResponse\x12H\n" +
-	"\tListFiles\x12\x1c.tritontube.BatchReadRequest\x1a\x1d.tritontube.BatchReadResponseB\x16Z\x14internal/proto;protob\x06proto3"
+func (x *ListFilesRequest) Reset() {
+	*x = ListFilesRequest{}
+	mi := &file_proto_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesRequest) ProtoMessage() {}
+
+func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListFilesRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetVideoIdPrefix() string {
+	if x != nil {
+		return x.VideoIdPrefix
+	}
+	return ""
+}
+
+type ListFilesInRangeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The interval wraps past zero when start > end and covers the whole
+	// ring when start == end.
+	Start uint64 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End   uint64 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	// Cursor from the previous page; empty starts at start.
+	Cursor string `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Page size limit, as in ListFilesRequest.
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesInRangeRequest) Reset() {
+	*x = ListFilesInRangeRequest{}
+	mi := &file_proto_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesInRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesInRangeRequest) ProtoMessage() {}
+
+func (x *ListFilesInRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesInRangeRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesInRangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListFilesInRangeRequest) GetStart() uint64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *ListFilesInRangeRequest) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *ListFilesInRangeRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListFilesInRangeRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListFilesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Entries carry videoId, filename and size, but no data.
+	Entries []*FileEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// Cursor for the next page, empty once the listing is complete.
+	NextCursor    string `protobuf:"bytes,2,opt,name=nextCursor,proto3" json:"nextCursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_proto_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListFilesResponse) GetEntries() []*FileEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListFilesResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type DeleteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VideoId       string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileRequest) Reset() {
+	*x = DeleteFileRequest{}
+	mi := &file_proto_storage_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileRequest) ProtoMessage() {}
+
+func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteFileRequest) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
+func (x *DeleteFileRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+type DeleteFileResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Deleted is false when the file did not exist.
+	Deleted       bool `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileResponse) Reset() {
+	*x = DeleteFileResponse{}
+	mi := &file_proto_storage_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileResponse) ProtoMessage() {}
+
+func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileResponse.ProtoReflect.Descriptor instead.
+func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteFileResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+type ScrubStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubStatusRequest) Reset() {
+	*x = ScrubStatusRequest{}
+	mi := &file_proto_storage_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusRequest) ProtoMessage() {}
+
+func (x *ScrubStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusRequest.ProtoReflect.Descriptor instead.
+func (*ScrubStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{17}
+}
+
+type ScrubStatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Running is true while a pass is in progress.
+	Running bool `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	// Unix times in seconds; zero until the first pass starts or finishes.
+	LastPassStarted  int64 `protobuf:"varint,2,opt,name=lastPassStarted,proto3" json:"lastPassStarted,omitempty"`
+	LastPassFinished int64 `protobuf:"varint,3,opt,name=lastPassFinished,proto3" json:"lastPassFinished,omitempty"`
+	// Counts from the last finished pass.
+	FilesChecked      int64 `protobuf:"varint,4,opt,name=filesChecked,proto3" json:"filesChecked,omitempty"`
+	ChecksumsRecorded int64 `protobuf:"varint,5,opt,name=checksumsRecorded,proto3" json:"checksumsRecorded,omitempty"`
+	FilesQuarantined  int64 `protobuf:"varint,6,opt,name=filesQuarantined,proto3" json:"filesQuarantined,omitempty"`
+	// Error that ended the last pass early, if any.
+	LastError string `protobuf:"bytes,7,opt,name=lastError,proto3" json:"lastError,omitempty"`
+	// Files that failed verification and have not been written again since.
+	// Each needs to be restored from another replica.
+	Quarantined   []*QuarantinedFile `protobuf:"bytes,8,rep,name=quarantined,proto3" json:"quarantined,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubStatusResponse) Reset() {
+	*x = ScrubStatusResponse{}
+	mi := &file_proto_storage_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusResponse) ProtoMessage() {}
+
+func (x *ScrubStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusResponse.ProtoReflect.Descriptor instead.
+func (*ScrubStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ScrubStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *ScrubStatusResponse) GetLastPassStarted() int64 {
+	if x != nil {
+		return x.LastPassStarted
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassFinished() int64 {
+	if x != nil {
+		return x.LastPassFinished
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetFilesChecked() int64 {
+	if x != nil {
+		return x.FilesChecked
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetChecksumsRecorded() int64 {
+	if x != nil {
+		return x.ChecksumsRecorded
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetFilesQuarantined() int64 {
+	if x != nil {
+		return x.FilesQuarantined
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *ScrubStatusResponse) GetQuarantined() []*QuarantinedFile {
+	if x != nil {
+		return x.Quarantined
+	}
+	return nil
+}
+
+type QuarantinedFile struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	VideoId  string                 `protobuf:"bytes,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Unix time in seconds when the file was quarantined.
+	QuarantinedAt int64 `protobuf:"varint,3,opt,name=quarantinedAt,proto3" json:"quarantinedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantinedFile) Reset() {
+	*x = QuarantinedFile{}
+	mi := &file_proto_storage_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantinedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantinedFile) ProtoMessage() {}
+
+func (x *QuarantinedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_storage_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantinedFile.ProtoReflect.Descriptor instead.
+func (*QuarantinedFile) Descriptor() ([]byte, []int) {
+	return file_proto_storage_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *QuarantinedFile) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
+func (x *QuarantinedFile) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *QuarantinedFile) GetQuarantinedAt() int64 {
+	if x != nil {
+		return x.QuarantinedAt
+	}
+	return 0
+}
+
+var File_proto_storage_proto protoreflect.FileDescriptor
+
+const file_proto_storage_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/storage.proto\x12\n" +
+	"tritontube\"p\n" +
+	"\fWriteRequest\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\fR\x06sha256\"\x0f\n" +
+	"\rWriteResponse\"\x81\x01\n" +
+	"\tFileEntry\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\fR\x06sha256\"\x81\x01\n" +
+	"\tFileChunk\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\fR\x06sha256\"D\n" +
+	"\x11BatchWriteRequest\x12/\n" +
+	"\aentries\x18\x01 \x03(\v2\x15.tritontube.FileEntryR\aentries\"&\n" +
+	"\x12BatchWriteResponse\x12\x10\n" +
+	"\x03cnt\x18\x01 \x01(\rR\x03cnt\"C\n" +
+	"\vReadRequest\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\":\n" +
+	"\fReadResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\fR\x06sha256\"x\n" +
+	"\x10ReadRangeRequest\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x04 \x01(\x03R\x06length\"S\n" +
+	"\x11ReadRangeResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\"a\n" +
+	"\x10BatchReadRequest\x123\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.tritontube.ReadRequestR\brequests\x12\x18\n" +
+	"\avideoId\x18\x02 \x01(\tR\avideoId\"D\n" +
+	"\x11BatchReadResponse\x12/\n" +
+	"\aentries\x18\x01 \x03(\v2\x15.tritontube.FileEntryR\aentries\"f\n" +
+	"\x10ListFilesRequest\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12$\n" +
+	"\rvideoIdPrefix\x18\x03 \x01(\tR\rvideoIdPrefix\"o\n" +
+	"\x17ListFilesInRangeRequest\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x04R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x04R\x03end\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"d\n" +
+	"\x11ListFilesResponse\x12/\n" +
+	"\aentries\x18\x01 \x03(\v2\x15.tritontube.FileEntryR\aentries\x12\x1e\n" +
+	"\n" +
+	"nextCursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"I\n" +
+	"\x11DeleteFileRequest\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\".\n" +
+	"\x12DeleteFileResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"\x14\n" +
+	"\x12ScrubStatusRequest\"\xe0\x02\n" +
+	"\x13ScrubStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12(\n" +
+	"\x0flastPassStarted\x18\x02 \x01(\x03R\x0flastPassStarted\x12*\n" +
+	"\x10lastPassFinished\x18\x03 \x01(\x03R\x10lastPassFinished\x12\"\n" +
+	"\ffilesChecked\x18\x04 \x01(\x03R\ffilesChecked\x12,\n" +
+	"\x11checksumsRecorded\x18\x05 \x01(\x03R\x11checksumsRecorded\x12*\n" +
+	"\x10filesQuarantined\x18\x06 \x01(\x03R\x10filesQuarantined\x12\x1c\n" +
+	"\tlastError\x18\a \x01(\tR\tlastError\x12=\n" +
+	"\vquarantined\x18\b \x03(\v2\x1b.tritontube.QuarantinedFileR\vquarantined\"m\n" +
+	"\x0fQuarantinedFile\x12\x18\n" +
+	"\avideoId\x18\x01 \x01(\tR\avideoId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12$\n" +
+	"\rquarantinedAt\x18\x03 \x01(\x03R\rquarantinedAt2\xe9\a\n" +
+	"\x1aVideoContentStorageService\x12@\n" +
+	"\tWriteFile\x12\x18.tritontube.WriteRequest\x1a\x19.tritontube.WriteResponse\x12K\n" +
+	"\n" +
+	"WriteFiles\x12\x1d.tritontube.BatchWriteRequest\x1a\x1e.tritontube.BatchWriteResponse\x12=\n" +
+	"\bReadFile\x12\x17.tritontube.ReadRequest\x1a\x18.tritontube.ReadResponse\x12H\n" +
+	"\tReadFiles\x12\x1c.tritontube.BatchReadRequest\x1a\x1d.tritontube.BatchReadResponse\x12L\n" +
+	"\rReadFileRange\x12\x1c.tritontube.ReadRangeRequest\x1a\x1d.tritontube.ReadRangeResponse\x12G\n" +
+	"\x0eReadFileStream\x12\x1c.tritontube.ReadRangeRequest\x1a\x15.tritontube.FileChunk0\x01\x12E\n" +
+	"\x0fWriteFileStream\x12\x15.tritontube.FileChunk\x1a\x19.tritontube.WriteResponse(\x01\x12H\n" +
+	"\tListFiles\x12\x1c.tritontube.BatchReadRequest\x1a\x1d.tritontube.BatchReadResponse\x12L\n" +
+	"\rListFilesPage\x12\x1c.tritontube.ListFilesRequest\x1a\x1d.tritontube.ListFilesResponse\x12V\n" +
+	"\x10ListFilesInRange\x12#.tritontube.ListFilesInRangeRequest\x1a\x1d.tritontube.ListFilesResponse\x12H\n" +
+	"\tStatFiles\x12\x1c.tritontube.BatchReadRequest\x1a\x1d.tritontube.BatchReadResponse\x12K\n" +
+	"\n" +
+	"DeleteFile\x12\x1d.tritontube.DeleteFileRequest\x1a\x1e.tritontube.DeleteFileResponse\x12N\n" +
+	"\vScrubStatus\x12\x1e.tritontube.ScrubStatusRequest\x1a\x1f.tritontube.ScrubStatusResponseB\x16Z\x14internal/proto;protob\x06proto3"
 
 var (
 	file_proto_storage_proto_rawDescOnce sync.Once
@@ -499,37 +1309,66 @@ func file_proto_storage_proto_rawDescGZIP() []byte {
 	return file_proto_storage_proto_rawDescData
 }
 
-var file_proto_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_proto_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_proto_storage_proto_goTypes = []any{
-	(*WriteRequest)(nil),       // 0: tritontube.WriteRequest
-	(*WriteResponse)(nil),      // 1: tritontube.WriteResponse
-	(*FileEntry)(nil),          // 2: tritontube.FileEntry
-	(*BatchWriteRequest)(nil),  // 3: tritontube.BatchWriteRequest
-	(*BatchWriteResponse)(nil), // 4: tritontube.BatchWriteResponse
-	(*ReadRequest)(nil),        // 5: tritontube.ReadRequest
-	(*ReadResponse)(nil),       // 6: tritontube.ReadResponse
-	(*BatchReadRequest)(nil),   // 7: tritontube.BatchReadRequest
-	(*BatchReadResponse)(nil),  // 8: tritontube.BatchReadResponse
+	(*WriteRequest)(nil),            // 0: tritontube.WriteRequest
+	(*WriteResponse)(nil),           // 1: tritontube.WriteResponse
+	(*FileEntry)(nil),               // 2: tritontube.FileEntry
+	(*FileChunk)(nil),               // 3: tritontube.FileChunk
+	(*BatchWriteRequest)(nil),       // 4: tritontube.BatchWriteRequest
+	(*BatchWriteResponse)(nil),      // 5: tritontube.BatchWriteResponse
+	(*ReadRequest)(nil),             // 6: tritontube.ReadRequest
+	(*ReadResponse)(nil),            // 7: tritontube.ReadResponse
+	(*ReadRangeRequest)(nil),        // 8: tritontube.ReadRangeRequest
+	(*ReadRangeResponse)(nil),       // 9: tritontube.ReadRangeResponse
+	(*BatchReadRequest)(nil),        // 10: tritontube.BatchReadRequest
+	(*BatchReadResponse)(nil),       // 11: tritontube.BatchReadResponse
+	(*ListFilesRequest)(nil),        // 12: tritontube.ListFilesRequest
+	(*ListFilesInRangeRequest)(nil), // 13: tritontube.ListFilesInRangeRequest
+	(*ListFilesResponse)(nil),       // 14: tritontube.ListFilesResponse
+	(*DeleteFileRequest)(nil),       // 15: tritontube.DeleteFileRequest
+	(*DeleteFileResponse)(nil),      // 16: tritontube.DeleteFileResponse
+	(*ScrubStatusRequest)(nil),      // 17: tritontube.ScrubStatusRequest
+	(*ScrubStatusResponse)(nil),     // 18: tritontube.ScrubStatusResponse
+	(*QuarantinedFile)(nil),         // 19: tritontube.QuarantinedFile
 }
 var file_proto_storage_proto_depIdxs = []int32{
-	2, // 0: tritontube.BatchWriteRequest.entries:type_name -> tritontube.FileEntry
-	5, // 1: tritontube.BatchReadRequest.requests:type_name -> tritontube.ReadRequest
-	2, // 2: tritontube.BatchReadResponse.entries:type_name -> tritontube.FileEntry
-	0, // 3: tritontube.VideoContentStorageService.WriteFile:input_type -> tritontube.WriteRequest
-	3, // 4: tritontube.VideoContentStorageService.WriteFiles:input_type -> tritontube.BatchWriteRequest
-	5, // 5: tritontube.VideoContentStorageService.ReadFile:input_type -> tritontube.ReadRequest
-	7, // 6: tritontube.VideoContentStorageService.ReadFiles:input_type -> tritontube.BatchReadRequest
-	7, // 7: tritontube.VideoContentStorageService.ListFiles:input_type -> tritontube.BatchReadRequest
-	1, // 8: tritontube.VideoContentStorageService.WriteFile:output_type -> tritontube.WriteResponse
-	4, // 9: tritontube.VideoContentStorageService.WriteFiles:output_type -> tritontube.BatchWriteResponse
-	6, // 10: tritontube.VideoContentStorageService.ReadFile:output_type -> tritontube.ReadResponse
-	8, // 11: tritontube.VideoContentStorageService.ReadFiles:output_type -> tritontube.BatchReadResponse
-	8, // 12: tritontube.VideoContentStorageService.ListFiles:output_type -> tritontube.BatchReadResponse
-	8, // [8:13] is the sub-list for method output_type
-	3, // [3:8] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	2,  // 0: tritontube.BatchWriteRequest.entries:type_name -> tritontube.FileEntry
+	6,  // 1: tritontube.BatchReadRequest.requests:type_name -> tritontube.ReadRequest
+	2,  // 2: tritontube.BatchReadResponse.entries:type_name -> tritontube.FileEntry
+	2,  // 3: tritontube.ListFilesResponse.entries:type_name -> tritontube.FileEntry
+	19, // 4: tritontube.ScrubStatusResponse.quarantined:type_name -> tritontube.QuarantinedFile
+	0,  // 5: tritontube.VideoContentStorageService.WriteFile:input_type -> tritontube.WriteRequest
+	4,  // 6: tritontube.VideoContentStorageService.WriteFiles:input_type -> tritontube.BatchWriteRequest
+	6,  // 7: tritontube.VideoContentStorageService.ReadFile:input_type -> tritontube.ReadRequest
+	10, // 8: tritontube.VideoContentStorageService.ReadFiles:input_type -> tritontube.BatchReadRequest
+	8,  // 9: tritontube.VideoContentStorageService.ReadFileRange:input_type -> tritontube.ReadRangeRequest
+	8,  // 10: tritontube.VideoContentStorageService.ReadFileStream:input_type -> tritontube.ReadRangeRequest
+	3,  // 11: tritontube.VideoContentStorageService.WriteFileStream:input_type -> tritontube.FileChunk
+	10, // 12: tritontube.VideoContentStorageService.ListFiles:input_type -> tritontube.BatchReadRequest
+	12, // 13: tritontube.VideoContentStorageService.ListFilesPage:input_type -> tritontube.ListFilesRequest
+	13, // 14: tritontube.VideoContentStorageService.ListFilesInRange:input_type -> tritontube.ListFilesInRangeRequest
+	10, // 15: tritontube.VideoContentStorageService.StatFiles:input_type -> tritontube.BatchReadRequest
+	15, // 16: tritontube.VideoContentStorageService.DeleteFile:input_type -> tritontube.DeleteFileRequest
+	17, // 17: tritontube.VideoContentStorageService.ScrubStatus:input_type -> tritontube.ScrubStatusRequest
+	1,  // 18: tritontube.VideoContentStorageService.WriteFile:output_type -> tritontube.WriteResponse
+	5,  // 19: tritontube.VideoContentStorageService.WriteFiles:output_type -> tritontube.BatchWriteResponse
+	7,  // 20: tritontube.VideoContentStorageService.ReadFile:output_type -> tritontube.ReadResponse
+	11, // 21: tritontube.VideoContentStorageService.ReadFiles:output_type -> tritontube.BatchReadResponse
+	9,  // 22: tritontube.VideoContentStorageService.ReadFileRange:output_type -> tritontube.ReadRangeResponse
+	3,  // 23: tritontube.VideoContentStorageService.ReadFileStream:output_type -> tritontube.FileChunk
+	1,  // 24: tritontube.VideoContentStorageService.WriteFileStream:output_type -> tritontube.WriteResponse
+	11, // 25: tritontube.VideoContentStorageService.ListFiles:output_type -> tritontube.BatchReadResponse
+	14, // 26: tritontube.VideoContentStorageService.ListFilesPage:output_type -> tritontube.ListFilesResponse
+	14, // 27: tritontube.VideoContentStorageService.ListFilesInRange:output_type -> tritontube.ListFilesResponse
+	11, // 28: tritontube.VideoContentStorageService.StatFiles:output_type -> tritontube.BatchReadResponse
+	16, // 29: tritontube.VideoContentStorageService.DeleteFile:output_type -> tritontube.DeleteFileResponse
+	18, // 30: tritontube.VideoContentStorageService.ScrubStatus:output_type -> tritontube.ScrubStatusResponse
+	18, // [18:31] is the sub-list for method output_type
+	5,  // [5:18] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_storage_proto_init() }
@@ -543,7 +1382,7 @@ func file_proto_storage_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_storage_proto_rawDesc), len(file_proto_storage_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
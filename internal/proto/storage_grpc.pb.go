@@ -19,11 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	VideoContentStorageService_WriteFile_FullMethodName  = "/tritontube.VideoContentStorageService/WriteFile"
-	VideoContentStorageService_WriteFiles_FullMethodName = "/tritontube.VideoContentStorageService/WriteFiles"
-	VideoContentStorageService_ReadFile_FullMethodName   = "/tritontube.VideoContentStorageService/ReadFile"
-	VideoContentStorageService_ReadFiles_FullMethodName  = "/tritontube.VideoContentStorageService/ReadFiles"
-	VideoContentStorageService_ListFiles_FullMethodName  = "/tritontube.VideoContentStorageService/ListFiles"
+	VideoContentStorageService_WriteFile_FullMethodName        = "/tritontube.VideoContentStorageService/WriteFile"
+	VideoContentStorageService_WriteFiles_FullMethodName       = "/tritontube.VideoContentStorageService/WriteFiles"
+	VideoContentStorageService_ReadFile_FullMethodName         = "/tritontube.VideoContentStorageService/ReadFile"
+	VideoContentStorageService_ReadFiles_FullMethodName        = "/tritontube.VideoContentStorageService/ReadFiles"
+	VideoContentStorageService_ReadFileRange_FullMethodName    = "/tritontube.VideoContentStorageService/ReadFileRange"
+	VideoContentStorageService_ReadFileStream_FullMethodName   = "/tritontube.VideoContentStorageService/ReadFileStream"
+	VideoContentStorageService_WriteFileStream_FullMethodName  = "/tritontube.VideoContentStorageService/WriteFileStream"
+	VideoContentStorageService_ListFiles_FullMethodName        = "/tritontube.VideoContentStorageService/ListFiles"
+	VideoContentStorageService_ListFilesPage_FullMethodName    = "/tritontube.VideoContentStorageService/ListFilesPage"
+	VideoContentStorageService_ListFilesInRange_FullMethodName = "/tritontube.VideoContentStorageService/ListFilesInRange"
+	VideoContentStorageService_StatFiles_FullMethodName        = "/tritontube.VideoContentStorageService/StatFiles"
+	VideoContentStorageService_DeleteFile_FullMethodName       = "/tritontube.VideoContentStorageService/DeleteFile"
+	VideoContentStorageService_ScrubStatus_FullMethodName      = "/tritontube.VideoContentStorageService/ScrubStatus"
 )
 
 // VideoContentStorageServiceClient is the client API for VideoContentStorageService service.
@@ -34,9 +42,37 @@ type VideoContentStorageServiceClient interface {
 	WriteFiles(ctx context.Context, in *BatchWriteRequest, opts ...grpc.CallOption) (*BatchWriteResponse, error)
 	ReadFile(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error)
 	ReadFiles(ctx context.Context, in *BatchReadRequest, opts ...grpc.CallOption) (*BatchReadResponse, error)
+	// ReadFileRange reads part of a single file so HTTP range requests only
+	// transfer the requested bytes.
+	ReadFileRange(ctx context.Context, in *ReadRangeRequest, opts ...grpc.CallOption) (*ReadRangeResponse, error)
+	// ReadFileStream sends a file, or the range selected as in ReadFileRange,
+	// as a sequence of chunks. The first chunk carries the file size.
+	ReadFileStream(ctx context.Context, in *ReadRangeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
+	// WriteFileStream stores a file sent as a sequence of chunks. The first
+	// chunk names the file. Streams let files larger than a single gRPC
+	// message be stored.
+	WriteFileStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[FileChunk, WriteResponse], error)
 	// ListFiles returns file identifiers without loading file contents. It lets
 	// migration discover files before transferring them with single-file RPCs.
 	ListFiles(ctx context.Context, in *BatchReadRequest, opts ...grpc.CallOption) (*BatchReadResponse, error)
+	// ListFilesPage lists file identifiers in key order, a bounded page at a
+	// time, so nodes holding millions of files can be listed without one
+	// oversized response. Pass the previous page's nextCursor to continue.
+	ListFilesPage(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// ListFilesInRange lists the files whose ring position, the hash of
+	// "videoId/filename", lies in [start, end). The node answers from an
+	// in-memory hash index instead of walking its directory. Pages work as in
+	// ListFilesPage and follow ring order from start.
+	ListFilesInRange(ctx context.Context, in *ListFilesInRangeRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// StatFiles returns the size and stored checksum of each requested file
+	// without its data. Files that do not exist are left out.
+	StatFiles(ctx context.Context, in *BatchReadRequest, opts ...grpc.CallOption) (*BatchReadResponse, error)
+	// DeleteFile removes a file and any directories it leaves empty. Deleting
+	// a file that does not exist succeeds, so deletions can be retried.
+	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error)
+	// ScrubStatus reports the background scrubber's progress and the files it
+	// quarantined because they no longer matched their checksum.
+	ScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error)
 }
 
 type videoContentStorageServiceClient struct {
@@ -87,6 +123,48 @@ func (c *videoContentStorageServiceClient) ReadFiles(ctx context.Context, in *Ba
 	return out, nil
 }
 
+func (c *videoContentStorageServiceClient) ReadFileRange(ctx context.Context, in *ReadRangeRequest, opts ...grpc.CallOption) (*ReadRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReadRangeResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_ReadFileRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoContentStorageServiceClient) ReadFileStream(ctx context.Context, in *ReadRangeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VideoContentStorageService_ServiceDesc.Streams[0], VideoContentStorageService_ReadFileStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReadRangeRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoContentStorageService_ReadFileStreamClient = grpc.ServerStreamingClient[FileChunk]
+
+func (c *videoContentStorageServiceClient) WriteFileStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[FileChunk, WriteResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VideoContentStorageService_ServiceDesc.Streams[1], VideoContentStorageService_WriteFileStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FileChunk, WriteResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoContentStorageService_WriteFileStreamClient = grpc.ClientStreamingClient[FileChunk, WriteResponse]
+
 func (c *videoContentStorageServiceClient) ListFiles(ctx context.Context, in *BatchReadRequest, opts ...grpc.CallOption) (*BatchReadResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(BatchReadResponse)
@@ -97,6 +175,56 @@ func (c *videoContentStorageServiceClient) ListFiles(ctx context.Context, in *Ba
 	return out, nil
 }
 
+func (c *videoContentStorageServiceClient) ListFilesPage(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_ListFilesPage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoContentStorageServiceClient) ListFilesInRange(ctx context.Context, in *ListFilesInRangeRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_ListFilesInRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoContentStorageServiceClient) StatFiles(ctx context.Context, in *BatchReadRequest, opts ...grpc.CallOption) (*BatchReadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchReadResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_StatFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoContentStorageServiceClient) DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteFileResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_DeleteFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoContentStorageServiceClient) ScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScrubStatusResponse)
+	err := c.cc.Invoke(ctx, VideoContentStorageService_ScrubStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // VideoContentStorageServiceServer is the server API for VideoContentStorageService service.
 // All implementations must embed UnimplementedVideoContentStorageServiceServer
 // for forward compatibility.
@@ -105,9 +233,37 @@ type VideoContentStorageServiceServer interface {
 	WriteFiles(context.Context, *BatchWriteRequest) (*BatchWriteResponse, error)
 	ReadFile(context.Context, *ReadRequest) (*ReadResponse, error)
 	ReadFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error)
+	// ReadFileRange reads part of a single file so HTTP range requests only
+	// transfer the requested bytes.
+	ReadFileRange(context.Context, *ReadRangeRequest) (*ReadRangeResponse, error)
+	// ReadFileStream sends a file, or the range selected as in ReadFileRange,
+	// as a sequence of chunks. The first chunk carries the file size.
+	ReadFileStream(*ReadRangeRequest, grpc.ServerStreamingServer[FileChunk]) error
+	// WriteFileStream stores a file sent as a sequence of chunks. The first
+	// chunk names the file. Streams let files larger than a single gRPC
+	// message be stored.
+	WriteFileStream(grpc.ClientStreamingServer[FileChunk, WriteResponse]) error
 	// ListFiles returns file identifiers without loading file contents. It lets
 	// migration discover files before transferring them with single-file RPCs.
 	ListFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error)
+	// ListFilesPage lists file identifiers in key order, a bounded page at a
+	// time, so nodes holding millions of files can be listed without one
+	// oversized response. Pass the previous page's nextCursor to continue.
+	ListFilesPage(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
+	// ListFilesInRange lists the files whose ring position, the hash of
+	// "videoId/filename", lies in [start, end). The node answers from an
+	// in-memory hash index instead of walking its directory. Pages work as in
+	// ListFilesPage and follow ring order from start.
+	ListFilesInRange(context.Context, *ListFilesInRangeRequest) (*ListFilesResponse, error)
+	// StatFiles returns the size and stored checksum of each requested file
+	// without its data. Files that do not exist are left out.
+	StatFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error)
+	// DeleteFile removes a file and any directories it leaves empty. Deleting
+	// a file that does not exist succeeds, so deletions can be retried.
+	DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error)
+	// ScrubStatus reports the background scrubber's progress and the files it
+	// quarantined because they no longer matched their checksum.
+	ScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error)
 	mustEmbedUnimplementedVideoContentStorageServiceServer()
 }
 
@@ -130,9 +286,33 @@ func (UnimplementedVideoContentStorageServiceServer) ReadFile(context.Context, *
 func (UnimplementedVideoContentStorageServiceServer) ReadFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ReadFiles not implemented")
 }
+func (UnimplementedVideoContentStorageServiceServer) ReadFileRange(context.Context, *ReadRangeRequest) (*ReadRangeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReadFileRange not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) ReadFileStream(*ReadRangeRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Error(codes.Unimplemented, "method ReadFileStream not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) WriteFileStream(grpc.ClientStreamingServer[FileChunk, WriteResponse]) error {
+	return status.Error(codes.Unimplemented, "method WriteFileStream not implemented")
+}
 func (UnimplementedVideoContentStorageServiceServer) ListFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListFiles not implemented")
 }
+func (UnimplementedVideoContentStorageServiceServer) ListFilesPage(context.Context, *ListFilesRequest) (*ListFilesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFilesPage not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) ListFilesInRange(context.Context, *ListFilesInRangeRequest) (*ListFilesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFilesInRange not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) StatFiles(context.Context, *BatchReadRequest) (*BatchReadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StatFiles not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteFile not implemented")
+}
+func (UnimplementedVideoContentStorageServiceServer) ScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ScrubStatus not implemented")
+}
 func (UnimplementedVideoContentStorageServiceServer) mustEmbedUnimplementedVideoContentStorageServiceServer() {
 }
 func (UnimplementedVideoContentStorageServiceServer) testEmbeddedByValue() {}
@@ -227,6 +407,42 @@ func _VideoContentStorageService_ReadFiles_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VideoContentStorageService_ReadFileRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).ReadFileRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_ReadFileRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).ReadFileRange(ctx, req.(*ReadRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoContentStorageService_ReadFileStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReadRangeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VideoContentStorageServiceServer).ReadFileStream(m, &grpc.GenericServerStream[ReadRangeRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoContentStorageService_ReadFileStreamServer = grpc.ServerStreamingServer[FileChunk]
+
+func _VideoContentStorageService_WriteFileStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VideoContentStorageServiceServer).WriteFileStream(&grpc.GenericServerStream[FileChunk, WriteResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VideoContentStorageService_WriteFileStreamServer = grpc.ClientStreamingServer[FileChunk, WriteResponse]
+
 func _VideoContentStorageService_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(BatchReadRequest)
 	if err := dec(in); err != nil {
@@ -245,6 +461,96 @@ func _VideoContentStorageService_ListFiles_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VideoContentStorageService_ListFilesPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).ListFilesPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_ListFilesPage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).ListFilesPage(ctx, req.(*ListFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoContentStorageService_ListFilesInRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesInRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).ListFilesInRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_ListFilesInRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).ListFilesInRange(ctx, req.(*ListFilesInRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoContentStorageService_StatFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).StatFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_StatFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).StatFiles(ctx, req.(*BatchReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoContentStorageService_DeleteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).DeleteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_DeleteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).DeleteFile(ctx, req.(*DeleteFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoContentStorageService_ScrubStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScrubStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoContentStorageServiceServer).ScrubStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoContentStorageService_ScrubStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoContentStorageServiceServer).ScrubStatus(ctx, req.(*ScrubStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // VideoContentStorageService_ServiceDesc is the grpc.ServiceDesc for VideoContentStorageService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -268,11 +574,46 @@ var VideoContentStorageService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReadFiles",
 			Handler:    _VideoContentStorageService_ReadFiles_Handler,
 		},
+		{
+			MethodName: "ReadFileRange",
+			Handler:    _VideoContentStorageService_ReadFileRange_Handler,
+		},
 		{
 			MethodName: "ListFiles",
 			Handler:    _VideoContentStorageService_ListFiles_Handler,
 		},
+		{
+			MethodName: "ListFilesPage",
+			Handler:    _VideoContentStorageService_ListFilesPage_Handler,
+		},
+		{
+			MethodName: "ListFilesInRange",
+			Handler:    _VideoContentStorageService_ListFilesInRange_Handler,
+		},
+		{
+			MethodName: "StatFiles",
+			Handler:    _VideoContentStorageService_StatFiles_Handler,
+		},
+		{
+			MethodName: "DeleteFile",
+			Handler:    _VideoContentStorageService_DeleteFile_Handler,
+		},
+		{
+			MethodName: "ScrubStatus",
+			Handler:    _VideoContentStorageService_ScrubStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReadFileStream",
+			Handler:       _VideoContentStorageService_ReadFileStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WriteFileStream",
+			Handler:       _VideoContentStorageService_WriteFileStream_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/storage.proto",
 }
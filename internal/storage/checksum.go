@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Every stored file has its SHA-256 kept in a hidden file next to it, named
+// checksumFilePrefix followed by the file's name.
+const checksumFilePrefix = internalFilePrefix + "sha256-"
+
+// ErrChecksumMismatch reports data that does not match its SHA-256 checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksum returns the SHA-256 digest used to verify stored files.
+func Checksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// VerifyChecksum reports ErrChecksumMismatch when data does not match
+// expected. An empty expected checksum is not checked.
+func VerifyChecksum(data, expected []byte) error {
+	if len(expected) > 0 && !bytes.Equal(Checksum(data), expected) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func checksumPath(path string) string {
+	return filepath.Join(filepath.Dir(path), checksumFilePrefix+filepath.Base(path))
+}
+
+// readChecksum returns the stored checksum of a file, or nil for files stored
+// before checksums were kept.
+func readChecksum(path string) ([]byte, error) {
+	encoded, err := os.ReadFile(checksumPath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := hex.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, fmt.Errorf("%w: unreadable checksum file for %s", ErrChecksumMismatch, path)
+	}
+	return checksum, nil
+}
+
+// storeFile writes a file through write and then records the SHA-256 of the
+// written data. When expected is set, data that does not match it is
+// rejected before it replaces the file. The old checksum is removed before
+// the new contents are renamed into place, so a crash in between leaves a
+// file without a checksum rather than one that fails verification. commit is
+// held from then until the new checksum is stored, so whoever holds it
+// exclusively never sees the file and its checksum out of step.
+func storeFile(path string, expected []byte, commit sync.Locker, write func(io.Writer) error) error {
+	hash := sha256.New()
+	var checksum []byte
+	locked := false
+	defer func() {
+		if locked {
+			commit.Unlock()
+		}
+	}()
+	err := writeFileAtomic(path, func(file *os.File) error {
+		if err := write(io.MultiWriter(file, hash)); err != nil {
+			return err
+		}
+		checksum = hash.Sum(nil)
+		if len(expected) > 0 && !bytes.Equal(checksum, expected) {
+			return fmt.Errorf("%w: received data for %s does not match the sender's checksum", ErrChecksumMismatch, path)
+		}
+		commit.Lock()
+		locked = true
+		return removeChecksum(path)
+	})
+	if err != nil {
+		return err
+	}
+	return writeChecksum(path, checksum)
+}
+
+func writeChecksum(path string, checksum []byte) error {
+	return writeFileAtomic(checksumPath(path), func(file *os.File) error {
+		_, err := file.WriteString(hex.EncodeToString(checksum))
+		return err
+	})
+}
+
+func removeChecksum(path string) error {
+	if err := os.Remove(checksumPath(path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// readVerifiedFile reads a whole file and checks it against its stored
+// checksum, returning the data and the checksum.
+func readVerifiedFile(path string) ([]byte, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	checksum, err := readChecksum(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyChecksum(data, checksum); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s is corrupted", err, path)
+	}
+	return data, checksum, nil
+}
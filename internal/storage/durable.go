@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Names starting with internalFilePrefix belong to the storage node itself
+// and are never listed or served. tempFilePrefix starts the name of every file
+// that is still being written; such files are removed on startup.
+const (
+	internalFilePrefix = ".tritontube-"
+	tempFilePrefix     = internalFilePrefix + "tmp-"
+)
+
+func isInternalFile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), internalFilePrefix)
+}
+
+func isTempFile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), tempFilePrefix)
+}
+
+// skipStoredPath reports whether a walk of stored files from root should pass
+// over path: directories, and internal files and directories such as the
+// quarantine. The error is filepath.SkipDir for internal directories.
+func skipStoredPath(root, path string, entry fs.DirEntry) (bool, error) {
+	if path != root && isInternalFile(path) {
+		if entry.IsDir() {
+			return true, filepath.SkipDir
+		}
+		return true, nil
+	}
+	return entry.IsDir(), nil
+}
+
+// writeFileAtomic writes a file so that readers see either the previous
+// contents or the complete new contents. Data goes to a temporary file in the
+// same directory, which is synced and then renamed over path; the directory is
+// synced afterwards so the rename survives a crash, and so is its parent when
+// the directory was created by this write. On failure the temporary file is
+// removed and path is left untouched.
+func writeFileAtomic(path string, write func(*os.File) error) error {
+	dir := filepath.Dir(path)
+	_, err := os.Stat(dir)
+	created := errors.Is(err, fs.ErrNotExist)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Storage: Create directory failed: %v\n", err)
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, tempFilePrefix+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	if err := write(file); err != nil {
+		return err
+	}
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", tempPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return err
+	}
+	committed = true
+
+	if err := syncDir(dir); err != nil {
+		return err
+	}
+	if created {
+		return syncDir(filepath.Dir(dir))
+	}
+	return nil
+}
+
+// syncDir flushes a directory's entries, making renames within it durable.
+func syncDir(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	if err := handle.Sync(); err != nil {
+		return fmt.Errorf("sync directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// removeTempFiles deletes temporary files left by writes that were
+// interrupted, for example by a crash, before they were renamed into place.
+func removeTempFiles(base string) error {
+	removed := 0
+	err := filepath.WalkDir(base, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() || !isTempFile(path) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if removed > 0 {
+		log.Printf("Storage: Removed %d incomplete files\n", removed)
+	}
+	return err
+}
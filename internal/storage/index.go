@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RingHash returns the position of a "videoId/filename" key on the
+// consistent-hash ring: the first eight bytes of its SHA-256, big endian.
+func RingHash(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// indexBucketBits is the number of leading hash bits that pick an index
+// bucket.
+const indexBucketBits = 16
+
+// hashIndex keeps every stored file ordered by ring position, so hash-range
+// listings do not walk the storage directory. Entries are spread over buckets
+// by the leading bits of their hash and each bucket is a sorted slice, which
+// keeps inserts cheap on nodes with millions of files.
+type hashIndex struct {
+	mu      sync.RWMutex
+	buckets [][]indexEntry
+}
+
+type indexEntry struct {
+	hash uint64
+	key  string
+	size int64
+}
+
+func newHashIndex() *hashIndex {
+	return &hashIndex{buckets: make([][]indexEntry, 1<<indexBucketBits)}
+}
+
+func compareIndexEntries(a, b indexEntry) int {
+	return cmp.Or(cmp.Compare(a.hash, b.hash), strings.Compare(a.key, b.key))
+}
+
+func bucketOf(hash uint64) int {
+	return int(hash >> (64 - indexBucketBits))
+}
+
+// put adds a key or updates its size.
+func (index *hashIndex) put(key string, size int64) {
+	entry := indexEntry{hash: RingHash(key), key: key, size: size}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	bucket := index.buckets[bucketOf(entry.hash)]
+	position, found := slices.BinarySearchFunc(bucket, entry, compareIndexEntries)
+	if found {
+		bucket[position].size = size
+		return
+	}
+	index.buckets[bucketOf(entry.hash)] = slices.Insert(bucket, position, entry)
+}
+
+func (index *hashIndex) remove(key string) {
+	entry := indexEntry{hash: RingHash(key), key: key}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	bucket := index.buckets[bucketOf(entry.hash)]
+	if position, found := slices.BinarySearchFunc(bucket, entry, compareIndexEntries); found {
+		index.buckets[bucketOf(entry.hash)] = slices.Delete(bucket, position, position+1)
+	}
+}
+
+// list returns up to limit entries whose hash lies in [start, end), in ring
+// order from start, and whether more follow. The interval wraps past zero
+// when start > end and covers the whole ring when start == end. A non-nil
+// after resumes the listing just past that entry.
+func (index *hashIndex) list(start, end uint64, after *indexEntry, limit int) ([]indexEntry, bool) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	// Offsets from start order the interval even when it wraps; an offset
+	// of span or more is outside it.
+	span := end - start
+	inRange := func(hash uint64) bool { return span == 0 || hash-start < span }
+	// Step n visits bucket (first + n) mod buckets. The last step revisits
+	// the first bucket for the hashes just below start.
+	first := bucketOf(start)
+	steps := len(index.buckets)
+	stepOf := func(hash uint64) int {
+		if bucketOf(hash) == first && hash < start {
+			return steps
+		}
+		return (bucketOf(hash) - first + steps) % steps
+	}
+	before := func(a, b indexEntry) bool {
+		return a.hash-start < b.hash-start || a.hash == b.hash && a.key <= b.key
+	}
+
+	entries := make([]indexEntry, 0, min(limit, 64))
+	step := 0
+	if after != nil {
+		step = stepOf(after.hash)
+	}
+	for ; step <= steps; step++ {
+		for _, entry := range index.buckets[(first+step)%steps] {
+			if stepOf(entry.hash) != step || after != nil && before(entry, *after) {
+				continue
+			}
+			if !inRange(entry.hash) {
+				return entries, false
+			}
+			if len(entries) == limit {
+				return entries, true
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, false
+}
+
+// indexCursor encodes the position of an entry for ListFilesInRange.
+func indexCursor(entry indexEntry) string {
+	return fmt.Sprintf("%016x/%s", entry.hash, entry.key)
+}
+
+func parseIndexCursor(cursor string) (*indexEntry, error) {
+	hash, key, ok := strings.Cut(cursor, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	value, err := strconv.ParseUint(hash, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+	return &indexEntry{hash: value, key: key}, nil
+}
+
+// buildIndex indexes every file in the storage directory.
+func buildIndex(base string) (*hashIndex, error) {
+	index := newHashIndex()
+	err := filepath.WalkDir(base, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if skip, err := skipStoredPath(base, path, entry); skip {
+			return err
+		}
+		relativePath, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relativePath)
+		if !strings.Contains(key, "/") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		index.put(key, info.Size())
+		return nil
+	})
+	return index, err
+}
@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"tritontube/internal/proto"
+)
+
+// quarantineDirName is the directory, inside the storage directory, that
+// holds files which failed verification. It keeps the videoId/filename layout
+// of the storage directory and each file's checksum file next to it.
+const quarantineDirName = internalFilePrefix + "quarantine"
+
+// scrubReadSize is how much of a file the scrubber reads at a time.
+const scrubReadSize = 1 << 20
+
+// ScrubResult counts what a scrubber pass did.
+type ScrubResult struct {
+	// Checked counts the files that were read and hashed.
+	Checked int
+	// Recorded counts files that had no checksum yet and now have one.
+	Recorded int
+	// Quarantined counts files moved aside because they no longer matched
+	// their checksum.
+	Quarantined int
+}
+
+// scrubState is what ScrubStatus reports about the scrubber's passes.
+type scrubState struct {
+	mu       sync.Mutex
+	running  bool
+	started  time.Time
+	finished time.Time
+	last     ScrubResult
+	lastErr  error
+}
+
+// RunScrubber scrubs the storage directory once now and then every interval
+// until ctx is cancelled. Each pass reads at most bytesPerSecond bytes per
+// second; zero or less does not limit it.
+func (ss *StorageServer) RunScrubber(ctx context.Context, interval time.Duration, bytesPerSecond int64) {
+	for {
+		result, err := ss.Scrub(ctx, bytesPerSecond)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Storage: Scrub failed: %v\n", err)
+		} else {
+			log.Printf("Storage: Scrubbed %d files, recorded %d checksums, quarantined %d files\n",
+				result.Checked, result.Recorded, result.Quarantined)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Scrub makes one pass over every stored file. A file without a checksum has
+// one recorded the first time it is seen. A file whose contents no longer
+// match its checksum is moved to the quarantine directory, so it is no longer
+// served and replicas are read instead, and is reported by ScrubStatus until
+// it is written again.
+func (ss *StorageServer) Scrub(ctx context.Context, bytesPerSecond int64) (ScrubResult, error) {
+	ss.scrub.mu.Lock()
+	if ss.scrub.running {
+		ss.scrub.mu.Unlock()
+		return ScrubResult{}, errors.New("a scrub is already running")
+	}
+	ss.scrub.running = true
+	ss.scrub.started = time.Now()
+	ss.scrub.mu.Unlock()
+
+	limiter := &rateLimiter{rate: bytesPerSecond, start: time.Now()}
+	var result ScrubResult
+	err := filepath.WalkDir(ss.basePath, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if skip, err := skipStoredPath(ss.basePath, path, entry); skip {
+			return err
+		}
+		return ss.scrubFile(ctx, path, limiter, &result)
+	})
+
+	ss.scrub.mu.Lock()
+	ss.scrub.running = false
+	ss.scrub.finished = time.Now()
+	ss.scrub.last = result
+	ss.scrub.lastErr = err
+	ss.scrub.mu.Unlock()
+	return result, err
+}
+
+func (ss *StorageServer) scrubFile(ctx context.Context, path string, limiter *rateLimiter, result *ScrubResult) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	checksum, checksumErr := readChecksum(path)
+	digest, err := hashContents(ctx, file, info.Size(), limiter)
+	if err != nil {
+		return err
+	}
+	result.Checked++
+	if checksumErr == nil && len(checksum) > 0 && bytes.Equal(digest, checksum) {
+		return nil
+	}
+	if checksumErr != nil || len(checksum) > 0 {
+		// Read the copy again before condemning it, in case the first read
+		// failed. Both reads are paced and run without the lock.
+		digest, err = hashContents(ctx, file, info.Size(), limiter)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Writers replace files by renaming, so a file that still has the same
+	// identity, size and modification time is the one that was hashed. Keep
+	// writers out while checking that and acting on the result.
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	current, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, current) || current.Size() != info.Size() || !current.ModTime().Equal(info.ModTime()) {
+		// Replaced since it was read; the next pass checks the new file.
+		return nil
+	}
+	checksum, checksumErr = readChecksum(path)
+	if checksumErr != nil && !errors.Is(checksumErr, ErrChecksumMismatch) {
+		return checksumErr
+	}
+
+	switch {
+	case checksumErr == nil && checksum == nil:
+		if err := writeChecksum(path, digest); err != nil {
+			return fmt.Errorf("record checksum of %s: %w", path, err)
+		}
+		result.Recorded++
+	case checksumErr != nil || !bytes.Equal(digest, checksum):
+		if err := ss.quarantine(path); err != nil {
+			return fmt.Errorf("quarantine %s: %w", path, err)
+		}
+		log.Printf("Storage: Quarantined corrupted file %s\n", path)
+		result.Quarantined++
+	}
+	return nil
+}
+
+// hashContents returns the SHA-256 of the first size bytes of file, pacing
+// reads through limiter when it is not nil.
+func hashContents(ctx context.Context, file *os.File, size int64, limiter *rateLimiter) ([]byte, error) {
+	reader := io.NewSectionReader(file, 0, size)
+	hash := sha256.New()
+	buffer := make([]byte, scrubReadSize)
+	for {
+		n, err := reader.Read(buffer)
+		hash.Write(buffer[:n])
+		if err == io.EOF {
+			return hash.Sum(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := limiter.wait(ctx, n); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// quarantinePath returns the quarantine directory and where a stored file is
+// kept in it once quarantined.
+func (ss *StorageServer) quarantinePath(path string) (string, string, error) {
+	basePath, err := filepath.Abs(ss.basePath)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := ss.storedKey(path)
+	if err != nil {
+		return "", "", err
+	}
+	root := filepath.Join(basePath, quarantineDirName)
+	return root, filepath.Join(root, filepath.FromSlash(key)), nil
+}
+
+// quarantine moves a file and its checksum file into the quarantine
+// directory, replacing any earlier quarantined copy. The move is recorded in
+// the quarantined file's modification time.
+func (ss *StorageServer) quarantine(path string) error {
+	key, err := ss.storedKey(path)
+	if err != nil {
+		return err
+	}
+	_, target, err := ss.quarantinePath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, target); err != nil {
+		return err
+	}
+	ss.index.remove(key)
+	now := time.Now()
+	if err := os.Chtimes(target, now, now); err != nil {
+		return err
+	}
+	if err := os.Rename(checksumPath(path), checksumPath(target)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	basePath, err := filepath.Abs(ss.basePath)
+	if err != nil {
+		return err
+	}
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return removeEmptyDirs(basePath, filepath.Dir(absolutePath))
+}
+
+// removeQuarantined removes the quarantined copy of a stored file, if any.
+func (ss *StorageServer) removeQuarantined(path string) error {
+	root, target, err := ss.quarantinePath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := removeChecksum(target); err != nil {
+		return err
+	}
+	return removeEmptyDirs(root, filepath.Dir(target))
+}
+
+// ScrubStatus reports the scrubber's last pass and every file waiting in
+// quarantine, so operators know which files to restore from a replica.
+func (ss *StorageServer) ScrubStatus(ctx context.Context, req *proto.ScrubStatusRequest) (*proto.ScrubStatusResponse, error) {
+	ss.scrub.mu.Lock()
+	response := &proto.ScrubStatusResponse{
+		Running:           ss.scrub.running,
+		LastPassStarted:   unixSeconds(ss.scrub.started),
+		LastPassFinished:  unixSeconds(ss.scrub.finished),
+		FilesChecked:      int64(ss.scrub.last.Checked),
+		ChecksumsRecorded: int64(ss.scrub.last.Recorded),
+		FilesQuarantined:  int64(ss.scrub.last.Quarantined),
+	}
+	if ss.scrub.lastErr != nil {
+		response.LastError = ss.scrub.lastErr.Error()
+	}
+	ss.scrub.mu.Unlock()
+
+	root := filepath.Join(ss.basePath, quarantineDirName)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
+		if errors.Is(walkErr, fs.ErrNotExist) && path == root {
+			return filepath.SkipAll
+		}
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if skip, err := skipStoredPath(root, path, entry); skip {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		parts := splitStoredPath(relativePath)
+		if len(parts) != 2 {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		response.Quarantined = append(response.Quarantined, &proto.QuarantinedFile{
+			VideoId:       parts[0],
+			Filename:      parts[1],
+			QuarantinedAt: info.ModTime().Unix(),
+		})
+		return nil
+	})
+	if err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+func unixSeconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// rateLimiter paces reads so that, on average, no more than rate bytes are
+// read per second since start. A nil limiter or a rate of zero or less does
+// not limit.
+type rateLimiter struct {
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func (limiter *rateLimiter) wait(ctx context.Context, n int) error {
+	if limiter == nil || limiter.rate <= 0 {
+		return nil
+	}
+	limiter.read += int64(n)
+	due := limiter.start.Add(time.Duration(float64(limiter.read) / float64(limiter.rate) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
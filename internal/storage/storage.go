@@ -1,13 +1,20 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"tritontube/internal/proto"
@@ -16,17 +23,42 @@ import (
 type StorageServer struct {
 	proto.UnimplementedVideoContentStorageServiceServer
 	basePath string
+
+	// Writes and deletions hold mu for reading while they change a file. The
+	// scrubber holds it for writing while it confirms and acts on a mismatch,
+	// so it never judges a file that is being replaced.
+	mu    sync.RWMutex
+	scrub scrubState
+	index *hashIndex
 }
 
 func NewStorageServer(base string) *StorageServer {
-	if err := os.MkdirAll(base, os.ModePerm); err != nil {
-		fmt.Printf("Failed to create storage directory: %v\n", err)
+	ss, err := OpenStorageServer(base)
+	if err != nil {
+		fmt.Printf("Failed to open storage directory: %v\n", err)
 		return nil
 	}
+	return ss
+}
+
+// OpenStorageServer prepares a storage directory like NewStorageServer but
+// reports why it could not be used.
+func OpenStorageServer(base string) (*StorageServer, error) {
+	if err := os.MkdirAll(base, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	if err := removeTempFiles(base); err != nil {
+		log.Printf("Storage: Remove incomplete files failed: %v\n", err)
+	}
+	index, err := buildIndex(base)
+	if err != nil {
+		return nil, fmt.Errorf("index storage directory: %w", err)
+	}
 
 	return &StorageServer{
 		basePath: base,
-	}
+		index:    index,
+	}, nil
 }
 
 // WriteFile writes a single file to the server's storage directory.
@@ -36,12 +68,7 @@ func (ss *StorageServer) WriteFile(ctx context.Context, req *proto.WriteRequest)
 		return &proto.WriteResponse{}, err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		log.Printf("Storage: Create directory failed: %v\n", err)
-		return &proto.WriteResponse{}, err
-	}
-
-	if err := os.WriteFile(filePath, req.Data, 0644); err != nil {
+	if err := ss.writeFileData(filePath, req.Data, req.Sha256); err != nil {
 		log.Printf("Storage: Write file failed: %v\n", err)
 		return &proto.WriteResponse{}, err
 	}
@@ -62,12 +89,7 @@ func (ss *StorageServer) WriteFiles(ctx context.Context, req *proto.BatchWriteRe
 			return &proto.BatchWriteResponse{Cnt: count}, err
 		}
 
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			log.Printf("Storage: Create directory failed: %v\n", err)
-			return &proto.BatchWriteResponse{Cnt: count}, err
-		}
-
-		if err := os.WriteFile(filePath, entry.Data, 0644); err != nil {
+		if err := ss.writeFileData(filePath, entry.Data, entry.Sha256); err != nil {
 			log.Printf("Storage: Write file failed: %v\n", err)
 			return &proto.BatchWriteResponse{Cnt: count}, err
 		}
@@ -85,7 +107,7 @@ func (ss *StorageServer) ReadFile(ctx context.Context, req *proto.ReadRequest) (
 	}
 
 	start := time.Now()
-	data, err := os.ReadFile(filePath)
+	data, checksum, err := readVerifiedFile(filePath)
 	if err != nil {
 		log.Printf("Storage: Read file failed: %v\n", err)
 		return &proto.ReadResponse{Data: nil}, err
@@ -93,7 +115,253 @@ func (ss *StorageServer) ReadFile(ctx context.Context, req *proto.ReadRequest) (
 	fileReadTime := time.Since(start)
 	log.Printf("Filesystem read time: %.3f ms", float64(fileReadTime)/float64(time.Millisecond))
 
-	return &proto.ReadResponse{Data: data}, nil
+	return &proto.ReadResponse{Data: data, Sha256: checksum}, nil
+}
+
+// ReadFileRange reads up to length bytes starting at offset. A negative offset
+// counts back from the end of the file and a negative length reads to the end.
+// Ranges that start past the end return no data along with the file size so
+// callers can report the range as unsatisfiable. A range covering the whole
+// file is verified against the stored checksum.
+func (ss *StorageServer) ReadFileRange(ctx context.Context, req *proto.ReadRangeRequest) (*proto.ReadRangeResponse, error) {
+	filePath, err := ss.filePath(req.VideoId, req.Filename)
+	if err != nil {
+		return &proto.ReadRangeResponse{}, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Printf("Storage: Read file range failed: %v\n", err)
+		return &proto.ReadRangeResponse{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return &proto.ReadRangeResponse{}, err
+	}
+	size := info.Size()
+	checksum, err := readChecksum(filePath)
+	if err != nil {
+		return &proto.ReadRangeResponse{}, err
+	}
+
+	offset, length := ResolveRange(req.Offset, req.Length, size)
+	if length == 0 {
+		return &proto.ReadRangeResponse{Size: size, Sha256: checksum}, nil
+	}
+
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, offset); err != nil {
+		log.Printf("Storage: Read file range failed: %v\n", err)
+		return &proto.ReadRangeResponse{}, err
+	}
+	if length == size {
+		if err := VerifyChecksum(data, checksum); err != nil {
+			log.Printf("Storage: Read file range failed: %s is corrupted\n", filePath)
+			return &proto.ReadRangeResponse{}, fmt.Errorf("%w: %s is corrupted", err, filePath)
+		}
+	}
+	return &proto.ReadRangeResponse{Data: data, Size: size, Sha256: checksum}, nil
+}
+
+// ReadFileStream sends the selected range of a file in chunks of at most
+// proto.StreamChunkSize bytes. The first chunk names the file and carries its
+// size and checksum; an empty range is answered with that chunk alone. When
+// the whole file is sent it is hashed on the way, and a mismatch fails the
+// stream after the last chunk so the receiver discards the data.
+func (ss *StorageServer) ReadFileStream(req *proto.ReadRangeRequest, stream proto.VideoContentStorageService_ReadFileStreamServer) error {
+	filePath, err := ss.filePath(req.VideoId, req.Filename)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Printf("Storage: Read file stream failed: %v\n", err)
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	checksum, err := readChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	offset, length := ResolveRange(req.Offset, req.Length, size)
+
+	hash := sha256.New()
+	reader := io.TeeReader(io.NewSectionReader(file, offset, length), hash)
+	buffer := make([]byte, min(length, proto.StreamChunkSize))
+	for sent := int64(0); sent == 0 || sent < length; {
+		data := buffer[:min(length-sent, proto.StreamChunkSize)]
+		if _, err := io.ReadFull(reader, data); err != nil {
+			log.Printf("Storage: Read file stream failed: %v\n", err)
+			return err
+		}
+		chunk := &proto.FileChunk{Data: data}
+		if sent == 0 {
+			chunk.VideoId, chunk.Filename, chunk.Size, chunk.Sha256 = req.VideoId, req.Filename, size, checksum
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		if length == 0 {
+			return nil
+		}
+		sent += int64(len(data))
+	}
+
+	if length == size && len(checksum) > 0 && !bytes.Equal(hash.Sum(nil), checksum) {
+		log.Printf("Storage: Read file stream failed: %s is corrupted\n", filePath)
+		return fmt.Errorf("%w: %s is corrupted", ErrChecksumMismatch, filePath)
+	}
+	return nil
+}
+
+// OpenFileRange opens up to length bytes of a stored file starting at offset,
+// selected as in ReadFileRange, for callers in the same process. It returns
+// the size and stored checksum of the whole file. When the whole file is read
+// it is hashed on the way, and the read that returns its last byte also
+// reports ErrChecksumMismatch if the file is corrupted.
+func (ss *StorageServer) OpenFileRange(videoID, filename string, offset, length int64) (io.ReadCloser, int64, []byte, error) {
+	filePath, err := ss.filePath(videoID, filename)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, err
+	}
+	size := info.Size()
+	checksum, err := readChecksum(filePath)
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, err
+	}
+
+	offset, length = ResolveRange(offset, length, size)
+	reader := &verifiedFileReader{file: file, reader: io.NewSectionReader(file, offset, length), remaining: length}
+	if length == size && len(checksum) > 0 {
+		reader.hash, reader.expected = sha256.New(), checksum
+		reader.reader = io.TeeReader(reader.reader, reader.hash)
+	}
+	return reader, size, checksum, nil
+}
+
+type verifiedFileReader struct {
+	file      *os.File
+	reader    io.Reader
+	remaining int64
+	// hash is set while the whole file is being read and checked.
+	hash     hash.Hash
+	expected []byte
+}
+
+func (reader *verifiedFileReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.remaining -= int64(n)
+	if reader.hash != nil && reader.remaining == 0 {
+		sum := reader.hash.Sum(nil)
+		reader.hash = nil
+		if !bytes.Equal(sum, reader.expected) {
+			return n, fmt.Errorf("%w: %s is corrupted", ErrChecksumMismatch, reader.file.Name())
+		}
+	}
+	return n, err
+}
+
+func (reader *verifiedFileReader) Close() error {
+	return reader.file.Close()
+}
+
+// WriteFileStream stores a file received in chunks. The first chunk names the
+// file and may carry its checksum. The file only appears once the whole
+// stream has been received and verified, so a stream that fails part way
+// leaves any previous version in place.
+func (ss *StorageServer) WriteFileStream(stream proto.VideoContentStorageService_WriteFileStreamServer) error {
+	chunk, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	filePath, err := ss.filePath(chunk.VideoId, chunk.Filename)
+	if err != nil {
+		return err
+	}
+
+	err = ss.storeFile(filePath, chunk.Sha256, func(file io.Writer) error {
+		for {
+			if _, err := file.Write(chunk.Data); err != nil {
+				return err
+			}
+			chunk, err = stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Storage: Write file stream failed: %v\n", err)
+		return err
+	}
+
+	return stream.SendAndClose(&proto.WriteResponse{})
+}
+
+func (ss *StorageServer) writeFileData(path string, data, expected []byte) error {
+	return ss.storeFile(path, expected, func(file io.Writer) error {
+		_, err := file.Write(data)
+		return err
+	})
+}
+
+// storeFile stores a file and its checksum. Writing a file again repairs it,
+// so any quarantined copy left by the scrubber is dropped.
+func (ss *StorageServer) storeFile(path string, expected []byte, write func(io.Writer) error) error {
+	if err := storeFile(path, expected, ss.mu.RLocker(), write); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	key, err := ss.storedKey(path)
+	if err != nil {
+		return err
+	}
+	ss.index.put(key, info.Size())
+	return ss.removeQuarantined(path)
+}
+
+// ResolveRange converts a requested offset and length into the absolute start
+// and byte count within a file of the given size. A negative offset counts
+// back from the end and a negative length reads to the end. The count is zero
+// when the range starts at or past the end of the file.
+func ResolveRange(offset, length, size int64) (int64, int64) {
+	if offset < 0 {
+		offset = max(size+offset, 0)
+	}
+	if offset >= size {
+		return size, 0
+	}
+	count := size - offset
+	if length >= 0 {
+		count = min(count, length)
+	}
+	return offset, count
 }
 
 // ReadFiles reads requested files, or every stored file when the request is empty.
@@ -108,12 +376,12 @@ func (ss *StorageServer) ReadFiles(ctx context.Context, req *proto.BatchReadRequ
 			if err != nil {
 				return &proto.BatchReadResponse{Entries: entries}, err
 			}
-			data, err := os.ReadFile(filePath)
+			data, checksum, err := readVerifiedFile(filePath)
 			if err != nil {
 				return &proto.BatchReadResponse{Entries: entries}, fmt.Errorf("read %q: %w", filePath, err)
 			}
 			entries = append(entries, &proto.FileEntry{
-				VideoId: request.VideoId, Filename: request.Filename, Data: data,
+				VideoId: request.VideoId, Filename: request.Filename, Data: data, Sha256: checksum,
 			})
 		}
 		return &proto.BatchReadResponse{Entries: entries}, nil
@@ -128,8 +396,8 @@ func (ss *StorageServer) ReadFiles(ctx context.Context, req *proto.BatchReadRequ
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if entry.IsDir() {
-			return nil
+		if skip, err := skipStoredPath(ss.basePath, path, entry); skip {
+			return err
 		}
 
 		relativePath, err := filepath.Rel(ss.basePath, path)
@@ -142,7 +410,7 @@ func (ss *StorageServer) ReadFiles(ctx context.Context, req *proto.BatchReadRequ
 			return nil
 		}
 
-		data, err := os.ReadFile(path)
+		data, checksum, err := readVerifiedFile(path)
 		if err != nil {
 			return fmt.Errorf("read %q: %w", path, err)
 		}
@@ -151,6 +419,7 @@ func (ss *StorageServer) ReadFiles(ctx context.Context, req *proto.BatchReadRequ
 			VideoId:  parts[0],
 			Filename: parts[1],
 			Data:     data,
+			Sha256:   checksum,
 		})
 		return nil
 	})
@@ -164,19 +433,65 @@ func (ss *StorageServer) ReadFiles(ctx context.Context, req *proto.BatchReadRequ
 	return &proto.BatchReadResponse{Entries: entries}, nil
 }
 
+// StatFiles returns the size and recorded checksum of each requested file
+// without reading it. Files that are not stored are left out, and a file
+// stored before checksums were kept has no checksum.
+func (ss *StorageServer) StatFiles(ctx context.Context, req *proto.BatchReadRequest) (*proto.BatchReadResponse, error) {
+	entries := make([]*proto.FileEntry, 0, len(req.GetRequests()))
+	for _, request := range req.GetRequests() {
+		if err := ctx.Err(); err != nil {
+			return &proto.BatchReadResponse{Entries: entries}, err
+		}
+		filePath, err := ss.filePath(request.VideoId, request.Filename)
+		if err != nil {
+			return &proto.BatchReadResponse{Entries: entries}, err
+		}
+		info, err := os.Stat(filePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return &proto.BatchReadResponse{Entries: entries}, err
+		}
+		checksum, err := readChecksum(filePath)
+		if errors.Is(err, ErrChecksumMismatch) {
+			checksum = nil
+		} else if err != nil {
+			return &proto.BatchReadResponse{Entries: entries}, err
+		}
+		entries = append(entries, &proto.FileEntry{
+			VideoId: request.VideoId, Filename: request.Filename, Size: info.Size(), Sha256: checksum,
+		})
+	}
+	return &proto.BatchReadResponse{Entries: entries}, nil
+}
+
 // ListFiles lists stored file identifiers without reading their contents.
+// A request with a video ID only lists that video's files.
 func (ss *StorageServer) ListFiles(ctx context.Context, req *proto.BatchReadRequest) (*proto.BatchReadResponse, error) {
 	entries := make([]*proto.FileEntry, 0)
 
-	err := filepath.WalkDir(ss.basePath, func(path string, entry fs.DirEntry, walkErr error) error {
+	root := ss.basePath
+	if req.VideoId != "" {
+		dir, err := ss.videoDir(req.VideoId)
+		if err != nil {
+			return &proto.BatchReadResponse{Entries: entries}, err
+		}
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			return &proto.BatchReadResponse{Entries: entries}, nil
+		}
+		root = dir
+	}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if entry.IsDir() {
-			return nil
+		if skip, err := skipStoredPath(root, path, entry); skip {
+			return err
 		}
 
 		relativePath, err := filepath.Rel(ss.basePath, path)
@@ -189,9 +504,15 @@ func (ss *StorageServer) ListFiles(ctx context.Context, req *proto.BatchReadRequ
 			return nil
 		}
 
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
 		entries = append(entries, &proto.FileEntry{
 			VideoId:  parts[0],
 			Filename: parts[1],
+			Size:     info.Size(),
 		})
 		return nil
 	})
@@ -202,10 +523,218 @@ func (ss *StorageServer) ListFiles(ctx context.Context, req *proto.BatchReadRequ
 	return &proto.BatchReadResponse{Entries: entries}, nil
 }
 
+// Page sizes for ListFilesPage.
+const (
+	defaultListPageSize = 1000
+	maxListPageSize     = 10000
+)
+
+// ListFilesPage lists stored file identifiers in key order, at most one page
+// at a time. Keys are ordered by path component, which is the order WalkDir
+// visits them, so each page resumes the walk after the cursor and skips the
+// directories that come before it.
+func (ss *StorageServer) ListFilesPage(ctx context.Context, req *proto.ListFilesRequest) (*proto.ListFilesResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultListPageSize
+	}
+	limit = min(limit, maxListPageSize)
+	var cursor []string
+	if req.Cursor != "" {
+		cursor = strings.Split(req.Cursor, "/")
+	}
+
+	entries := make([]*proto.FileEntry, 0, min(limit, 64))
+	more := false
+	err := filepath.WalkDir(ss.basePath, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == ss.basePath {
+			return nil
+		}
+		if isInternalFile(path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(ss.basePath, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(relativePath), "/")
+		if entry.IsDir() {
+			if !strings.HasPrefix(parts[0], req.VideoIdPrefix) {
+				return filepath.SkipDir
+			}
+			if n := min(len(parts), len(cursor)); slices.Compare(parts[:n], cursor[:n]) < 0 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(parts) < 2 || cursor != nil && slices.Compare(parts, cursor) <= 0 {
+			return nil
+		}
+		if len(entries) == limit {
+			more = true
+			return filepath.SkipAll
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &proto.FileEntry{
+			VideoId:  parts[0],
+			Filename: strings.Join(parts[1:], "/"),
+			Size:     info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return &proto.ListFilesResponse{Entries: entries}, err
+	}
+
+	response := &proto.ListFilesResponse{Entries: entries}
+	if more {
+		last := entries[len(entries)-1]
+		response.NextCursor = last.VideoId + "/" + last.Filename
+	}
+	return response, nil
+}
+
+// ListFilesInRange lists the stored files whose ring hash lies in the
+// requested interval, one page at a time, from the hash index.
+func (ss *StorageServer) ListFilesInRange(ctx context.Context, req *proto.ListFilesInRangeRequest) (*proto.ListFilesResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultListPageSize
+	}
+	limit = min(limit, maxListPageSize)
+	var after *indexEntry
+	if req.Cursor != "" {
+		var err error
+		if after, err = parseIndexCursor(req.Cursor); err != nil {
+			return &proto.ListFilesResponse{}, err
+		}
+	}
+
+	indexed, more := ss.index.list(req.Start, req.End, after, limit)
+	response := &proto.ListFilesResponse{Entries: make([]*proto.FileEntry, 0, len(indexed))}
+	for _, entry := range indexed {
+		videoID, filename, _ := strings.Cut(entry.key, "/")
+		response.Entries = append(response.Entries, &proto.FileEntry{
+			VideoId:  videoID,
+			Filename: filename,
+			Size:     entry.size,
+		})
+	}
+	if more {
+		response.NextCursor = indexCursor(indexed[len(indexed)-1])
+	}
+	return response, nil
+}
+
+// DeleteFile removes a stored file, then removes its directory and any parent
+// directories up to the storage directory once they are empty. A quarantined
+// copy of the file is removed as well. A missing file is not an error, so a
+// deletion that failed part way can be repeated.
+func (ss *StorageServer) DeleteFile(ctx context.Context, req *proto.DeleteFileRequest) (*proto.DeleteFileResponse, error) {
+	filePath, err := ss.filePath(req.VideoId, req.Filename)
+	if err != nil {
+		return &proto.DeleteFileResponse{}, err
+	}
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	deleted := true
+	if err := os.Remove(filePath); errors.Is(err, fs.ErrNotExist) {
+		deleted = false
+	} else if err != nil {
+		log.Printf("Storage: Delete file failed: %v\n", err)
+		return &proto.DeleteFileResponse{}, err
+	}
+	if err := removeChecksum(filePath); err != nil {
+		log.Printf("Storage: Delete checksum failed: %v\n", err)
+		return &proto.DeleteFileResponse{Deleted: deleted}, err
+	}
+	key, err := ss.storedKey(filePath)
+	if err != nil {
+		return &proto.DeleteFileResponse{Deleted: deleted}, err
+	}
+	ss.index.remove(key)
+
+	basePath, err := filepath.Abs(ss.basePath)
+	if err != nil {
+		return &proto.DeleteFileResponse{Deleted: deleted}, err
+	}
+	if err := removeEmptyDirs(basePath, filepath.Dir(filePath)); err != nil {
+		log.Printf("Storage: Remove empty directory failed: %v\n", err)
+		return &proto.DeleteFileResponse{Deleted: deleted}, err
+	}
+	if err := ss.removeQuarantined(filePath); err != nil {
+		log.Printf("Storage: Delete quarantined file failed: %v\n", err)
+		return &proto.DeleteFileResponse{Deleted: deleted}, err
+	}
+
+	return &proto.DeleteFileResponse{Deleted: deleted}, nil
+}
+
+// removeEmptyDirs removes dir and then each parent below basePath, stopping
+// at the first directory that is not empty.
+func removeEmptyDirs(basePath, dir string) error {
+	for ; dir != basePath; dir = filepath.Dir(dir) {
+		err := os.Remove(dir)
+		if isDirectoryNotEmpty(err) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedKey returns the "videoId/filename" key of a stored file's path.
+func (ss *StorageServer) storedKey(path string) (string, error) {
+	basePath, err := filepath.Abs(ss.basePath)
+	if err != nil {
+		return "", err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	relativePath, err := filepath.Rel(basePath, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relativePath), nil
+}
+
+// videoDir returns the directory that holds a video's files.
+func (ss *StorageServer) videoDir(videoID string) (string, error) {
+	if videoID == "" || videoID == "." || videoID == ".." || strings.ContainsAny(videoID, `/\`) {
+		return "", fmt.Errorf("invalid video ID %q", videoID)
+	}
+	return filepath.Join(ss.basePath, videoID), nil
+}
+
 func (ss *StorageServer) filePath(videoID, filename string) (string, error) {
 	if videoID == "" || filename == "" {
 		return "", fmt.Errorf("video ID and filename must not be empty")
 	}
+	if isInternalFile(videoID) || isInternalFile(filename) {
+		return "", fmt.Errorf("names starting with %q are reserved", internalFilePrefix)
+	}
 
 	basePath, err := filepath.Abs(ss.basePath)
 	if err != nil {
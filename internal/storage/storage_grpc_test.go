@@ -3,6 +3,8 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net"
 	"testing"
 
@@ -195,3 +197,60 @@ func TestStorageGRPCReadMissingFile(t *testing.T) {
 		t.Fatal("ReadFile expected an error for a missing file")
 	}
 }
+
+func TestStorageGRPCStreamLargeFile(t *testing.T) {
+	client := newGRPCStorageClient(t)
+	data := bytes.Repeat([]byte("0123456789abcdef"), (proto.StreamChunkSize*5+123)/16)
+
+	writeStream, err := client.WriteFileStream(t.Context())
+	if err != nil {
+		t.Fatalf("WriteFileStream RPC failed: %v", err)
+	}
+	for start := 0; start < len(data); start += proto.StreamChunkSize {
+		chunk := &proto.FileChunk{Data: data[start:min(start+proto.StreamChunkSize, len(data))]}
+		if start == 0 {
+			chunk.VideoId, chunk.Filename = "video-123", "original.mp4"
+		}
+		if err := writeStream.Send(chunk); err != nil {
+			t.Fatalf("send chunk: %v", err)
+		}
+	}
+	if _, err := writeStream.CloseAndRecv(); err != nil {
+		t.Fatalf("WriteFileStream close failed: %v", err)
+	}
+
+	listResponse, err := client.ListFiles(t.Context(), &proto.BatchReadRequest{})
+	if err != nil {
+		t.Fatalf("ListFiles RPC failed: %v", err)
+	}
+	if len(listResponse.Entries) != 1 || listResponse.Entries[0].Size != int64(len(data)) {
+		t.Fatalf("ListFiles entries = %v, want one entry of size %d", listResponse.Entries, len(data))
+	}
+
+	readStream, err := client.ReadFileStream(t.Context(), &proto.ReadRangeRequest{
+		VideoId: "video-123", Filename: "original.mp4", Offset: 10, Length: -1,
+	})
+	if err != nil {
+		t.Fatalf("ReadFileStream RPC failed: %v", err)
+	}
+	var received []byte
+	for {
+		chunk, err := readStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("receive chunk: %v", err)
+		}
+		if len(received) == 0 && chunk.Size != int64(len(data)) {
+			t.Fatalf("first chunk size = %d, want %d", chunk.Size, len(data))
+		}
+		if len(chunk.Data) > proto.StreamChunkSize {
+			t.Fatalf("chunk holds %d bytes, want at most %d", len(chunk.Data), proto.StreamChunkSize)
+		}
+		received = append(received, chunk.Data...)
+	}
+	if !bytes.Equal(received, data[10:]) {
+		t.Fatalf("ReadFileStream returned %d bytes, want %d", len(received), len(data)-10)
+	}
+}
@@ -2,13 +2,19 @@ package storage
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 	"tritontube/internal/proto"
+
+	"google.golang.org/grpc"
 )
 
 func newServer(t *testing.T) *StorageServer {
@@ -265,6 +271,52 @@ func TestReadFileError(t *testing.T) {
 	}
 }
 
+func TestReadFileRange(t *testing.T) {
+	server := newServer(t)
+	content := []byte("0123456789")
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "abc123", Filename: "chunk.m4s", Data: content,
+	}); err != nil {
+		t.Fatalf("Write File Error: %v\n", err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int64
+		want   string
+	}{
+		{name: "bounded", offset: 2, length: 3, want: "234"},
+		{name: "to end", offset: 7, length: -1, want: "789"},
+		{name: "clamped length", offset: 8, length: 100, want: "89"},
+		{name: "suffix", offset: -4, length: -1, want: "6789"},
+		{name: "suffix longer than file", offset: -50, length: -1, want: "0123456789"},
+		{name: "past end", offset: 10, length: 1, want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := server.ReadFileRange(t.Context(), &proto.ReadRangeRequest{
+				VideoId: "abc123", Filename: "chunk.m4s", Offset: test.offset, Length: test.length,
+			})
+			if err != nil {
+				t.Fatalf("ReadFileRange failed: %v", err)
+			}
+			if string(response.Data) != test.want {
+				t.Fatalf("ReadFileRange data = %q, want %q", response.Data, test.want)
+			}
+			if response.Size != int64(len(content)) {
+				t.Fatalf("ReadFileRange size = %d, want %d", response.Size, len(content))
+			}
+		})
+	}
+
+	if _, err := server.ReadFileRange(t.Context(), &proto.ReadRangeRequest{
+		VideoId: "abc123", Filename: "missing.m4s", Length: -1,
+	}); err == nil {
+		t.Fatal("ReadFileRange expected an error for a missing file")
+	}
+}
+
 func TestWriteFileRejectsInvalidPath(t *testing.T) {
 	server := newServer(t)
 
@@ -419,3 +471,582 @@ func TestReadFilesSameVideoAndNestedFilename(t *testing.T) {
 		t.Fatalf("ReadFiles did not return %d expected files\n", len(expected))
 	}
 }
+
+func TestDeleteFileRemovesEmptyDirectories(t *testing.T) {
+	server := newServer(t)
+	entries := []*proto.FileEntry{
+		{VideoId: "video-a", Filename: "manifest.mpd", Data: []byte("manifest")},
+		{VideoId: "video-a", Filename: filepath.Join("nested", "init.m4s"), Data: []byte("init")},
+		{VideoId: "video-b", Filename: "manifest.mpd", Data: []byte("other")},
+	}
+	if _, err := server.WriteFiles(t.Context(), &proto.BatchWriteRequest{Entries: entries}); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	listed, err := server.ListFiles(t.Context(), &proto.BatchReadRequest{VideoId: "video-a"})
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(listed.Entries) != 2 {
+		t.Fatalf("ListFiles for video-a returned %d entries, want 2", len(listed.Entries))
+	}
+
+	for _, entry := range listed.Entries {
+		response, err := server.DeleteFile(t.Context(), &proto.DeleteFileRequest{
+			VideoId: entry.VideoId, Filename: entry.Filename,
+		})
+		if err != nil {
+			t.Fatalf("DeleteFile %s failed: %v", entry.Filename, err)
+		}
+		if !response.Deleted {
+			t.Fatalf("DeleteFile %s reported nothing deleted", entry.Filename)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(server.basePath, "video-a")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("video directory still exists after deleting its files: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(server.basePath, "video-b", "manifest.mpd")); err != nil {
+		t.Fatalf("other video's file was removed: %v", err)
+	}
+
+	// Repeating the deletion succeeds without deleting anything.
+	response, err := server.DeleteFile(t.Context(), &proto.DeleteFileRequest{VideoId: "video-a", Filename: "manifest.mpd"})
+	if err != nil || response.Deleted {
+		t.Fatalf("repeated DeleteFile = %+v, %v; want nothing deleted", response, err)
+	}
+	listed, err = server.ListFiles(t.Context(), &proto.BatchReadRequest{VideoId: "video-a"})
+	if err != nil || len(listed.Entries) != 0 {
+		t.Fatalf("ListFiles for deleted video = %v, %v; want no entries", listed.GetEntries(), err)
+	}
+}
+
+func TestDeleteFileRejectsInvalidPath(t *testing.T) {
+	server := newServer(t)
+
+	if _, err := server.DeleteFile(t.Context(), &proto.DeleteFileRequest{VideoId: "..", Filename: "x"}); err == nil {
+		t.Fatal("DeleteFile accepted a path outside the storage directory")
+	}
+	if _, err := server.ListFiles(t.Context(), &proto.BatchReadRequest{VideoId: "../other"}); err == nil {
+		t.Fatal("ListFiles accepted a video ID outside the storage directory")
+	}
+}
+
+// failingWriteStream delivers its chunks and then fails as a cancelled client
+// stream would.
+type failingWriteStream struct {
+	grpc.ServerStream
+	chunks []*proto.FileChunk
+}
+
+func (stream *failingWriteStream) Recv() (*proto.FileChunk, error) {
+	if len(stream.chunks) == 0 {
+		return nil, context.Canceled
+	}
+	chunk := stream.chunks[0]
+	stream.chunks = stream.chunks[1:]
+	return chunk, nil
+}
+
+func (stream *failingWriteStream) SendAndClose(*proto.WriteResponse) error {
+	return errors.New("stream failed before it was closed")
+}
+
+func storedNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read %s: %v", dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestWritesLeaveOnlyCompleteFiles(t *testing.T) {
+	server := newServer(t)
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Data: []byte("complete"),
+	}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	path := filepath.Join(server.basePath, "video-a", "chunk.m4s")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat written file: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Fatalf("file mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	err = server.WriteFileStream(&failingWriteStream{chunks: []*proto.FileChunk{
+		{VideoId: "video-a", Filename: "chunk.m4s", Data: []byte("partial")},
+	}})
+	if err == nil {
+		t.Fatal("WriteFileStream succeeded although the stream failed")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "complete" {
+		t.Fatalf("file after failed stream = %q, want the previous contents", data)
+	}
+	want := []string{checksumFilePrefix + "chunk.m4s", "chunk.m4s"}
+	if names := storedNames(t, filepath.Dir(path)); !slices.Equal(names, want) {
+		t.Fatalf("video directory holds %v, want %v", names, want)
+	}
+}
+
+func TestIncompleteFilesAreHiddenAndRemovedOnStartup(t *testing.T) {
+	baseDir := t.TempDir()
+	videoDir := filepath.Join(baseDir, "video-a")
+	if err := os.MkdirAll(videoDir, 0755); err != nil {
+		t.Fatalf("create video directory: %v", err)
+	}
+	leftover := filepath.Join(videoDir, tempFilePrefix+"chunk.m4s-123")
+	for path, data := range map[string]string{
+		filepath.Join(videoDir, "manifest.mpd"): "manifest",
+		leftover:                                "partial",
+	} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+
+	listed, err := (&StorageServer{basePath: baseDir}).ListFiles(t.Context(), &proto.BatchReadRequest{})
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(listed.Entries) != 1 || listed.Entries[0].Filename != "manifest.mpd" {
+		t.Fatalf("ListFiles entries = %v, want only manifest.mpd", listed.Entries)
+	}
+
+	NewStorageServer(baseDir)
+	if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("incomplete file survived startup: %v", err)
+	}
+	if names := storedNames(t, videoDir); len(names) != 1 || names[0] != "manifest.mpd" {
+		t.Fatalf("video directory holds %v, want only manifest.mpd", names)
+	}
+
+	if _, err := NewStorageServer(baseDir).WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: tempFilePrefix + "x", Data: []byte("x"),
+	}); err == nil {
+		t.Fatal("WriteFile accepted a reserved filename")
+	}
+}
+
+func TestChecksumsAreVerifiedOnWriteAndRead(t *testing.T) {
+	server := newServer(t)
+	data := []byte("segment data")
+
+	_, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Data: data, Sha256: Checksum([]byte("other data")),
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("WriteFile with a wrong checksum = %v, want ErrChecksumMismatch", err)
+	}
+	path := filepath.Join(server.basePath, "video-a", "chunk.m4s")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("rejected write created the file: %v", err)
+	}
+
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Data: data, Sha256: Checksum(data),
+	}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	read, err := server.ReadFile(t.Context(), &proto.ReadRequest{VideoId: "video-a", Filename: "chunk.m4s"})
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(read.Sha256, Checksum(data)) {
+		t.Fatalf("ReadFile checksum = %x, want %x", read.Sha256, Checksum(data))
+	}
+
+	// Flip one byte on disk, as silent corruption would.
+	corrupted := slices.Clone(data)
+	corrupted[0] ^= 0xff
+	if err := os.WriteFile(path, corrupted, 0644); err != nil {
+		t.Fatalf("corrupt file: %v", err)
+	}
+	if _, err := server.ReadFile(t.Context(), &proto.ReadRequest{VideoId: "video-a", Filename: "chunk.m4s"}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ReadFile of a corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := server.ReadFiles(t.Context(), &proto.BatchReadRequest{Requests: []*proto.ReadRequest{
+		{VideoId: "video-a", Filename: "chunk.m4s"},
+	}}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ReadFiles of a corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := server.ReadFileRange(t.Context(), &proto.ReadRangeRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Length: -1,
+	}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ReadFileRange of a whole corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := server.ReadFileRange(t.Context(), &proto.ReadRangeRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Offset: 2, Length: 3,
+	}); err != nil {
+		t.Fatalf("ReadFileRange of part of a file is not verified but failed: %v", err)
+	}
+}
+
+func TestFilesWithoutChecksumsAreStillReadable(t *testing.T) {
+	server := newServer(t)
+	videoDir := filepath.Join(server.basePath, "video-a")
+	if err := os.MkdirAll(videoDir, 0755); err != nil {
+		t.Fatalf("create video directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(videoDir, "chunk.m4s"), []byte("legacy"), 0644); err != nil {
+		t.Fatalf("write legacy file: %v", err)
+	}
+
+	read, err := server.ReadFile(t.Context(), &proto.ReadRequest{VideoId: "video-a", Filename: "chunk.m4s"})
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(read.Data) != "legacy" || len(read.Sha256) != 0 {
+		t.Fatalf("ReadFile = %q with checksum %x, want legacy data without a checksum", read.Data, read.Sha256)
+	}
+}
+
+func TestStatFilesReportsSizeAndChecksumOfStoredFiles(t *testing.T) {
+	server := newServer(t)
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{VideoId: "video-a", Filename: "chunk.m4s", Data: []byte("data")}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	videoDir := filepath.Join(server.basePath, "video-a")
+	if err := os.WriteFile(filepath.Join(videoDir, "legacy.m4s"), []byte("legacy"), 0644); err != nil {
+		t.Fatalf("write legacy file: %v", err)
+	}
+
+	response, err := server.StatFiles(t.Context(), &proto.BatchReadRequest{Requests: []*proto.ReadRequest{
+		{VideoId: "video-a", Filename: "chunk.m4s"},
+		{VideoId: "video-a", Filename: "missing.m4s"},
+		{VideoId: "video-a", Filename: "legacy.m4s"},
+	}})
+	if err != nil {
+		t.Fatalf("StatFiles failed: %v", err)
+	}
+	if len(response.Entries) != 2 {
+		t.Fatalf("StatFiles returned %d entries, want the 2 stored files", len(response.Entries))
+	}
+	stored, legacy := response.Entries[0], response.Entries[1]
+	if stored.Filename != "chunk.m4s" || stored.Size != 4 || !bytes.Equal(stored.Sha256, Checksum([]byte("data"))) || stored.Data != nil {
+		t.Fatalf("StatFiles(chunk.m4s) = %s, %d bytes, checksum %x, data %q", stored.Filename, stored.Size, stored.Sha256, stored.Data)
+	}
+	if legacy.Filename != "legacy.m4s" || legacy.Size != 6 || len(legacy.Sha256) != 0 {
+		t.Fatalf("StatFiles(legacy.m4s) = %s, %d bytes, checksum %x; want no checksum", legacy.Filename, legacy.Size, legacy.Sha256)
+	}
+}
+
+type recordingReadStream struct {
+	grpc.ServerStream
+	data []byte
+}
+
+func (stream *recordingReadStream) Send(chunk *proto.FileChunk) error {
+	stream.data = append(stream.data, chunk.Data...)
+	return nil
+}
+
+func TestReadFileStreamFailsAfterCorruptedFile(t *testing.T) {
+	server := newServer(t)
+	data := bytes.Repeat([]byte("x"), proto.StreamChunkSize+10)
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "original.mp4", Data: data,
+	}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	request := &proto.ReadRangeRequest{VideoId: "video-a", Filename: "original.mp4", Length: -1}
+
+	if err := server.ReadFileStream(request, &recordingReadStream{}); err != nil {
+		t.Fatalf("ReadFileStream of an intact file failed: %v", err)
+	}
+
+	path := filepath.Join(server.basePath, "video-a", "original.mp4")
+	data[len(data)-1] = 'y'
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("corrupt file: %v", err)
+	}
+	stream := &recordingReadStream{}
+	if err := server.ReadFileStream(request, stream); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ReadFileStream of a corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	if len(stream.data) != len(data) {
+		t.Fatalf("stream sent %d bytes before failing, want %d", len(stream.data), len(data))
+	}
+}
+
+func TestScrubRecordsChecksumsAndQuarantinesCorruptedFiles(t *testing.T) {
+	server := newServer(t)
+	for _, filename := range []string{"manifest.mpd", "chunk.m4s"} {
+		if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+			VideoId: "video-a", Filename: filename, Data: []byte("contents of " + filename),
+		}); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	// A file stored before checksums existed.
+	legacyDir := filepath.Join(server.basePath, "video-b")
+	if err := os.MkdirAll(legacyDir, 0755); err != nil {
+		t.Fatalf("create legacy directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(legacyDir, "legacy.m4s"), []byte("legacy"), 0644); err != nil {
+		t.Fatalf("write legacy file: %v", err)
+	}
+	corruptedPath := filepath.Join(server.basePath, "video-a", "chunk.m4s")
+	if err := os.WriteFile(corruptedPath, []byte("rotten bits"), 0644); err != nil {
+		t.Fatalf("corrupt file: %v", err)
+	}
+
+	result, err := server.Scrub(t.Context(), 0)
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	if want := (ScrubResult{Checked: 3, Recorded: 1, Quarantined: 1}); result != want {
+		t.Fatalf("first Scrub = %+v, want %+v", result, want)
+	}
+	if checksum, err := readChecksum(filepath.Join(legacyDir, "legacy.m4s")); err != nil || !bytes.Equal(checksum, Checksum([]byte("legacy"))) {
+		t.Fatalf("recorded legacy checksum = %x, %v", checksum, err)
+	}
+	if _, err := os.Stat(corruptedPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("corrupted file is still served: %v", err)
+	}
+
+	listed, err := server.ListFiles(t.Context(), &proto.BatchReadRequest{})
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if len(listed.Entries) != 2 {
+		t.Fatalf("ListFiles returned %d entries, want the 2 intact files", len(listed.Entries))
+	}
+
+	status, err := server.ScrubStatus(t.Context(), &proto.ScrubStatusRequest{})
+	if err != nil {
+		t.Fatalf("ScrubStatus failed: %v", err)
+	}
+	if status.Running || status.LastPassFinished == 0 || status.FilesQuarantined != 1 {
+		t.Fatalf("ScrubStatus = %+v, want one finished pass that quarantined one file", status)
+	}
+	if len(status.Quarantined) != 1 || status.Quarantined[0].VideoId != "video-a" || status.Quarantined[0].Filename != "chunk.m4s" {
+		t.Fatalf("quarantined files = %v, want video-a/chunk.m4s", status.Quarantined)
+	}
+
+	result, err = server.Scrub(t.Context(), 0)
+	if err != nil {
+		t.Fatalf("second Scrub failed: %v", err)
+	}
+	if want := (ScrubResult{Checked: 2}); result != want {
+		t.Fatalf("second Scrub = %+v, want %+v", result, want)
+	}
+
+	// Restoring the file from a replica clears it from quarantine.
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Data: []byte("contents of chunk.m4s"),
+	}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	status, err = server.ScrubStatus(t.Context(), &proto.ScrubStatusRequest{})
+	if err != nil {
+		t.Fatalf("ScrubStatus failed: %v", err)
+	}
+	if len(status.Quarantined) != 0 {
+		t.Fatalf("quarantined files after repair = %v, want none", status.Quarantined)
+	}
+}
+
+func TestRateLimiterPacesReads(t *testing.T) {
+	for _, limiter := range []*rateLimiter{nil, {rate: 0, start: time.Now()}} {
+		start := time.Now()
+		for range 10 {
+			if err := limiter.wait(t.Context(), 1<<20); err != nil {
+				t.Fatalf("unlimited wait failed: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Fatalf("unlimited reads took %v", elapsed)
+		}
+	}
+
+	// Four reads of 500 bytes at 10000 bytes per second take 200ms.
+	limiter := &rateLimiter{rate: 10000, start: time.Now()}
+	for range 4 {
+		if err := limiter.wait(t.Context(), 500); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(limiter.start); elapsed < 190*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("2000 bytes at 10000 bytes per second took %v, want about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := limiter.wait(ctx, 10000); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestScrubStopsWhenCancelled(t *testing.T) {
+	server := newServer(t)
+	if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+		VideoId: "video-a", Filename: "chunk.m4s", Data: bytes.Repeat([]byte("x"), 64),
+	}); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// At one byte per second the pass would take a minute.
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := server.Scrub(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Scrub = %v, want %v", err, context.DeadlineExceeded)
+	}
+	status, err := server.ScrubStatus(t.Context(), &proto.ScrubStatusRequest{})
+	if err != nil {
+		t.Fatalf("ScrubStatus failed: %v", err)
+	}
+	if status.Running || status.LastError == "" {
+		t.Fatalf("ScrubStatus = %+v, want a stopped pass with its error", status)
+	}
+}
+
+func TestListFilesPageResumesInKeyOrder(t *testing.T) {
+	server := newServer(t)
+	// "a-b" sorts before "a/..." as a string but after "a" as a directory, and
+	// nested filenames put a directory between plain files.
+	keys := [][2]string{
+		{"a", "chunk-1.m4s"},
+		{"a", "manifest.mpd"},
+		{"a", "sub/x.m4s"},
+		{"a", "sub-2.m4s"},
+		{"a-b", "manifest.mpd"},
+		{"b", "chunk-1.m4s"},
+		{"b", "manifest.mpd"},
+	}
+	for _, key := range keys {
+		if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+			VideoId: key[0], Filename: key[1], Data: []byte(key[1]),
+		}); err != nil {
+			t.Fatalf("WriteFile(%s/%s) failed: %v", key[0], key[1], err)
+		}
+	}
+
+	var listed [][2]string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(keys) {
+			t.Fatalf("listing did not finish after %d pages", pages)
+		}
+		response, err := server.ListFilesPage(t.Context(), &proto.ListFilesRequest{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListFilesPage(%q) failed: %v", cursor, err)
+		}
+		if len(response.Entries) > 2 {
+			t.Fatalf("page has %d entries, want at most 2", len(response.Entries))
+		}
+		for _, entry := range response.Entries {
+			if entry.Size != int64(len(entry.Filename)) || entry.Data != nil {
+				t.Fatalf("entry %s/%s has size %d and %d bytes of data", entry.VideoId, entry.Filename, entry.Size, len(entry.Data))
+			}
+			listed = append(listed, [2]string{entry.VideoId, entry.Filename})
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+
+	want := slices.Clone(keys)
+	slices.SortFunc(want, func(a, b [2]string) int {
+		return slices.Compare(strings.Split(a[0]+"/"+a[1], "/"), strings.Split(b[0]+"/"+b[1], "/"))
+	})
+	if !slices.Equal(listed, want) {
+		t.Fatalf("listed %v, want %v", listed, want)
+	}
+
+	prefixed, err := server.ListFilesPage(t.Context(), &proto.ListFilesRequest{VideoIdPrefix: "a-"})
+	if err != nil {
+		t.Fatalf("ListFilesPage with prefix failed: %v", err)
+	}
+	if len(prefixed.Entries) != 1 || prefixed.Entries[0].VideoId != "a-b" || prefixed.NextCursor != "" {
+		t.Fatalf("prefixed listing = %v (next %q), want only a-b/manifest.mpd", prefixed.Entries, prefixed.NextCursor)
+	}
+}
+
+func TestListFilesInRangeUsesHashIndex(t *testing.T) {
+	baseDir := t.TempDir()
+	server := NewStorageServer(baseDir)
+	keys := make(map[string]bool)
+	for index := range 300 {
+		filename := fmt.Sprintf("chunk-%05d.m4s", index)
+		if _, err := server.WriteFile(t.Context(), &proto.WriteRequest{
+			VideoId: "video", Filename: filename, Data: []byte(filename),
+		}); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		keys["video/"+filename] = true
+	}
+	if _, err := server.DeleteFile(t.Context(), &proto.DeleteFileRequest{VideoId: "video", Filename: "chunk-00007.m4s"}); err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+	delete(keys, "video/chunk-00007.m4s")
+
+	listRange := func(server *StorageServer, start, end uint64) []string {
+		t.Helper()
+		var listed []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(keys) {
+				t.Fatalf("listing [%x, %x) did not finish", start, end)
+			}
+			response, err := server.ListFilesInRange(t.Context(), &proto.ListFilesInRangeRequest{
+				Start: start, End: end, Cursor: cursor, Limit: 7,
+			})
+			if err != nil {
+				t.Fatalf("ListFilesInRange failed: %v", err)
+			}
+			for _, entry := range response.Entries {
+				listed = append(listed, entry.VideoId+"/"+entry.Filename)
+			}
+			if response.NextCursor == "" {
+				return listed
+			}
+			cursor = response.NextCursor
+		}
+	}
+	expected := func(start, end uint64) []string {
+		var want []string
+		for key := range keys {
+			if start == end || RingHash(key)-start < end-start {
+				want = append(want, key)
+			}
+		}
+		slices.SortFunc(want, func(a, b string) int {
+			return cmp.Compare(RingHash(a)-start, RingHash(b)-start)
+		})
+		return want
+	}
+
+	ranges := []struct {
+		name       string
+		start, end uint64
+	}{
+		{"quarter", 1 << 62, 1 << 63},
+		{"wraps past zero", 0xf000000000000000, 0x1000000000000000},
+		{"whole ring", 12345, 12345},
+	}
+	restarted := NewStorageServer(baseDir)
+	for _, tt := range ranges {
+		t.Run(tt.name, func(t *testing.T) {
+			want := expected(tt.start, tt.end)
+			if len(want) == 0 {
+				t.Fatal("test range selects no keys")
+			}
+			if got := listRange(server, tt.start, tt.end); !slices.Equal(got, want) {
+				t.Fatalf("listed %d keys %v, want %d keys %v", len(got), got, len(want), want)
+			}
+			if got := listRange(restarted, tt.start, tt.end); !slices.Equal(got, want) {
+				t.Fatalf("after restart listed %d keys, want %d", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltVideosBucket = []byte("videos")
+	boltJobsBucket   = []byte("jobs")
+	// boltUnfinishedJobsBucket holds the IDs of the jobs that are not
+	// finished yet.
+	boltUnfinishedJobsBucket = []byte("unfinished_jobs")
+)
+
+// boltOpenTimeout bounds the wait for the database file lock, which another
+// process holds while it has the file open.
+const boltOpenTimeout = 5 * time.Second
+
+// BoltVideoMetadataService keeps video and job records in a single bbolt file
+// for single-host setups without etcd. Records are encoded as JSON exactly as
+// in etcd, so CopyMetadata can move them between the two.
+type BoltVideoMetadataService struct {
+	db *bolt.DB
+}
+
+var _ VideoMetadataService = (*BoltVideoMetadataService)(nil)
+
+// NewBoltVideoMetadataService opens the database at path, creating it if it
+// does not exist.
+func NewBoltVideoMetadataService(path string) (*BoltVideoMetadataService, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open metadata database %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		// Databases written before jobs were indexed get their unfinished
+		// jobs indexed once.
+		indexed := tx.Bucket(boltUnfinishedJobsBucket) != nil
+		for _, bucket := range [][]byte{boltVideosBucket, boltJobsBucket, boltUnfinishedJobsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		if !indexed {
+			if err := indexUnfinishedJobs(tx); err != nil {
+				return err
+			}
+		}
+		return pruneFinishedJobs(tx, time.Now())
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("prepare metadata database %s: %w", path, err)
+	}
+	return &BoltVideoMetadataService{db: db}, nil
+}
+
+func (bs *BoltVideoMetadataService) Close() error {
+	return bs.db.Close()
+}
+
+func (bs *BoltVideoMetadataService) Read(videoId string) (*VideoMetadata, error) {
+	var metadata *VideoMetadata
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltVideosBucket).Get([]byte(videoId))
+		if value == nil {
+			return nil
+		}
+		metadata = &VideoMetadata{}
+		if err := json.Unmarshal(value, metadata); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", videoId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (bs *BoltVideoMetadataService) Create(metadata VideoMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltVideosBucket).Put([]byte(metadata.Id), value)
+	})
+}
+
+func (bs *BoltVideoMetadataService) CreateIfAbsent(metadata VideoMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		videos := tx.Bucket(boltVideosBucket)
+		if videos.Get([]byte(metadata.Id)) != nil {
+			return ErrVideoExists
+		}
+		return videos.Put([]byte(metadata.Id), value)
+	})
+}
+
+func (bs *BoltVideoMetadataService) Update(metadata VideoMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		videos := tx.Bucket(boltVideosBucket)
+		if videos.Get([]byte(metadata.Id)) == nil {
+			return ErrVideoNotFound
+		}
+		return videos.Put([]byte(metadata.Id), value)
+	})
+}
+
+func (bs *BoltVideoMetadataService) UpdateIfStatus(metadata VideoMetadata, status VideoStatus) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		videos := tx.Bucket(boltVideosBucket)
+		current := videos.Get([]byte(metadata.Id))
+		if current == nil {
+			return ErrVideoNotFound
+		}
+		var stored VideoMetadata
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", metadata.Id, err)
+		}
+		if stored.Status != status {
+			return ErrVideoStatusChanged
+		}
+		return videos.Put([]byte(metadata.Id), value)
+	})
+}
+
+func (bs *BoltVideoMetadataService) Delete(videoId string) error {
+	err := bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltVideosBucket).Delete([]byte(videoId))
+	})
+	if err != nil {
+		return fmt.Errorf("delete metadata for %s: %w", videoId, err)
+	}
+	return nil
+}
+
+func (bs *BoltVideoMetadataService) DeleteIfStatus(videoId string, status VideoStatus) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		videos := tx.Bucket(boltVideosBucket)
+		current := videos.Get([]byte(videoId))
+		if current == nil {
+			return ErrVideoNotFound
+		}
+		var stored VideoMetadata
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", videoId, err)
+		}
+		if stored.Status != status {
+			return ErrVideoStatusChanged
+		}
+		return videos.Delete([]byte(videoId))
+	})
+}
+
+func (bs *BoltVideoMetadataService) List() ([]VideoMetadata, error) {
+	var results []VideoMetadata
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltVideosBucket).ForEach(func(key, value []byte) error {
+			var metadata VideoMetadata
+			if err := json.Unmarshal(value, &metadata); err != nil {
+				return fmt.Errorf("failed to parse metadata for %s: %w", key, err)
+			}
+			results = append(results, metadata)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (bs *BoltVideoMetadataService) SaveJob(job TranscodeJob) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(boltJobsBucket).Put([]byte(job.Id), value); err != nil {
+			return err
+		}
+		unfinished := tx.Bucket(boltUnfinishedJobsBucket)
+		if !job.Finished() {
+			return unfinished.Put([]byte(job.Id), nil)
+		}
+		if err := unfinished.Delete([]byte(job.Id)); err != nil {
+			return err
+		}
+		return pruneFinishedJobs(tx, time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("save job %s: %w", job.Id, err)
+	}
+	return nil
+}
+
+// indexUnfinishedJobs adds every stored job that is not finished to
+// boltUnfinishedJobsBucket.
+func indexUnfinishedJobs(tx *bolt.Tx) error {
+	unfinished := tx.Bucket(boltUnfinishedJobsBucket)
+	return tx.Bucket(boltJobsBucket).ForEach(func(key, value []byte) error {
+		var job TranscodeJob
+		if err := json.Unmarshal(value, &job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %w", key, err)
+		}
+		if job.Finished() {
+			return nil
+		}
+		return unfinished.Put(key, nil)
+	})
+}
+
+// pruneFinishedJobs deletes the jobs that finished more than
+// finishedJobRetention before now. It runs when the database is opened and
+// whenever a job finishes, so the bucket only holds recent history.
+func pruneFinishedJobs(tx *bolt.Tx, now time.Time) error {
+	jobs := tx.Bucket(boltJobsBucket)
+	var expired [][]byte
+	err := jobs.ForEach(func(key, value []byte) error {
+		var job TranscodeJob
+		if err := json.Unmarshal(value, &job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %w", key, err)
+		}
+		if job.Finished() && now.Sub(job.UpdatedAt) > finishedJobRetention {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Keys stay valid until the transaction ends, but the bucket cannot be
+	// changed while ForEach runs.
+	for _, key := range expired {
+		if err := jobs.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bs *BoltVideoMetadataService) ReadJob(jobId string) (*TranscodeJob, error) {
+	var job *TranscodeJob
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltJobsBucket).Get([]byte(jobId))
+		if value == nil {
+			return nil
+		}
+		job = &TranscodeJob{}
+		if err := json.Unmarshal(value, job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %w", jobId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (bs *BoltVideoMetadataService) ListJobs() ([]TranscodeJob, error) {
+	var jobs []TranscodeJob
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltJobsBucket).ForEach(func(key, value []byte) error {
+			var job TranscodeJob
+			if err := json.Unmarshal(value, &job); err != nil {
+				return fmt.Errorf("failed to parse job %s: %w", key, err)
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (bs *BoltVideoMetadataService) ListUnfinishedJobs() ([]TranscodeJob, error) {
+	var jobs []TranscodeJob
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(boltJobsBucket)
+		return tx.Bucket(boltUnfinishedJobsBucket).ForEach(func(key, _ []byte) error {
+			value := stored.Get(key)
+			if value == nil {
+				return nil
+			}
+			var job TranscodeJob
+			if err := json.Unmarshal(value, &job); err != nil {
+				return fmt.Errorf("failed to parse job %s: %w", key, err)
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
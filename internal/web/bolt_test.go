@@ -0,0 +1,181 @@
+package web
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openBoltTestService(t *testing.T, path string) *BoltVideoMetadataService {
+	t.Helper()
+
+	service, err := NewBoltVideoMetadataService(path)
+	if err != nil {
+		t.Fatalf("NewBoltVideoMetadataService failed: %v", err)
+	}
+	return service
+}
+
+func sortedVideos(videos []VideoMetadata) []VideoMetadata {
+	return slices.SortedFunc(slices.Values(videos), func(a, b VideoMetadata) int { return strings.Compare(a.Id, b.Id) })
+}
+
+func TestBoltVideoMetadataServiceStoresRecordsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	service := openBoltTestService(t, path)
+
+	if metadata, err := service.Read("clip"); metadata != nil || err != nil {
+		t.Fatalf("Read of missing video = %v, %v; want nil, nil", metadata, err)
+	}
+	if err := service.Update(VideoMetadata{Id: "clip"}); !errors.Is(err, ErrVideoNotFound) {
+		t.Fatalf("Update of missing video = %v, want ErrVideoNotFound", err)
+	}
+
+	clip := VideoMetadata{Id: "clip", Title: "Clip", Tags: []string{"demo"}, Status: VideoReady}
+	if err := service.Create(clip); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := service.CreateIfAbsent(VideoMetadata{Id: "clip", Title: "Duplicate"}); !errors.Is(err, ErrVideoExists) {
+		t.Fatalf("CreateIfAbsent of existing video = %v, want ErrVideoExists", err)
+	}
+	if err := service.CreateIfAbsent(VideoMetadata{Id: "other"}); err != nil {
+		t.Fatalf("CreateIfAbsent failed: %v", err)
+	}
+	clip.Title = "Renamed"
+	if err := service.Update(clip); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := service.UpdateIfStatus(VideoMetadata{Id: "clip", Title: "Stale"}, VideoProcessing); !errors.Is(err, ErrVideoStatusChanged) {
+		t.Fatalf("UpdateIfStatus with another status = %v, want ErrVideoStatusChanged", err)
+	}
+	if err := service.UpdateIfStatus(VideoMetadata{Id: "missing"}, VideoReady); !errors.Is(err, ErrVideoNotFound) {
+		t.Fatalf("UpdateIfStatus of missing video = %v, want ErrVideoNotFound", err)
+	}
+	if err := service.UpdateIfStatus(clip, VideoReady); err != nil {
+		t.Fatalf("UpdateIfStatus failed: %v", err)
+	}
+	if err := service.DeleteIfStatus("other", VideoProcessing); !errors.Is(err, ErrVideoStatusChanged) {
+		t.Fatalf("DeleteIfStatus with another status = %v, want ErrVideoStatusChanged", err)
+	}
+	if err := service.DeleteIfStatus("missing", VideoReady); !errors.Is(err, ErrVideoNotFound) {
+		t.Fatalf("DeleteIfStatus of missing video = %v, want ErrVideoNotFound", err)
+	}
+	if err := service.DeleteIfStatus("other", ""); err != nil {
+		t.Fatalf("DeleteIfStatus failed: %v", err)
+	}
+	if err := service.Delete("other"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := service.Delete("other"); err != nil {
+		t.Fatalf("Delete of missing video failed: %v", err)
+	}
+	job := TranscodeJob{Id: "job", VideoId: "clip", State: JobQueued}
+	if err := service.SaveJob(job); err != nil {
+		t.Fatalf("SaveJob failed: %v", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened := openBoltTestService(t, path)
+	defer reopened.Close()
+
+	metadata, err := reopened.Read("clip")
+	if err != nil || metadata == nil || !reflect.DeepEqual(*metadata, clip) {
+		t.Fatalf("Read after reopen = %+v, %v; want %+v", metadata, err, clip)
+	}
+	videos, err := reopened.List()
+	if err != nil || !reflect.DeepEqual(videos, []VideoMetadata{clip}) {
+		t.Fatalf("List after reopen = %+v, %v; want only %s", videos, err, clip.Id)
+	}
+	saved, err := reopened.ReadJob("job")
+	if err != nil || saved == nil || !reflect.DeepEqual(*saved, job) {
+		t.Fatalf("ReadJob after reopen = %+v, %v; want %+v", saved, err, job)
+	}
+	if missing, err := reopened.ReadJob("unknown"); missing != nil || err != nil {
+		t.Fatalf("ReadJob of missing job = %v, %v; want nil, nil", missing, err)
+	}
+	jobs, err := reopened.ListJobs()
+	if err != nil || !reflect.DeepEqual(jobs, []TranscodeJob{job}) {
+		t.Fatalf("ListJobs after reopen = %+v, %v; want only %s", jobs, err, job.Id)
+	}
+}
+
+func TestBoltVideoMetadataServiceListsUnfinishedJobsAndExpiresFinishedOnes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	service := openBoltTestService(t, path)
+
+	// Stored times lose the monotonic clock reading.
+	now := time.Now().UTC().Round(0)
+	running := TranscodeJob{Id: "running", State: JobUploading, UpdatedAt: now}
+	old := TranscodeJob{Id: "old", State: JobFailed, UpdatedAt: now.Add(-finishedJobRetention - time.Minute)}
+	for _, job := range []TranscodeJob{running, old, {Id: "done", State: JobQueued, UpdatedAt: now}} {
+		if err := service.SaveJob(job); err != nil {
+			t.Fatalf("SaveJob(%s) failed: %v", job.Id, err)
+		}
+	}
+	recent := TranscodeJob{Id: "done", State: JobReady, UpdatedAt: now}
+	if err := service.SaveJob(recent); err != nil {
+		t.Fatalf("SaveJob(%s) failed: %v", recent.Id, err)
+	}
+
+	unfinished, err := service.ListUnfinishedJobs()
+	if err != nil || !reflect.DeepEqual(unfinished, []TranscodeJob{running}) {
+		t.Fatalf("ListUnfinishedJobs = %+v, %v; want only %s", unfinished, err, running.Id)
+	}
+	if expired, err := service.ReadJob(old.Id); expired != nil || err != nil {
+		t.Fatalf("ReadJob of a job past its retention = %+v, %v; want nil, nil", expired, err)
+	}
+	if kept, err := service.ReadJob(recent.Id); kept == nil || err != nil {
+		t.Fatalf("ReadJob of a recently finished job = %v, %v; want the job", kept, err)
+	}
+	service.Close()
+
+	reopened := openBoltTestService(t, path)
+	defer reopened.Close()
+	if unfinished, err := reopened.ListUnfinishedJobs(); err != nil || !reflect.DeepEqual(unfinished, []TranscodeJob{running}) {
+		t.Fatalf("ListUnfinishedJobs after reopen = %+v, %v; want only %s", unfinished, err, running.Id)
+	}
+}
+
+func TestCopyMetadataMovesRecordsToBoltAndBack(t *testing.T) {
+	source := newMemoryMetadataService()
+	videos := []VideoMetadata{
+		{Id: "a", Title: "A", Status: VideoReady},
+		{Id: "b", Title: "B", Status: VideoDeleting},
+	}
+	for _, metadata := range videos {
+		source.Create(metadata)
+	}
+	source.SaveJob(TranscodeJob{Id: "job", VideoId: "b", State: JobTranscoding})
+
+	bolt := openBoltTestService(t, filepath.Join(t.TempDir(), "metadata.db"))
+	defer bolt.Close()
+	bolt.Create(VideoMetadata{Id: "a", Title: "Stale"})
+	bolt.Create(VideoMetadata{Id: "kept"})
+
+	copiedVideos, copiedJobs, err := CopyMetadata(bolt, source)
+	if err != nil || copiedVideos != 2 || copiedJobs != 1 {
+		t.Fatalf("CopyMetadata into bolt = %d videos, %d jobs, %v; want 2, 1", copiedVideos, copiedJobs, err)
+	}
+	stored, _ := bolt.List()
+	want := sortedVideos(append(slices.Clone(videos), VideoMetadata{Id: "kept"}))
+	if got := sortedVideos(stored); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bolt records after copy = %+v, want %+v", got, want)
+	}
+
+	back := newMemoryMetadataService()
+	if _, _, err := CopyMetadata(back, bolt); err != nil {
+		t.Fatalf("CopyMetadata out of bolt failed: %v", err)
+	}
+	if !reflect.DeepEqual(back.videos, map[string]VideoMetadata{"a": videos[0], "b": videos[1], "kept": {Id: "kept"}}) {
+		t.Fatalf("records copied back = %+v", back.videos)
+	}
+	if !reflect.DeepEqual(back.jobs, source.jobs) {
+		t.Fatalf("jobs copied back = %+v, want %+v", back.jobs, source.jobs)
+	}
+}
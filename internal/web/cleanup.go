@@ -0,0 +1,335 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+	"tritontube/internal/proto"
+)
+
+// defaultCleanupGracePeriod is how long copies left behind by a ring change
+// are kept unless WithCleanupGracePeriod says otherwise.
+const defaultCleanupGracePeriod = 5 * time.Minute
+
+// WithCleanupGracePeriod sets how long after a ring change is published the
+// nodes that lost ranges keep their copies, so reads that started against the
+// old ring, here or on another web instance, can finish. A negative period
+// keeps the copies for good.
+func WithCleanupGracePeriod(period time.Duration) NetworkOption {
+	return func(ns *NetworkVideoContentService) {
+		ns.cleanupGracePeriod = period
+	}
+}
+
+// scheduleCleanup deletes, once the grace period has passed, the copies that
+// a published ring change left on nodes in drops. The cleanup is recorded in
+// the migration store until it has run, so ResumeCleanups can run it after a
+// restart. Close cancels cleanups that have not run yet.
+func (ns *NetworkVideoContentService) scheduleCleanup(operation string, drops []rangeDrop) {
+	if len(drops) == 0 || ns.cleanupGracePeriod < 0 {
+		return
+	}
+
+	cleanup := PendingCleanup{
+		Id:        newCleanupID(),
+		Operation: operation,
+		Ranges:    make([]CleanupRange, 0, len(drops)),
+		DueAt:     time.Now().Add(ns.cleanupGracePeriod),
+	}
+	for _, drop := range drops {
+		cleanup.Ranges = append(cleanup.Ranges, CleanupRange{Start: drop.start, End: drop.end, Nodes: drop.nodes})
+	}
+	if err := ns.migrations.SaveCleanup(ns.background, cleanup); err != nil {
+		log.Printf("%s cleanup could not be recorded and is lost if this web server restarts: %v", operation, err)
+	}
+	ns.runCleanup(cleanup)
+}
+
+// ResumeCleanups schedules the cleanups recorded in the migration store,
+// which include those a web server stopped before running. Each one runs
+// when its grace period ends, or at once if it already has. Running a cleanup
+// that another web instance also runs is harmless. It returns the number of
+// cleanups scheduled.
+func (ns *NetworkVideoContentService) ResumeCleanups(ctx context.Context) (int, error) {
+	if ns.cleanupGracePeriod < 0 {
+		return 0, nil
+	}
+	cleanups, err := ns.migrations.ListCleanups(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list cleanups: %w", err)
+	}
+	for _, cleanup := range cleanups {
+		ns.runCleanup(cleanup)
+	}
+	return len(cleanups), nil
+}
+
+// runCleanup waits until the cleanup is due, deletes the copies it names and
+// then removes its record. A cleanup that fails keeps its record and is
+// retried the next time a web server starts.
+func (ns *NetworkVideoContentService) runCleanup(cleanup PendingCleanup) {
+	drops := make([]rangeDrop, 0, len(cleanup.Ranges))
+	for _, arc := range cleanup.Ranges {
+		drops = append(drops, rangeDrop{start: arc.Start, end: arc.End, nodes: arc.Nodes})
+	}
+
+	ns.cleanups.Add(1)
+	go func() {
+		defer ns.cleanups.Done()
+
+		timer := time.NewTimer(time.Until(cleanup.DueAt))
+		defer timer.Stop()
+		select {
+		case <-ns.background.Done():
+			return
+		case <-timer.C:
+		}
+
+		count, err := ns.cleanupDroppedRanges(ns.background, drops)
+		if err != nil {
+			log.Printf("%s cleanup deleted %d files before failing: %v", cleanup.Operation, count, err)
+			return
+		}
+		log.Printf("%s cleanup deleted %d files from nodes that no longer own them", cleanup.Operation, count)
+		if err := ns.migrations.DeleteCleanup(ns.background, cleanup.Id); err != nil {
+			log.Printf("Remove record of %s cleanup %s failed: %v", cleanup.Operation, cleanup.Id, err)
+		}
+	}()
+}
+
+func newCleanupID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// cleanupDroppedRanges deletes each dropped range's files from the nodes that
+// dropped it. It runs between membership changes and checks ownership against
+// the ring published by then, so a file is kept when a later change made the
+// node a replica again. Files the target ring of a pending migration places on
+// the node are kept too, since resuming it would not copy them again.
+func (ns *NetworkVideoContentService) cleanupDroppedRanges(ctx context.Context, drops []rangeDrop) (int, error) {
+	ns.membershipMu.Lock()
+	defer ns.membershipMu.Unlock()
+
+	pending, err := ns.migrations.LoadMigration(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("load migration: %w", err)
+	}
+	owners := ns.FindStorageAddrs
+	if pending != nil {
+		pendingIDs, pendingServers := buildRing(pending.Target.Nodes, pending.Target.Weights, ns.virtualNodes)
+		owners = func(key string) []string {
+			return append(ns.FindStorageAddrs(key), findStorageAddrs(key, pendingIDs, pendingServers, ns.replicationFactor)...)
+		}
+	}
+	return ns.deleteUnownedCopies(ctx, drops, owners)
+}
+
+// deleteUnownedCopies lists the files each node in drops holds in the dropped
+// ranges and deletes those that owners does not place on it. A node that
+// fails does not stop the others. It returns the number of files deleted.
+func (ns *NetworkVideoContentService) deleteUnownedCopies(
+	ctx context.Context,
+	drops []rangeDrop,
+	owners func(key string) []string,
+) (int, error) {
+	dropsByNode := make(map[string][]rangeDrop)
+	for _, drop := range drops {
+		for _, node := range drop.nodes {
+			dropsByNode[node] = append(dropsByNode[node], drop)
+		}
+	}
+
+	count := 0
+	var errs []error
+	for node, nodeDrops := range dropsByNode {
+		deleted, err := ns.deleteUnownedCopiesOnNode(ctx, node, nodeDrops, owners)
+		count += deleted
+		if err != nil {
+			errs = append(errs, fmt.Errorf("clean up node %s: %w", node, err))
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+func (ns *NetworkVideoContentService) deleteUnownedCopiesOnNode(
+	ctx context.Context,
+	node string,
+	drops []rangeDrop,
+	owners func(key string) []string,
+) (int, error) {
+	client, closeNode, err := ns.dialNode(ctx, node)
+	if err != nil {
+		return 0, fmt.Errorf("connect: %w", err)
+	}
+	defer closeNode()
+
+	count := 0
+	for _, drop := range drops {
+		// Ring arcs are (start, end]; the storage node takes [start, end).
+		// Listing cursors name the last file returned, so deleting files
+		// does not shift later pages.
+		request := &proto.ListFilesInRangeRequest{Start: drop.start + 1, End: drop.end + 1, Limit: listPageSize}
+		err := forEachFilePage("", func(cursor string) (*proto.ListFilesResponse, error) {
+			request.Cursor = cursor
+			return client.ListFilesInRange(ctx, request)
+		}, func(page []*proto.FileEntry, _ string) error {
+			unowned := make([]*proto.FileEntry, 0, len(page))
+			for _, entry := range page {
+				if !slices.Contains(owners(entry.VideoId+"/"+entry.Filename), node) {
+					unowned = append(unowned, entry)
+				}
+			}
+			held, err := ns.heldByOwners(ctx, node, client, unowned, owners)
+			if err != nil {
+				return err
+			}
+			for _, entry := range held {
+				response, err := client.DeleteFile(ctx, &proto.DeleteFileRequest{VideoId: entry.VideoId, Filename: entry.Filename})
+				if err != nil {
+					return fmt.Errorf("delete %s/%s: %w", entry.VideoId, entry.Filename, err)
+				}
+				if response.GetDeleted() {
+					count++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// heldByOwners returns the entries node can delete because one of their
+// owners holds a copy with the same size and checksum. Writes that reached the
+// old owners after a range was copied, from this web server during a
+// migration or from another one before its ring changed, exist only on node:
+// those are copied to the owners that lack them first. A file that an owner
+// holds with different contents, or that has no checksum to compare, is kept.
+func (ns *NetworkVideoContentService) heldByOwners(
+	ctx context.Context,
+	node string,
+	client storageRPCClient,
+	entries []*proto.FileEntry,
+	owners func(key string) []string,
+) ([]*proto.FileEntry, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	stored, err := statFiles(ctx, client, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	entriesByOwner := make(map[string][]*proto.FileEntry)
+	for _, entry := range entries {
+		for _, owner := range owners(entry.VideoId + "/" + entry.Filename) {
+			entriesByOwner[owner] = append(entriesByOwner[owner], entry)
+		}
+	}
+	// An owner that cannot be asked has no entry, so nothing it may hold is
+	// taken for missing.
+	ownerStats := make(map[string]map[string]*proto.FileEntry, len(entriesByOwner))
+	for owner, ownerEntries := range entriesByOwner {
+		ownerClient, closeOwner, err := ns.dialNode(ctx, owner)
+		if err != nil {
+			log.Printf("Cleanup of %s could not reach owner %s: %v", node, owner, err)
+			continue
+		}
+		stats, err := statFiles(ctx, ownerClient, ownerEntries)
+		closeOwner()
+		if err != nil {
+			log.Printf("Cleanup of %s could not check copies on owner %s: %v", node, owner, err)
+			continue
+		}
+		ownerStats[owner] = stats
+	}
+
+	held := make([]*proto.FileEntry, 0, len(entries))
+	for _, entry := range entries {
+		key := entry.VideoId + "/" + entry.Filename
+		// A file that is gone already needs no copy.
+		if stored[key] == nil {
+			continue
+		}
+		if ns.ensureOwnerCopy(ctx, node, client, stored[key], owners(key), ownerStats) {
+			held = append(held, entry)
+		}
+	}
+	return held, nil
+}
+
+// ensureOwnerCopy reports whether an owner holds the copy of file that node
+// stores, copying it from node to the reachable owners when none of them
+// holds the file at all.
+func (ns *NetworkVideoContentService) ensureOwnerCopy(
+	ctx context.Context,
+	node string,
+	client storageRPCClient,
+	file *proto.FileEntry,
+	owners []string,
+	ownerStats map[string]map[string]*proto.FileEntry,
+) bool {
+	key := file.VideoId + "/" + file.Filename
+	if len(file.Sha256) == 0 {
+		log.Printf("Cleanup kept %s on %s: it has no checksum to compare with its owners' copies", key, node)
+		return false
+	}
+
+	var missing, differing []string
+	for _, owner := range owners {
+		stats, reachable := ownerStats[owner]
+		if !reachable {
+			continue
+		}
+		copied := stats[key]
+		switch {
+		case copied == nil:
+			if !slices.Contains(missing, owner) {
+				missing = append(missing, owner)
+			}
+		case copied.Size == file.Size && bytes.Equal(copied.Sha256, file.Sha256):
+			return true
+		default:
+			differing = append(differing, owner)
+		}
+	}
+	if len(differing) > 0 {
+		log.Printf("Cleanup kept %s on %s: owners %v hold different contents", key, node, differing)
+		return false
+	}
+	if len(missing) == 0 {
+		log.Printf("Cleanup kept %s on %s: none of its owners %v could be checked", key, node, owners)
+		return false
+	}
+
+	copied := false
+	for _, owner := range missing {
+		ownerClient, closeOwner, err := ns.dialNode(ctx, owner)
+		if err != nil {
+			log.Printf("Cleanup could not copy %s from %s to owner %s: %v", key, node, owner, err)
+			continue
+		}
+		_, _, _, err = migrateFilesBatch(ctx, client, ownerClient, []*proto.FileEntry{file})
+		closeOwner()
+		if err != nil {
+			log.Printf("Cleanup could not copy %s from %s to owner %s: %v", key, node, owner, err)
+			continue
+		}
+		log.Printf("Cleanup copied %s from %s to owner %s, which lacked it", key, node, owner)
+		copied = true
+	}
+	if !copied {
+		log.Printf("Cleanup kept %s on %s: no owner holds it", key, node)
+	}
+	return copied
+}
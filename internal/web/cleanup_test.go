@@ -0,0 +1,224 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"tritontube/internal/proto"
+	"tritontube/internal/storage"
+)
+
+func newCleanupTestService(t *testing.T, nodes []string, grace time.Duration) (*NetworkVideoContentService, []ContentFile) {
+	t.Helper()
+
+	service := NewNetworkVideoContentService(nodes, WithVirtualNodes(8), WithCleanupGracePeriod(grace))
+	t.Cleanup(func() { service.Close() })
+
+	files := make([]ContentFile, 0, 100)
+	for index := range 100 {
+		files = append(files, ContentFile{
+			VideoID: "video", Filename: fmt.Sprintf("chunk-%05d.m4s", index), Data: []byte(fmt.Sprint(index)),
+		})
+	}
+	if _, err := service.WriteBatch(files); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+	return service, files
+}
+
+func TestAddNodeDeletesMovedFilesFromPreviousOwner(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	service, files := newCleanupTestService(t, []string{first}, 0)
+
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second})
+	if err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	service.cleanups.Wait()
+
+	moved := int(response.MigratedFileCount)
+	if moved == 0 {
+		t.Fatal("AddNode moved no files")
+	}
+	if count := storedFileCount(t, service, first); count != len(files)-moved {
+		t.Fatalf("previous owner holds %d files after cleanup, want the %d it still owns", count, len(files)-moved)
+	}
+	if count := storedFileCount(t, service, second); count != moved {
+		t.Fatalf("new node holds %d files, want the %d moved", count, moved)
+	}
+	for _, file := range files {
+		data, err := service.Read(file.VideoID, file.Filename)
+		if err != nil || !bytes.Equal(data, file.Data) {
+			t.Fatalf("Read(%s) after cleanup = %q, %v", file.Filename, data, err)
+		}
+	}
+}
+
+func TestRemoveNodeDeletesFilesFromRemovedNode(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	service, files := newCleanupTestService(t, []string{first, second}, 0)
+
+	if _, err := service.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	service.cleanups.Wait()
+
+	if count := storedFileCount(t, service, second); count != 0 {
+		t.Fatalf("removed node holds %d files after cleanup, want none", count)
+	}
+	if count := storedFileCount(t, service, first); count != len(files) {
+		t.Fatalf("remaining node holds %d files, want all %d", count, len(files))
+	}
+}
+
+func TestCleanupWaitsForGracePeriodAndStopsOnClose(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	service, files := newCleanupTestService(t, []string{first}, time.Hour)
+
+	if _, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if count := storedFileCount(t, service, first); count != len(files) {
+		t.Fatalf("previous owner holds %d files during the grace period, want all %d", count, len(files))
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		service.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not cancel the scheduled cleanup")
+	}
+}
+
+func TestCleanupRecordedBeforeRestartIsResumed(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	store := &memoryMigrationStore{}
+	service := NewNetworkVideoContentService([]string{first}, WithVirtualNodes(8),
+		WithCleanupGracePeriod(time.Hour), WithMigrationStore(store))
+	files := make([]ContentFile, 0, 100)
+	for index := range 100 {
+		files = append(files, ContentFile{VideoID: "video", Filename: fmt.Sprintf("chunk-%05d.m4s", index), Data: []byte(fmt.Sprint(index))})
+	}
+	if _, err := service.WriteBatch(files); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second})
+	if err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	// The web server stops during the grace period.
+	service.Close()
+
+	pending, _ := store.ListCleanups(t.Context())
+	if len(pending) != 1 || pending[0].Operation != "AddNode" {
+		t.Fatalf("recorded cleanups = %+v, want the one of AddNode", pending)
+	}
+	pending[0].DueAt = time.Now()
+	store.SaveCleanup(t.Context(), pending[0])
+
+	restarted := NewNetworkVideoContentService([]string{first, second}, WithVirtualNodes(8),
+		WithCleanupGracePeriod(time.Hour), WithMigrationStore(store))
+	t.Cleanup(func() { restarted.Close() })
+	resumed, err := restarted.ResumeCleanups(t.Context())
+	if err != nil || resumed != 1 {
+		t.Fatalf("ResumeCleanups = %d, %v; want 1", resumed, err)
+	}
+	restarted.cleanups.Wait()
+
+	moved := int(response.MigratedFileCount)
+	if count := storedFileCount(t, restarted, first); moved == 0 || count != len(files)-moved {
+		t.Fatalf("previous owner holds %d files after the resumed cleanup, want %d", count, len(files)-moved)
+	}
+	if pending, _ := store.ListCleanups(t.Context()); len(pending) != 0 {
+		t.Fatalf("finished cleanup is still recorded: %+v", pending)
+	}
+}
+
+func TestCleanupKeepsFilesOwnedAgainByLaterRing(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	// Cleanups are run by hand below.
+	service, files := newCleanupTestService(t, []string{first}, -1)
+
+	service.mu.RLock()
+	beforeIDs, beforeServers := service.storageIds, service.storageServers
+	service.mu.RUnlock()
+	if _, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	service.mu.RLock()
+	afterIDs, afterServers := service.storageIds, service.storageServers
+	service.mu.RUnlock()
+	drops := planRangeDrops(beforeIDs, beforeServers, afterIDs, afterServers, service.replicationFactor)
+
+	// The node leaves again before the cleanup of its addition runs.
+	if _, err := service.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	deleted, err := service.cleanupDroppedRanges(t.Context(), drops)
+	if err != nil {
+		t.Fatalf("cleanupDroppedRanges failed: %v", err)
+	}
+	if deleted != 0 {
+		t.Fatalf("cleanup deleted %d files the current ring places on %s", deleted, first)
+	}
+	if count := storedFileCount(t, service, first); count != len(files) {
+		t.Fatalf("owner holds %d files after a stale cleanup, want all %d", count, len(files))
+	}
+}
+
+func TestCleanupKeepsFilesWrittenToPreviousOwnerAfterRingChange(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	// Cleanups are run by hand below.
+	service, _ := newCleanupTestService(t, []string{first}, -1)
+
+	service.mu.RLock()
+	beforeIDs, beforeServers := service.storageIds, service.storageServers
+	service.mu.RUnlock()
+	if _, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	service.mu.RLock()
+	afterIDs, afterServers := service.storageIds, service.storageServers
+	service.mu.RUnlock()
+	drops := planRangeDrops(beforeIDs, beforeServers, afterIDs, afterServers, service.replicationFactor)
+
+	// A web server that has not seen the new ring yet writes a file the new
+	// node owns to the previous owner.
+	late := &proto.FileEntry{VideoId: "video"}
+	for index := 0; ; index++ {
+		late.Filename = fmt.Sprintf("late-%05d.m4s", index)
+		if owners := service.FindStorageAddrs(late.VideoId + "/" + late.Filename); len(owners) == 1 && owners[0] == second {
+			break
+		}
+	}
+	late.Data = []byte("written against the old ring")
+	late.Sha256 = storage.Checksum(late.Data)
+	client, closeNode, err := service.dialNode(t.Context(), first)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", first, err)
+	}
+	defer closeNode()
+	if _, err := client.WriteFiles(t.Context(), &proto.BatchWriteRequest{Entries: []*proto.FileEntry{late}}); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	if _, err := service.cleanupDroppedRanges(t.Context(), drops); err != nil {
+		t.Fatalf("cleanupDroppedRanges failed: %v", err)
+	}
+	data, err := service.Read(late.VideoId, late.Filename)
+	if err != nil || !bytes.Equal(data, late.Data) {
+		t.Fatalf("Read(%s) after cleanup = %q, %v; want the late write", late.Filename, data, err)
+	}
+}
@@ -0,0 +1,29 @@
+package web
+
+import "fmt"
+
+// CopyMetadata copies every video and transcoding job record from source into
+// destination, replacing records with the same ID. Records that only
+// destination holds are kept. It returns the number of videos and jobs copied.
+func CopyMetadata(destination, source VideoMetadataService) (int, int, error) {
+	videos, err := source.List()
+	if err != nil {
+		return 0, 0, fmt.Errorf("list videos: %w", err)
+	}
+	for index, metadata := range videos {
+		if err := destination.Create(metadata); err != nil {
+			return index, 0, fmt.Errorf("copy video %s: %w", metadata.Id, err)
+		}
+	}
+
+	jobs, err := source.ListJobs()
+	if err != nil {
+		return len(videos), 0, fmt.Errorf("list jobs: %w", err)
+	}
+	for index, job := range jobs {
+		if err := destination.SaveJob(job); err != nil {
+			return len(videos), index, fmt.Errorf("copy job %s: %w", job.Id, err)
+		}
+	}
+	return len(videos), len(jobs), nil
+}
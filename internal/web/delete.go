@@ -0,0 +1,109 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"tritontube/internal/proto"
+)
+
+// maxMarkDeletingAttempts bounds how often marking a video as deleting is
+// retried when its status keeps changing between the read and the write.
+const maxMarkDeletingAttempts = 5
+
+// deleteVideo removes a video's stored files and then its metadata record. The
+// record is first marked as deleting, which hides the video and keeps its ID
+// taken, and is only removed once every file is gone. A failure at any step
+// leaves the record in place, so the deletion can simply be repeated.
+func (s *server) deleteVideo(videoId string) (int, error) {
+	start := time.Now()
+	if err := s.markDeleting(videoId); err != nil {
+		return 0, err
+	}
+
+	count, err := s.contentService.Delete(videoId)
+	if err != nil {
+		return count, fmt.Errorf("delete content after %d files: %w", count, err)
+	}
+	if err := s.metadataService.Delete(videoId); err != nil {
+		return count, fmt.Errorf("delete metadata: %w", err)
+	}
+
+	log.Printf("Video delete time: video=%s files=%d duration=%.3f ms",
+		videoId, count, durationMilliseconds(time.Since(start)))
+	return count, nil
+}
+
+// markDeleting sets the status of a video's record to deleting. The record is
+// only written if its status is still the one that was read, so a publish or
+// another status change in between is not overwritten; the record is read
+// again and marked on top of that change instead.
+func (s *server) markDeleting(videoId string) error {
+	for range maxMarkDeletingAttempts {
+		metadata, err := s.metadataService.Read(videoId)
+		if err != nil {
+			return fmt.Errorf("read metadata: %w", err)
+		}
+		if metadata == nil {
+			return ErrVideoNotFound
+		}
+		if metadata.Status == VideoDeleting {
+			return nil
+		}
+
+		status := metadata.Status
+		metadata.Status = VideoDeleting
+		err = s.metadataService.UpdateIfStatus(*metadata, status)
+		if !errors.Is(err, ErrVideoStatusChanged) {
+			if err != nil {
+				return fmt.Errorf("mark video as deleting: %w", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("mark video as deleting: %w after %d attempts", ErrVideoStatusChanged, maxMarkDeletingAttempts)
+}
+
+func (s *server) handleVideoDelete(w http.ResponseWriter, videoId string) {
+	count, err := s.deleteVideo(videoId)
+	if errors.Is(err, ErrVideoNotFound) {
+		http.Error(w, "Video not found: "+videoId, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Delete video %s failed: %v", videoId, err)
+		http.Error(w, "Failed to delete video; retry the request to finish", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		VideoId          string `json:"video_id"`
+		DeletedFileCount int    `json:"deleted_file_count"`
+	}{videoId, count})
+}
+
+// AdminService serves the admin RPCs. Ring changes are handled by the content
+// service; video deletion needs the metadata as well and goes through the web
+// server.
+type AdminService struct {
+	*NetworkVideoContentService
+	server *server
+}
+
+func NewAdminService(content *NetworkVideoContentService, s *server) *AdminService {
+	return &AdminService{NetworkVideoContentService: content, server: s}
+}
+
+func (as *AdminService) DeleteVideo(ctx context.Context, req *proto.DeleteVideoRequest) (*proto.DeleteVideoResponse, error) {
+	if req.VideoId == "" {
+		return &proto.DeleteVideoResponse{}, errors.New("video ID must not be empty")
+	}
+	count, err := as.server.deleteVideo(req.VideoId)
+	if err != nil {
+		return &proto.DeleteVideoResponse{DeletedFileCount: int32(count)}, fmt.Errorf("delete video %s: %w", req.VideoId, err)
+	}
+	return &proto.DeleteVideoResponse{DeletedFileCount: int32(count)}, nil
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tritontube/internal/proto"
+)
+
+func TestDeleteVideoRemovesContentAndMetadata(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{Id: "clip"})
+	content := &recordingContentService{files: map[string][]byte{
+		"clip/manifest.mpd":      []byte("manifest"),
+		"clip/chunk-0-00001.m4s": []byte("segment"),
+		"other/manifest.mpd":     []byte("manifest"),
+	}}
+	s := &server{metadataService: metadata, contentService: content}
+
+	recorder := httptest.NewRecorder()
+	s.handleVideo(recorder, httptest.NewRequest(http.MethodDelete, "/videos/clip", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	var response struct {
+		DeletedFileCount int `json:"deleted_file_count"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.DeletedFileCount != 2 {
+		t.Fatalf("deleted_file_count = %d, want 2", response.DeletedFileCount)
+	}
+	if video, _ := metadata.Read("clip"); video != nil {
+		t.Fatalf("metadata still exists: %+v", video)
+	}
+	if len(content.files) != 1 {
+		t.Fatalf("remaining files = %v, want only the other video", content.files)
+	}
+
+	missing := httptest.NewRecorder()
+	s.handleVideo(missing, httptest.NewRequest(http.MethodDelete, "/videos/clip", nil))
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("second delete status = %d, want %d", missing.Code, http.StatusNotFound)
+	}
+}
+
+func TestFailedDeleteHidesVideoAndCanBeRetried(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{Id: "clip", Title: "Clip"})
+	content := &recordingContentService{
+		files:     map[string][]byte{"clip/manifest.mpd": []byte("manifest")},
+		deleteErr: errors.New("storage node unreachable"),
+	}
+	s := &server{metadataService: metadata, contentService: content}
+
+	if _, err := s.deleteVideo("clip"); err == nil {
+		t.Fatal("deleteVideo succeeded although content deletion failed")
+	}
+	video, _ := metadata.Read("clip")
+	if video == nil || video.Status != VideoDeleting || video.Title != "Clip" {
+		t.Fatalf("metadata after failed delete = %+v, want the record marked deleting", video)
+	}
+
+	page := httptest.NewRecorder()
+	s.handleVideo(page, httptest.NewRequest(http.MethodGet, "/videos/clip", nil))
+	if page.Code != http.StatusNotFound {
+		t.Fatalf("video page status = %d, want %d while deleting", page.Code, http.StatusNotFound)
+	}
+
+	content.deleteErr = nil
+	admin := NewAdminService(nil, s)
+	response, err := admin.DeleteVideo(t.Context(), &proto.DeleteVideoRequest{VideoId: "clip"})
+	if err != nil {
+		t.Fatalf("retried DeleteVideo failed: %v", err)
+	}
+	if response.DeletedFileCount != 1 {
+		t.Fatalf("DeletedFileCount = %d, want 1", response.DeletedFileCount)
+	}
+	if video, _ := metadata.Read("clip"); video != nil {
+		t.Fatalf("metadata still exists after retry: %+v", video)
+	}
+}
+
+func TestMarkDeletingKeepsConcurrentPublish(t *testing.T) {
+	metadata := &interleavingMetadataService{memoryMetadataService: newMemoryMetadataService()}
+	metadata.Create(VideoMetadata{Id: "clip", Status: VideoProcessing})
+	// The transcode job publishes the video between the read and the write.
+	metadata.afterRead = func() {
+		metadata.Update(VideoMetadata{Id: "clip", Status: VideoReady, Duration: 95})
+	}
+	s := &server{metadataService: metadata}
+
+	if err := s.markDeleting("clip"); err != nil {
+		t.Fatalf("markDeleting failed: %v", err)
+	}
+	if video, _ := metadata.Read("clip"); video.Status != VideoDeleting || video.Duration != 95 {
+		t.Fatalf("metadata = %+v, want the published record marked as deleting", *video)
+	}
+}
@@ -3,19 +3,75 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
+// Keys under etcdReservedPrefix belong to TritonTube. Video records live under
+// a configurable prefix, DefaultEtcdVideoPrefix unless changed, and the other
+// keys hold cluster state.
+const (
+	etcdReservedPrefix = "/tritontube/"
+	etcdRingKey        = etcdReservedPrefix + "ring"
+	etcdMigrationKey   = etcdReservedPrefix + "migration"
+	etcdJobPrefix      = etcdReservedPrefix + "jobs/"
+	etcdCleanupPrefix  = etcdReservedPrefix + "cleanups/"
+	// etcdUnfinishedJobPrefix indexes the jobs that are not finished yet.
+	etcdUnfinishedJobPrefix = etcdReservedPrefix + "unfinished-jobs/"
+
+	DefaultEtcdVideoPrefix = etcdReservedPrefix + "videos/"
+
+	membershipWatchRetryDelay = time.Second
+)
+
 type EtcdVideoMetadataService struct {
 	etcdClient *clientv3.Client
+	prefix     string
+}
+
+// EtcdOption configures an EtcdVideoMetadataService at construction time.
+type EtcdOption func(*EtcdVideoMetadataService)
+
+// WithEtcdVideoPrefix stores video records under prefix instead of
+// DefaultEtcdVideoPrefix. The prefix must not overlap the keys that hold
+// cluster state.
+func WithEtcdVideoPrefix(prefix string) EtcdOption {
+	return func(es *EtcdVideoMetadataService) {
+		es.prefix = prefix
+	}
 }
 
 var _ VideoMetadataService = (*EtcdVideoMetadataService)(nil)
 
-func NewEtcdVideoMetadataService(nodes []string) (*EtcdVideoMetadataService, error) {
+// EtcdMembershipStore keeps the storage ring in etcd so every web instance
+// shares one membership and admin changes survive restarts. It also keeps the
+// progress of the ring change being migrated, if any.
+type EtcdMembershipStore struct {
+	etcdClient    *clientv3.Client
+	key           string
+	migrationKey  string
+	cleanupPrefix string
+}
+
+var (
+	_ MembershipStore = (*EtcdMembershipStore)(nil)
+	_ MigrationStore  = (*EtcdMembershipStore)(nil)
+)
+
+func NewEtcdVideoMetadataService(nodes []string, options ...EtcdOption) (*EtcdVideoMetadataService, error) {
+	service := &EtcdVideoMetadataService{prefix: DefaultEtcdVideoPrefix}
+	for _, option := range options {
+		option(service)
+	}
+	if err := validateEtcdVideoPrefix(service.prefix); err != nil {
+		return nil, err
+	}
+
 	client, err := clientv3.New(clientv3.Config{
 		Endpoints: nodes,
 	})
@@ -25,17 +81,40 @@ func NewEtcdVideoMetadataService(nodes []string) (*EtcdVideoMetadataService, err
 		return nil, err
 	}
 
-	return &EtcdVideoMetadataService{
-		etcdClient: client,
-	}, nil
+	service.etcdClient = client
+	return service, nil
+}
+
+// validateEtcdVideoPrefix rejects prefixes whose range would include cluster
+// state, or fall inside it, so List never parses a ring or job as a video.
+func validateEtcdVideoPrefix(prefix string) error {
+	if prefix == "" {
+		return errors.New("etcd video prefix must not be empty")
+	}
+	for _, key := range []string{etcdRingKey, etcdMigrationKey, etcdJobPrefix, etcdCleanupPrefix, etcdUnfinishedJobPrefix} {
+		if strings.HasPrefix(key, prefix) || strings.HasPrefix(prefix, key) {
+			return fmt.Errorf("etcd video prefix %q overlaps cluster state key %q", prefix, key)
+		}
+	}
+	return nil
+}
+
+func (es *EtcdVideoMetadataService) videoKey(videoId string) string {
+	return es.prefix + videoId
 }
 
 func (es *EtcdVideoMetadataService) Close() error {
 	return es.etcdClient.Close()
 }
 
+// Client returns the underlying etcd client so other cluster state can share
+// the metadata connection.
+func (es *EtcdVideoMetadataService) Client() *clientv3.Client {
+	return es.etcdClient
+}
+
 func (es *EtcdVideoMetadataService) Read(videoId string) (*VideoMetadata, error) {
-	res, err := es.etcdClient.Get(context.Background(), videoId)
+	res, err := es.etcdClient.Get(context.Background(), es.videoKey(videoId))
 
 	if err != nil {
 		fmt.Printf("Read Error: %v\n", err)
@@ -56,17 +135,13 @@ func (es *EtcdVideoMetadataService) Read(videoId string) (*VideoMetadata, error)
 	return &metadata, nil
 }
 
-func (es *EtcdVideoMetadataService) Create(videoId string, uploadedAt time.Time) error {
-	metadata := VideoMetadata{
-		Id:         videoId,
-		UploadedAt: uploadedAt,
-	}
+func (es *EtcdVideoMetadataService) Create(metadata VideoMetadata) error {
 	value, err := json.Marshal(metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	_, err = es.etcdClient.Put(context.Background(), videoId, string(value))
+	_, err = es.etcdClient.Put(context.Background(), es.videoKey(metadata.Id), string(value))
 	if err != nil {
 		fmt.Printf("Create Error: %v\n", err)
 		return err
@@ -75,8 +150,129 @@ func (es *EtcdVideoMetadataService) Create(videoId string, uploadedAt time.Time)
 	return nil
 }
 
+func (es *EtcdVideoMetadataService) CreateIfAbsent(metadata VideoMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	key := es.videoKey(metadata.Id)
+	res, err := es.etcdClient.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("create metadata for %s: %w", metadata.Id, err)
+	}
+	if !res.Succeeded {
+		return ErrVideoExists
+	}
+	return nil
+}
+
+func (es *EtcdVideoMetadataService) Update(metadata VideoMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	// A key that was never created has create revision zero.
+	key := es.videoKey(metadata.Id)
+	res, err := es.etcdClient.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("update metadata for %s: %w", metadata.Id, err)
+	}
+	if !res.Succeeded {
+		return ErrVideoNotFound
+	}
+	return nil
+}
+
+// UpdateIfStatus reads the record and writes the new one in a transaction
+// that only succeeds if the key was not modified since the read. A record
+// changed in between is read again.
+func (es *EtcdVideoMetadataService) UpdateIfStatus(metadata VideoMetadata, status VideoStatus) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	key := es.videoKey(metadata.Id)
+	for {
+		res, err := es.etcdClient.Get(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("read metadata for %s: %w", metadata.Id, err)
+		}
+		if len(res.Kvs) == 0 {
+			return ErrVideoNotFound
+		}
+		var stored VideoMetadata
+		if err := json.Unmarshal(res.Kvs[0].Value, &stored); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", metadata.Id, err)
+		}
+		if stored.Status != status {
+			return ErrVideoStatusChanged
+		}
+
+		txn, err := es.etcdClient.Txn(context.Background()).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", res.Kvs[0].ModRevision)).
+			Then(clientv3.OpPut(key, string(value))).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("update metadata for %s: %w", metadata.Id, err)
+		}
+		if txn.Succeeded {
+			return nil
+		}
+	}
+}
+
+func (es *EtcdVideoMetadataService) Delete(videoId string) error {
+	if _, err := es.etcdClient.Delete(context.Background(), es.videoKey(videoId)); err != nil {
+		return fmt.Errorf("delete metadata for %s: %w", videoId, err)
+	}
+	return nil
+}
+
+// DeleteIfStatus reads the record and deletes it in a transaction that only
+// succeeds if the key was not modified since the read. A record changed in
+// between is read again.
+func (es *EtcdVideoMetadataService) DeleteIfStatus(videoId string, status VideoStatus) error {
+	key := es.videoKey(videoId)
+	for {
+		res, err := es.etcdClient.Get(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("read metadata for %s: %w", videoId, err)
+		}
+		if len(res.Kvs) == 0 {
+			return ErrVideoNotFound
+		}
+		var stored VideoMetadata
+		if err := json.Unmarshal(res.Kvs[0].Value, &stored); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", videoId, err)
+		}
+		if stored.Status != status {
+			return ErrVideoStatusChanged
+		}
+
+		txn, err := es.etcdClient.Txn(context.Background()).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", res.Kvs[0].ModRevision)).
+			Then(clientv3.OpDelete(key)).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("delete metadata for %s: %w", videoId, err)
+		}
+		if txn.Succeeded {
+			return nil
+		}
+	}
+}
+
 func (es *EtcdVideoMetadataService) List() ([]VideoMetadata, error) {
-	res, err := es.etcdClient.Get(context.Background(), "", clientv3.WithPrefix())
+	res, err := es.etcdClient.Get(context.Background(), es.prefix, clientv3.WithPrefix())
 
 	if err != nil {
 		fmt.Printf("Create Error: %v\n", err)
@@ -97,3 +293,356 @@ func (es *EtcdVideoMetadataService) List() ([]VideoMetadata, error) {
 	}
 	return results, nil
 }
+
+// MigrateUnprefixedKeys moves video records stored under their bare video ID,
+// as they were before records had a prefix, under the video prefix. Keys that
+// do not hold the record of the video they name are left alone. When a record
+// already exists under the prefix it is kept and the bare key is only
+// removed. Running it again, or from several processes at once, is safe. It
+// lists every key in the cluster, so it is run once by hand through
+// `metadata migrate-prefix` rather than on every start. It returns the number
+// of records moved.
+func (es *EtcdVideoMetadataService) MigrateUnprefixedKeys(ctx context.Context) (int, error) {
+	records, err := es.unprefixedRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, record := range records {
+		// A record changed since it was read is left for the next run.
+		key := record.key
+		target := es.videoKey(key)
+		txn, err := es.etcdClient.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", record.modRevision)).
+			Then(
+				clientv3.OpTxn(
+					[]clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(target), "=", 0)},
+					[]clientv3.Op{clientv3.OpPut(target, string(record.value))},
+					nil,
+				),
+				clientv3.OpDelete(key),
+			).
+			Commit()
+		if err != nil {
+			return moved, fmt.Errorf("move %s: %w", key, err)
+		}
+		if txn.Succeeded && txn.Responses[0].GetResponseTxn().Succeeded {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// UnprefixedVideoIDs returns the IDs of the video records that
+// MigrateUnprefixedKeys would move. Like it, it lists every key in the
+// cluster.
+func (es *EtcdVideoMetadataService) UnprefixedVideoIDs(ctx context.Context) ([]string, error) {
+	records, err := es.unprefixedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.key)
+	}
+	return ids, nil
+}
+
+// unprefixedRecord is a video record stored under its bare video ID, with the
+// revision it was read at.
+type unprefixedRecord struct {
+	key         string
+	value       []byte
+	modRevision int64
+}
+
+// unprefixedRecords reads the video records stored under their bare video ID.
+// Keys are listed without values first, so only candidate records are read.
+func (es *EtcdVideoMetadataService) unprefixedRecords(ctx context.Context) ([]unprefixedRecord, error) {
+	res, err := es.etcdClient.Get(ctx, "", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+
+	var records []unprefixedRecord
+	for _, kv := range res.Kvs {
+		key := string(kv.Key)
+		if !legacyVideoKey(key) {
+			continue
+		}
+		current, err := es.etcdClient.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", key, err)
+		}
+		if len(current.Kvs) == 0 || !legacyVideoRecord(key, current.Kvs[0].Value) {
+			continue
+		}
+		records = append(records, unprefixedRecord{
+			key: key, value: current.Kvs[0].Value, modRevision: current.Kvs[0].ModRevision,
+		})
+	}
+	return records, nil
+}
+
+// legacyVideoKey reports whether key can be a video record written before
+// records had a prefix. Video IDs come from uploaded filenames and never
+// contain a slash, while every other TritonTube key does.
+func legacyVideoKey(key string) bool {
+	return key != "" && !strings.Contains(key, "/")
+}
+
+// legacyVideoRecord reports whether value is the metadata of the video whose
+// ID is key, so unrelated data that happens to share the keyspace is kept.
+func legacyVideoRecord(key string, value []byte) bool {
+	var metadata VideoMetadata
+	return json.Unmarshal(value, &metadata) == nil && metadata.Id == key
+}
+
+// SaveJob writes the job together with its key under etcdUnfinishedJobPrefix
+// while it is unfinished. A finished job loses the index key and its record is
+// attached to a lease, so etcd removes it after finishedJobRetention.
+func (es *EtcdVideoMetadataService) SaveJob(job TranscodeJob) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	key, index := etcdJobPrefix+job.Id, etcdUnfinishedJobPrefix+job.Id
+	ops := []clientv3.Op{clientv3.OpPut(key, string(value)), clientv3.OpPut(index, "")}
+	if job.Finished() {
+		lease, err := es.etcdClient.Grant(context.Background(), int64(finishedJobRetention/time.Second))
+		if err != nil {
+			return fmt.Errorf("save job %s: grant lease: %w", job.Id, err)
+		}
+		ops = []clientv3.Op{clientv3.OpPut(key, string(value), clientv3.WithLease(lease.ID)), clientv3.OpDelete(index)}
+	}
+	if _, err := es.etcdClient.Txn(context.Background()).Then(ops...).Commit(); err != nil {
+		return fmt.Errorf("save job %s: %w", job.Id, err)
+	}
+	return nil
+}
+
+func (es *EtcdVideoMetadataService) ReadJob(jobId string) (*TranscodeJob, error) {
+	res, err := es.etcdClient.Get(context.Background(), etcdJobPrefix+jobId)
+	if err != nil {
+		return nil, fmt.Errorf("read job %s: %w", jobId, err)
+	}
+	if len(res.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var job TranscodeJob
+	if err := json.Unmarshal(res.Kvs[0].Value, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", jobId, err)
+	}
+	return &job, nil
+}
+
+func (es *EtcdVideoMetadataService) ListJobs() ([]TranscodeJob, error) {
+	res, err := es.etcdClient.Get(context.Background(), etcdJobPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("list jobs: %w", err)
+	}
+
+	jobs := make([]TranscodeJob, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		var job TranscodeJob
+		if err := json.Unmarshal(kv.Value, &job); err != nil {
+			return nil, fmt.Errorf("failed to parse job %s: %w", kv.Key, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListUnfinishedJobs reads the jobs named under etcdUnfinishedJobPrefix.
+func (es *EtcdVideoMetadataService) ListUnfinishedJobs() ([]TranscodeJob, error) {
+	res, err := es.etcdClient.Get(context.Background(), etcdUnfinishedJobPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("list unfinished jobs: %w", err)
+	}
+
+	jobs := make([]TranscodeJob, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		job, err := es.ReadJob(strings.TrimPrefix(string(kv.Key), etcdUnfinishedJobPrefix))
+		if err != nil {
+			return nil, err
+		}
+		// The index key and the record change together, so this only skips
+		// records removed by hand.
+		if job == nil || job.Finished() {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+func NewEtcdMembershipStore(client *clientv3.Client) *EtcdMembershipStore {
+	return &EtcdMembershipStore{
+		etcdClient:    client,
+		key:           etcdRingKey,
+		migrationKey:  etcdMigrationKey,
+		cleanupPrefix: etcdCleanupPrefix,
+	}
+}
+
+func (ms *EtcdMembershipStore) Load(ctx context.Context) (*RingMembership, error) {
+	res, err := ms.etcdClient.Get(ctx, ms.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var membership RingMembership
+	if err := json.Unmarshal(res.Kvs[0].Value, &membership); err != nil {
+		return nil, fmt.Errorf("failed to parse ring membership: %w", err)
+	}
+	membership.Revision = res.Kvs[0].ModRevision
+	return &membership, nil
+}
+
+func (ms *EtcdMembershipStore) Save(ctx context.Context, membership RingMembership, expectedRevision int64) (int64, error) {
+	value, err := json.Marshal(membership)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal ring membership: %w", err)
+	}
+
+	// A missing key compares equal to mod revision zero, so the first save
+	// also fails if another instance seeded the ring in the meantime.
+	res, err := ms.etcdClient.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(ms.key), "=", expectedRevision)).
+		Then(clientv3.OpPut(ms.key, string(value))).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !res.Succeeded {
+		return 0, ErrMembershipConflict
+	}
+	return res.Header.Revision, nil
+}
+
+func (ms *EtcdMembershipStore) Watch(ctx context.Context, afterRevision int64, apply func(RingMembership)) {
+	for ctx.Err() == nil {
+		watchCtx, cancelWatch := context.WithCancel(ctx)
+		watchChan := ms.etcdClient.Watch(watchCtx, ms.key, clientv3.WithRev(afterRevision+1))
+		for response := range watchChan {
+			if err := response.Err(); err != nil {
+				log.Printf("etcd: ring membership watch failed: %v", err)
+				break
+			}
+			for _, event := range response.Events {
+				if event.Type != clientv3.EventTypePut {
+					continue
+				}
+				var membership RingMembership
+				if err := json.Unmarshal(event.Kv.Value, &membership); err != nil {
+					log.Printf("etcd: skipping unreadable ring membership: %v", err)
+					continue
+				}
+				membership.Revision = event.Kv.ModRevision
+				afterRevision = membership.Revision
+				apply(membership)
+			}
+		}
+		cancelWatch()
+
+		// The watch ends on compaction or a lost connection. Reload the
+		// current record so no change is missed before watching again.
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(membershipWatchRetryDelay):
+		}
+		if membership, err := ms.Load(ctx); err != nil {
+			log.Printf("etcd: reload ring membership failed: %v", err)
+		} else if membership != nil && membership.Revision > afterRevision {
+			afterRevision = membership.Revision
+			apply(*membership)
+		}
+	}
+}
+
+func (ms *EtcdMembershipStore) LoadMigration(ctx context.Context) (*Migration, error) {
+	res, err := ms.etcdClient.Get(ctx, ms.migrationKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var migration Migration
+	if err := json.Unmarshal(res.Kvs[0].Value, &migration); err != nil {
+		return nil, fmt.Errorf("failed to parse migration: %w", err)
+	}
+	return &migration, nil
+}
+
+func (ms *EtcdMembershipStore) CreateMigration(ctx context.Context, migration Migration) error {
+	value, err := json.Marshal(migration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration: %w", err)
+	}
+
+	res, err := ms.etcdClient.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(ms.migrationKey), "=", 0)).
+		Then(clientv3.OpPut(ms.migrationKey, string(value))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !res.Succeeded {
+		return ErrMigrationPending
+	}
+	return nil
+}
+
+func (ms *EtcdMembershipStore) SaveMigration(ctx context.Context, migration Migration) error {
+	value, err := json.Marshal(migration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration: %w", err)
+	}
+	_, err = ms.etcdClient.Put(ctx, ms.migrationKey, string(value))
+	return err
+}
+
+func (ms *EtcdMembershipStore) DeleteMigration(ctx context.Context) error {
+	_, err := ms.etcdClient.Delete(ctx, ms.migrationKey)
+	return err
+}
+
+func (ms *EtcdMembershipStore) SaveCleanup(ctx context.Context, cleanup PendingCleanup) error {
+	value, err := json.Marshal(cleanup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cleanup: %w", err)
+	}
+	_, err = ms.etcdClient.Put(ctx, ms.cleanupPrefix+cleanup.Id, string(value))
+	return err
+}
+
+func (ms *EtcdMembershipStore) ListCleanups(ctx context.Context) ([]PendingCleanup, error) {
+	res, err := ms.etcdClient.Get(ctx, ms.cleanupPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	cleanups := make([]PendingCleanup, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		var cleanup PendingCleanup
+		if err := json.Unmarshal(kv.Value, &cleanup); err != nil {
+			return nil, fmt.Errorf("failed to parse cleanup %s: %w", kv.Key, err)
+		}
+		cleanups = append(cleanups, cleanup)
+	}
+	return cleanups, nil
+}
+
+func (ms *EtcdMembershipStore) DeleteCleanup(ctx context.Context, id string) error {
+	_, err := ms.etcdClient.Delete(ctx, ms.cleanupPrefix+id)
+	return err
+}
@@ -0,0 +1,37 @@
+package web
+
+import "testing"
+
+func TestValidateEtcdVideoPrefixRejectsClusterStateKeys(t *testing.T) {
+	for _, prefix := range []string{DefaultEtcdVideoPrefix, "/other-app/videos/", "videos/"} {
+		if err := validateEtcdVideoPrefix(prefix); err != nil {
+			t.Errorf("validateEtcdVideoPrefix(%q) = %v, want nil", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"", "/", etcdReservedPrefix, "/tritontube/ri", etcdRingKey, etcdJobPrefix + "videos/", etcdCleanupPrefix, etcdUnfinishedJobPrefix} {
+		if err := validateEtcdVideoPrefix(prefix); err == nil {
+			t.Errorf("validateEtcdVideoPrefix(%q) succeeded, want an overlap error", prefix)
+		}
+	}
+}
+
+func TestLegacyVideoRecordsOnlyMatchBareVideoIDs(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  bool
+	}{
+		{key: "clip", value: `{"video_id":"clip","title":"Clip"}`, want: true},
+		{key: "clip", value: `{"video_id":"other"}`, want: false},
+		{key: "clip", value: `not json`, want: false},
+		{key: "config", value: `{"replicas":3}`, want: false},
+		{key: DefaultEtcdVideoPrefix + "clip", value: `{"video_id":"clip"}`, want: false},
+		{key: etcdRingKey, value: `{"nodes":["localhost:8090"]}`, want: false},
+	}
+	for _, test := range tests {
+		got := legacyVideoKey(test.key) && legacyVideoRecord(test.key, []byte(test.value))
+		if got != test.want {
+			t.Errorf("legacy record %q = %q: got %v, want %v", test.key, test.value, got, test.want)
+		}
+	}
+}
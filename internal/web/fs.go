@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"tritontube/internal/proto"
+	"tritontube/internal/storage"
+)
+
+// FSVideoContentService stores video content in a local directory, for running
+// the web server on a single host without storage nodes or an admin listener.
+// The directory is laid out and checked exactly like a storage node's: the
+// same rules reject unsafe video IDs and filenames, files are written
+// atomically with their SHA-256 checksums and reads verify them.
+type FSVideoContentService struct {
+	files *storage.StorageServer
+}
+
+var _ VideoContentService = (*FSVideoContentService)(nil)
+
+// NewFSVideoContentService stores content under dir, creating it if needed.
+func NewFSVideoContentService(dir string) (*FSVideoContentService, error) {
+	files, err := storage.OpenStorageServer(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FSVideoContentService{files: files}, nil
+}
+
+func (s *FSVideoContentService) Read(videoId string, filename string) ([]byte, error) {
+	response, err := s.files.ReadFile(context.Background(), &proto.ReadRequest{VideoId: videoId, Filename: filename})
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (s *FSVideoContentService) OpenRange(videoId string, filename string, offset, length int64) (io.ReadCloser, int64, []byte, error) {
+	return s.files.OpenFileRange(videoId, filename, offset, length)
+}
+
+func (s *FSVideoContentService) Write(videoId string, filename string, data []byte) error {
+	_, err := s.files.WriteFile(context.Background(), &proto.WriteRequest{VideoId: videoId, Filename: filename, Data: data})
+	return err
+}
+
+// WriteBatch writes files in order and stops at the first failure. It returns
+// the number of files written.
+func (s *FSVideoContentService) WriteBatch(files []ContentFile) (int, error) {
+	entries := make([]*proto.FileEntry, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, &proto.FileEntry{VideoId: file.VideoID, Filename: file.Filename, Data: file.Data})
+	}
+	response, err := s.files.WriteFiles(context.Background(), &proto.BatchWriteRequest{Entries: entries})
+	return int(response.GetCnt()), err
+}
+
+func (s *FSVideoContentService) Delete(videoId string) (int, error) {
+	count := 0
+	err := forEachFilePage("", func(cursor string) (*proto.ListFilesResponse, error) {
+		return s.files.ListFilesPage(context.Background(), &proto.ListFilesRequest{
+			Cursor: cursor, Limit: listPageSize, VideoIdPrefix: videoId,
+		})
+	}, func(page []*proto.FileEntry, _ string) error {
+		for _, entry := range page {
+			if entry.VideoId != videoId {
+				continue
+			}
+			response, err := s.files.DeleteFile(context.Background(), &proto.DeleteFileRequest{VideoId: entry.VideoId, Filename: entry.Filename})
+			if err != nil {
+				return fmt.Errorf("delete %s/%s: %w", entry.VideoId, entry.Filename, err)
+			}
+			if response.GetDeleted() {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("delete files of %s: %w", videoId, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tritontube/internal/storage"
+)
+
+func TestFSVideoContentServiceStoresAndDeletesFiles(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewFSVideoContentService(dir)
+	if err != nil {
+		t.Fatalf("NewFSVideoContentService failed: %v", err)
+	}
+
+	written, err := service.WriteBatch([]ContentFile{
+		{VideoID: "clip", Filename: "manifest.mpd", Data: []byte("manifest")},
+		{VideoID: "clip", Filename: "chunk-0-00001.m4s", Data: []byte("0123456789")},
+	})
+	if err != nil || written != 2 {
+		t.Fatalf("WriteBatch = %d, %v; want 2 files", written, err)
+	}
+	for _, videoID := range []string{"other", "clip-2"} {
+		if err := service.Write(videoID, "manifest.mpd", []byte(videoID)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	data, err := service.Read("clip", "manifest.mpd")
+	if err != nil || string(data) != "manifest" {
+		t.Fatalf("Read = %q, %v; want manifest", data, err)
+	}
+	content, size, checksum, err := service.OpenRange("clip", "chunk-0-00001.m4s", 2, 3)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	ranged, err := io.ReadAll(content)
+	content.Close()
+	if err != nil || string(ranged) != "234" || size != 10 {
+		t.Fatalf("OpenRange(2, 3) = %q of %d bytes, %v; want 234 of 10", ranged, size, err)
+	}
+	if want := storage.Checksum([]byte("0123456789")); !bytes.Equal(checksum, want) {
+		t.Fatalf("OpenRange checksum = %x, want %x", checksum, want)
+	}
+
+	deleted, err := service.Delete("clip")
+	if err != nil || deleted != 2 {
+		t.Fatalf("Delete = %d, %v; want 2 files", deleted, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "clip")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("video directory left after Delete: %v", err)
+	}
+	for _, videoID := range []string{"other", "clip-2"} {
+		if data, err := service.Read(videoID, "manifest.mpd"); err != nil || string(data) != videoID {
+			t.Fatalf("Read of video %s after Delete = %q, %v", videoID, data, err)
+		}
+	}
+}
+
+func TestFSVideoContentServiceRejectsUnsafePaths(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewFSVideoContentService(filepath.Join(dir, "videos"))
+	if err != nil {
+		t.Fatalf("NewFSVideoContentService failed: %v", err)
+	}
+
+	for _, file := range []ContentFile{
+		{VideoID: "..", Filename: "escape.txt"},
+		{VideoID: "clip", Filename: "../../escape.txt"},
+		{VideoID: "clip", Filename: ".tritontube-sha256-manifest.mpd"},
+	} {
+		if err := service.Write(file.VideoID, file.Filename, []byte("data")); err == nil {
+			t.Errorf("Write(%q, %q) succeeded", file.VideoID, file.Filename)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file written outside the content directory: %v", err)
+	}
+}
+
+func TestFSVideoContentServiceDetectsCorruptedFiles(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewFSVideoContentService(dir)
+	if err != nil {
+		t.Fatalf("NewFSVideoContentService failed: %v", err)
+	}
+	if err := service.Write("clip", "chunk.m4s", []byte("original")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "clip", "chunk.m4s"), []byte("modified"), 0644); err != nil {
+		t.Fatalf("corrupt file: %v", err)
+	}
+
+	if _, err := service.Read("clip", "chunk.m4s"); !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("Read of corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	content, _, _, err := service.OpenRange("clip", "chunk.m4s", 0, -1)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	defer content.Close()
+	if _, err := io.ReadAll(content); !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("reading corrupted file through OpenRange = %v, want ErrChecksumMismatch", err)
+	}
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// byteRange is a single range from an HTTP Range header. A negative start
+// marks a suffix range for the last end bytes, and a negative end marks a
+// range that runs to the end of the file.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// parseByteRange parses a Range header that names a single byte range. It
+// reports false for a missing or malformed header, which RFC 9110 lets a
+// server ignore by sending the whole file, and for multi-range requests, which
+// callers detect with multipleRanges.
+func parseByteRange(header string) (byteRange, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return byteRange{}, false
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return byteRange{}, false
+	}
+
+	if first == "" {
+		length, ok := parseRangeNumber(last)
+		if !ok {
+			return byteRange{}, false
+		}
+		return byteRange{start: -1, end: length}, true
+	}
+
+	start, ok := parseRangeNumber(first)
+	if !ok {
+		return byteRange{}, false
+	}
+	if last == "" {
+		return byteRange{start: start, end: -1}, true
+	}
+	end, ok := parseRangeNumber(last)
+	if !ok || end < start {
+		return byteRange{}, false
+	}
+	return byteRange{start: start, end: end}, true
+}
+
+// multipleRanges reports whether a Range header asks for more than one byte
+// range. Such requests would need a multipart/byteranges response, which is
+// not supported, so they are refused rather than answered with the whole file.
+func multipleRanges(header string) bool {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	return ok && strings.Contains(spec, ",")
+}
+
+// contentETag returns a strong entity tag derived from a file's stored
+// checksum, or "" for files stored without one.
+func contentETag(checksum []byte) string {
+	if len(checksum) == 0 {
+		return ""
+	}
+	return `"` + hex.EncodeToString(checksum) + `"`
+}
+
+// ifRangeMatches reports whether an If-Range header lets a range be served for
+// a file with the given entity tag. Only an identical strong tag matches; weak
+// tags and dates never do, since content is served without Last-Modified.
+func ifRangeMatches(header, etag string) bool {
+	return header == "" || (etag != "" && header == etag)
+}
+
+func parseRangeNumber(value string) (int64, bool) {
+	if value == "" || value[0] < '0' || value[0] > '9' {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	return number, err == nil
+}
+
+// storageRange converts the range to the offset and length understood by
+// VideoContentService.OpenRange.
+func (requested byteRange) storageRange() (int64, int64) {
+	switch {
+	case requested.start < 0 && requested.end == 0:
+		// An empty suffix is unsatisfiable; a zero-length read reports the
+		// file size without returning data.
+		return 0, 0
+	case requested.start < 0:
+		return -requested.end, -1
+	case requested.end < 0:
+		return requested.start, -1
+	default:
+		return requested.start, requested.end - requested.start + 1
+	}
+}
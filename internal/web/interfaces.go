@@ -1,18 +1,190 @@
 package web
 
-import "time"
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+)
 
+// VideoMetadata is the record stored for every published video. Only Id and
+// UploadedAt are guaranteed: records written by older servers lack every
+// other field and decode with their zero values.
 type VideoMetadata struct {
+	// Id is generated by the server for new uploads. Videos uploaded by older
+	// servers are keyed by their filename without its extension.
 	Id         string    `json:"video_id"`
 	UploadedAt time.Time `json:"uploaded_at"`
+
+	// OriginalFilename is the name the file was uploaded with. Slug is a
+	// readable form of the title, or of that name, used in page URLs. Both are
+	// empty in records written before IDs were generated.
+	OriginalFilename string `json:"original_filename,omitempty"`
+	Slug             string `json:"slug,omitempty"`
+
+	// Title, Description, Tags and Uploader come from the upload form.
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Uploader    string   `json:"uploader,omitempty"`
+
+	// Duration is in seconds. It and the fields below describe the uploaded
+	// source file as reported by ffprobe; Size is its length in bytes.
+	Duration   float64 `json:"duration,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	VideoCodec string  `json:"video_codec,omitempty"`
+	AudioCodec string  `json:"audio_codec,omitempty"`
+	Size       int64   `json:"size,omitempty"`
+
+	// Status is empty in records written before it existed; DisplayStatus
+	// reports those as ready.
+	Status VideoStatus `json:"status,omitempty"`
+
+	// Poster and Thumbnails name images stored with the video's content.
+	// Records written before thumbnails existed leave them empty.
+	Poster     string           `json:"poster,omitempty"`
+	Thumbnails *ThumbnailSprite `json:"thumbnails,omitempty"`
+}
+
+// VideoStatus is the lifecycle state of a video's record.
+type VideoStatus string
+
+const (
+	// VideoProcessing marks a record created when an upload is accepted. It
+	// reserves the video ID while the upload is transcoded and stored, and is
+	// hidden from viewers until the job publishes the video as ready.
+	VideoProcessing VideoStatus = "processing"
+	VideoReady      VideoStatus = "ready"
+	// VideoDeleting marks a video whose deletion has started. It is hidden
+	// from viewers and its deletion can be retried until the record is gone.
+	VideoDeleting VideoStatus = "deleting"
+)
+
+// DisplayTitle returns the title, or the ID for videos uploaded without one.
+func (metadata VideoMetadata) DisplayTitle() string {
+	if metadata.Title != "" {
+		return metadata.Title
+	}
+	return metadata.Id
+}
+
+// PageURL returns the path of the video's page, ending in its slug when it
+// has one.
+func (metadata VideoMetadata) PageURL() string {
+	page := "/videos/" + url.PathEscape(metadata.Id)
+	if metadata.Slug != "" {
+		page += "/" + metadata.Slug
+	}
+	return page
+}
+
+// Hidden reports whether the video is kept from viewers because it is not
+// published yet or is being deleted.
+func (metadata VideoMetadata) Hidden() bool {
+	return metadata.Status == VideoProcessing || metadata.Status == VideoDeleting
+}
+
+// DisplayStatus returns the status, treating records without one as ready.
+func (metadata VideoMetadata) DisplayStatus() VideoStatus {
+	if metadata.Status == "" {
+		return VideoReady
+	}
+	return metadata.Status
+}
+
+// ThumbnailSprite describes a sprite sheet of frames taken every Interval
+// seconds, laid out left to right in rows of Columns tiles.
+type ThumbnailSprite struct {
+	Filename string  `json:"filename"`
+	Interval float64 `json:"interval"`
+	Count    int     `json:"count"`
+	Columns  int     `json:"columns"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
 }
 
 type VideoMetadataService interface {
 	Read(id string) (*VideoMetadata, error)
 	List() ([]VideoMetadata, error)
-	Create(videoId string, uploadedAt time.Time) error
+	Create(metadata VideoMetadata) error
+	// CreateIfAbsent stores a record only if none exists with the metadata's
+	// ID, atomically, and returns ErrVideoExists otherwise.
+	CreateIfAbsent(metadata VideoMetadata) error
+	// Update replaces the record of an existing video. It returns
+	// ErrVideoNotFound when no record has the metadata's ID.
+	Update(metadata VideoMetadata) error
+	// UpdateIfStatus replaces the record of a video only if its stored status
+	// is still status, atomically. It returns ErrVideoNotFound when no record
+	// has the metadata's ID and ErrVideoStatusChanged when the status differs.
+	UpdateIfStatus(metadata VideoMetadata, status VideoStatus) error
+	// Delete removes a video's record. Deleting a missing record succeeds.
+	Delete(id string) error
+	// DeleteIfStatus removes a video's record only if its stored status is
+	// status, atomically. It returns ErrVideoNotFound when no record has the
+	// ID and ErrVideoStatusChanged when the status differs.
+	DeleteIfStatus(id string, status VideoStatus) error
+
+	// SaveJob creates or replaces the stored state of a transcoding job.
+	SaveJob(job TranscodeJob) error
+	// ReadJob returns a transcoding job, or nil when the ID is unknown.
+	ReadJob(jobId string) (*TranscodeJob, error)
+	// ListJobs returns every stored transcoding job. Finished jobs are removed
+	// finishedJobRetention after they finished.
+	ListJobs() ([]TranscodeJob, error)
+	// ListUnfinishedJobs returns the stored jobs that are neither ready nor
+	// failed, without reading the finished ones.
+	ListUnfinishedJobs() ([]TranscodeJob, error)
+}
+
+// ErrVideoNotFound reports an update of a video that has no metadata record.
+var ErrVideoNotFound = errors.New("video not found")
+
+// ErrVideoExists reports a create-if-absent of a video ID that already has a
+// metadata record.
+var ErrVideoExists = errors.New("video already exists")
+
+// ErrVideoStatusChanged reports a conditional update of a video whose record
+// no longer has the expected status.
+var ErrVideoStatusChanged = errors.New("video status changed")
+
+// JobState is the progress of an upload through the transcoding pipeline.
+type JobState string
+
+const (
+	JobQueued      JobState = "queued"
+	JobTranscoding JobState = "transcoding"
+	JobUploading   JobState = "uploading"
+	JobReady       JobState = "ready"
+	JobFailed      JobState = "failed"
+)
+
+// TranscodeJob tracks one upload from the moment it is accepted until its
+// video is playable or the pipeline fails.
+type TranscodeJob struct {
+	Id       string `json:"job_id"`
+	VideoId  string `json:"video_id"`
+	Filename string `json:"filename,omitempty"`
+	// Owner names the web instance running the job, which is the only one
+	// that recovers it after a restart.
+	Owner     string    `json:"owner,omitempty"`
+	State     JobState  `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Finished reports whether the job has reached ready or failed, which are
+// final.
+func (job TranscodeJob) Finished() bool {
+	return job.State == JobReady || job.State == JobFailed
+}
+
+// finishedJobRetention is how long the record of a ready or failed job is
+// kept, so clients polling it see the outcome, before the store removes it.
+const finishedJobRetention = 24 * time.Hour
+
 type ContentFile struct {
 	VideoID  string
 	Filename string
@@ -21,6 +193,130 @@ type ContentFile struct {
 
 type VideoContentService interface {
 	Read(videoId string, filename string) ([]byte, error)
+	// OpenRange returns a reader over up to length bytes of a file starting at
+	// offset, along with the size and stored SHA-256 of the whole file. The
+	// checksum is empty for files stored without one. A negative offset
+	// selects the last -offset bytes and a negative length reads to the end. A
+	// range that starts past the end yields no data.
+	OpenRange(videoId string, filename string, offset, length int64) (io.ReadCloser, int64, []byte, error)
 	Write(videoId string, filename string, data []byte) error
 	WriteBatch(files []ContentFile) (int, error)
+	// Delete removes every stored file of a video and returns how many files
+	// it removed. Files that are already gone are skipped, so a deletion that
+	// failed part way can be repeated.
+	Delete(videoId string) (int, error)
+}
+
+// RingMembership is the storage ring shared by every web instance. The ring
+// settings are stored with the node list because instances configured with
+// different values would place the same key on different nodes.
+type RingMembership struct {
+	Nodes []string `json:"nodes"`
+	// Weights lists nodes whose weight differs from the default of one.
+	Weights           map[string]uint32 `json:"weights,omitempty"`
+	VirtualNodes      int               `json:"virtual_nodes"`
+	ReplicationFactor int               `json:"replication_factor"`
+
+	// Revision identifies the stored version for compare-and-swap updates. It
+	// is assigned by the store and is not part of the encoded record.
+	Revision int64 `json:"-"`
+}
+
+// ErrMembershipConflict reports that another web instance changed the ring
+// after this instance last read it.
+var ErrMembershipConflict = errors.New("ring membership changed concurrently")
+
+type MembershipStore interface {
+	// Load returns the stored membership, or nil when none has been saved.
+	Load(ctx context.Context) (*RingMembership, error)
+	// Save replaces the stored membership only when its revision still equals
+	// expectedRevision, which is zero before the first save. It returns the
+	// new revision or ErrMembershipConflict.
+	Save(ctx context.Context, membership RingMembership, expectedRevision int64) (int64, error)
+	// Watch calls apply for every membership saved after afterRevision until
+	// ctx is done.
+	Watch(ctx context.Context, afterRevision int64, apply func(RingMembership))
+}
+
+// MigrationOperation names the admin change a migration carries out.
+type MigrationOperation string
+
+const (
+	MigrationAdd      MigrationOperation = "add"
+	MigrationRemove   MigrationOperation = "remove"
+	MigrationReweight MigrationOperation = "reweight"
+)
+
+// Migration records a ring change whose files are being copied, from the
+// moment copying starts until the new ring is published. A record left behind
+// by a failed change lets ResumeMigration continue it or AbandonMigration
+// clean up after it.
+type Migration struct {
+	Operation MigrationOperation `json:"operation"`
+	Node      string             `json:"node"`
+	// Weight is the weight requested by an add or reweight.
+	Weight uint32 `json:"weight,omitempty"`
+	// Base is the ring the files are copied from and Target the ring that is
+	// published once every range has been copied.
+	Base   RingMembership `json:"base"`
+	Target RingMembership `json:"target"`
+	// Ranges holds the progress of each ring range copied so far, keyed by
+	// the range's start and end.
+	Ranges    map[string]RangeProgress `json:"ranges,omitempty"`
+	StartedAt time.Time                `json:"started_at"`
+	// LastError is why the latest attempt stopped, if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// RangeProgress is how far the copy of one ring range has got. Cursor is the
+// source listing cursor of the first page not yet copied.
+type RangeProgress struct {
+	Cursor string `json:"cursor,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+}
+
+// ErrMigrationPending reports a ring change refused because an earlier one
+// has not been resumed or abandoned.
+var ErrMigrationPending = errors.New("an unfinished migration is pending")
+
+// ErrNoMigration reports a resume or abandon with no pending migration.
+var ErrNoMigration = errors.New("no migration is pending")
+
+// PendingCleanup records the copies a published ring change left on nodes
+// that no longer own them, from the moment the change is published until they
+// are deleted, so a restart does not forget them.
+type PendingCleanup struct {
+	Id        string         `json:"id"`
+	Operation string         `json:"operation"`
+	Ranges    []CleanupRange `json:"ranges"`
+	// DueAt is when the grace period ends and the copies are deleted.
+	DueAt time.Time `json:"due_at"`
+}
+
+// CleanupRange is a ring arc (Start, End] whose copies are deleted from Nodes.
+type CleanupRange struct {
+	Start uint64   `json:"start"`
+	End   uint64   `json:"end"`
+	Nodes []string `json:"nodes"`
+}
+
+type MigrationStore interface {
+	// LoadMigration returns the pending migration, or nil when there is none.
+	LoadMigration(ctx context.Context) (*Migration, error)
+	// CreateMigration stores a new migration. It returns ErrMigrationPending
+	// when one is already stored.
+	CreateMigration(ctx context.Context, migration Migration) error
+	// SaveMigration replaces the pending migration's progress.
+	SaveMigration(ctx context.Context, migration Migration) error
+	// DeleteMigration removes the pending migration. Deleting when none is
+	// stored succeeds.
+	DeleteMigration(ctx context.Context) error
+
+	// SaveCleanup stores a pending cleanup under its ID.
+	SaveCleanup(ctx context.Context, cleanup PendingCleanup) error
+	// ListCleanups returns every stored pending cleanup.
+	ListCleanups(ctx context.Context) ([]PendingCleanup, error)
+	// DeleteCleanup removes a pending cleanup. Deleting one that is not
+	// stored succeeds.
+	DeleteCleanup(ctx context.Context, id string) error
 }
@@ -0,0 +1,357 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// videoIDBytes of randomness make 11-character video IDs.
+	videoIDBytes = 8
+	// maxVideoIDAttempts bounds how many generated IDs an upload tries
+	// before giving up because every one was taken.
+	maxVideoIDAttempts = 5
+
+	defaultTranscodeWorkers = 2
+	// transcodeQueueSize bounds the uploads waiting for a worker. Each one
+	// holds its source file on local disk until it runs.
+	transcodeQueueSize = 64
+)
+
+var errTranscodeQueueFull = errors.New("too many uploads are waiting to be transcoded")
+
+// transcodeTask is a queued job together with the local files it works on.
+// metadata holds the details known at upload time; the job adds the rest.
+type transcodeTask struct {
+	job       TranscodeJob
+	metadata  VideoMetadata
+	workDir   string
+	videoPath string
+}
+
+// WithTranscodeWorkers sets how many uploads are transcoded at the same time.
+// Values below one are treated as one.
+func WithTranscodeWorkers(workers int) ServerOption {
+	return func(s *server) {
+		s.transcodeWorkers = max(workers, 1)
+	}
+}
+
+// WithInstanceName sets the name under which the server records its jobs, so
+// that RecoverJobs after a restart finds them. Instances sharing a metadata
+// store need distinct names that stay the same across restarts. The default
+// is the host name.
+func WithInstanceName(name string) ServerOption {
+	return func(s *server) {
+		s.instance = name
+	}
+}
+
+func defaultInstanceName() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// jobWorkDir is the local directory holding a job's upload and output.
+func jobWorkDir(jobId string) string {
+	return filepath.Join(os.TempDir(), "videos", jobId)
+}
+
+func runFFmpeg(args []string) ([]byte, error) {
+	return exec.Command("ffmpeg", args...).CombinedOutput()
+}
+
+// newVideoID returns a random ID made only of characters that are safe in
+// URLs, etcd keys and storage paths.
+func newVideoID() string {
+	id := make([]byte, videoIDBytes)
+	rand.Read(id)
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+func newJobID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (s *server) startTranscodeWorkers() {
+	s.jobs = make(chan transcodeTask, transcodeQueueSize)
+	s.workers.Add(s.transcodeWorkers)
+	for range s.transcodeWorkers {
+		go func() {
+			defer s.workers.Done()
+			for task := range s.jobs {
+				if s.stopping.Load() {
+					s.abandonTask(task, errors.New("web server stopped before the job started"))
+					continue
+				}
+				s.runTranscodeJob(task)
+			}
+		}()
+	}
+}
+
+// reserveVideo creates the record of a new upload as processing, under a
+// newly generated ID, before any work on the upload starts. The metadata
+// service creates it atomically, so no ID is handed to two uploads, even on
+// different web instances; an ID that is taken is replaced by another one.
+func (s *server) reserveVideo(details VideoMetadata) (string, error) {
+	details.Status = VideoProcessing
+	for range maxVideoIDAttempts {
+		details.Id = s.newVideoID()
+		if err := s.metadataService.CreateIfAbsent(details); !errors.Is(err, ErrVideoExists) {
+			return details.Id, err
+		}
+	}
+	return "", fmt.Errorf("every one of %d generated IDs is taken: %w", maxVideoIDAttempts, ErrVideoExists)
+}
+
+// releaseVideo frees the ID reserved for an upload that will not be
+// published. The record is only removed while it is still processing, in one
+// atomic step; one that changed belongs to a deletion or a publish that
+// happened meanwhile and is left to it.
+func (s *server) releaseVideo(videoId string) {
+	err := s.metadataService.DeleteIfStatus(videoId, VideoProcessing)
+	if err != nil && !errors.Is(err, ErrVideoNotFound) && !errors.Is(err, ErrVideoStatusChanged) {
+		log.Printf("Release video ID %s failed: %v", videoId, err)
+	}
+}
+
+// enqueueTranscode stores the job as queued and hands it to a worker. It fails
+// without blocking when the queue is full.
+func (s *server) enqueueTranscode(task transcodeTask) error {
+	if err := s.metadataService.SaveJob(task.job); err != nil {
+		return err
+	}
+	select {
+	case s.jobs <- task:
+		return nil
+	default:
+		s.abandonTask(task, errTranscodeQueueFull)
+		return errTranscodeQueueFull
+	}
+}
+
+// setJobState records a job's progress. Failing to persist the state only
+// affects status reporting, so the pipeline keeps going.
+func (s *server) setJobState(job *TranscodeJob, state JobState, jobErr error) {
+	job.State = state
+	job.UpdatedAt = time.Now()
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+	if err := s.metadataService.SaveJob(*job); err != nil {
+		log.Printf("Save job %s state %s failed: %v", job.Id, state, err)
+	}
+}
+
+// RecoverJobs fails the jobs this instance left unfinished when it last
+// stopped, which no worker will ever pick up again. Each one releases its
+// video ID, removes any files it had started to store and its work
+// directory. It must run before the server accepts uploads, and returns the
+// number of jobs it failed.
+func (s *server) RecoverJobs() (int, error) {
+	jobs, err := s.metadataService.ListUnfinishedJobs()
+	if err != nil {
+		return 0, fmt.Errorf("list unfinished jobs: %w", err)
+	}
+
+	count := 0
+	for _, job := range jobs {
+		if job.Owner != s.instance {
+			continue
+		}
+		if job.State == JobUploading {
+			if deleted, err := s.contentService.Delete(job.VideoId); err != nil {
+				log.Printf("Remove stored files of video %s failed after %d files: %v", job.VideoId, deleted, err)
+			}
+		}
+		s.abandonTask(transcodeTask{job: job, workDir: jobWorkDir(job.Id)}, errors.New("web server restarted before the job finished"))
+		count++
+	}
+	return count, nil
+}
+
+func (s *server) abandonTask(task transcodeTask, err error) {
+	defer os.RemoveAll(task.workDir)
+
+	log.Printf("Transcode job %s for video %s failed: %v", task.job.Id, task.job.VideoId, err)
+	s.releaseVideo(task.job.VideoId)
+	s.setJobState(&task.job, JobFailed, err)
+}
+
+// runTranscodeJob converts an uploaded file into DASH and HLS content, stores
+// it on the content service and publishes the video by marking its reserved
+// record ready. A failed job removes the files it stored and releases the
+// video ID.
+func (s *server) runTranscodeJob(task transcodeTask) {
+	jobStart := time.Now()
+	job := task.job
+	videoId := job.VideoId
+	defer func() {
+		log.Printf("Transcode job total time: job=%s video=%s state=%s duration=%.3f ms",
+			job.Id, videoId, job.State, durationMilliseconds(time.Since(jobStart)))
+	}()
+	defer os.RemoveAll(task.workDir)
+
+	fail := func(err error) {
+		log.Printf("Transcode job %s for video %s failed: %v", job.Id, videoId, err)
+		s.releaseVideo(videoId)
+		s.setJobState(&job, JobFailed, err)
+	}
+
+	s.setJobState(&job, JobTranscoding, nil)
+
+	dashDir := filepath.Join(task.workDir, videoId)
+	if err := os.MkdirAll(dashDir, os.ModePerm); err != nil {
+		fail(fmt.Errorf("create DASH directory: %w", err))
+		return
+	}
+	manifestPath := filepath.Join(dashDir, "manifest.mpd")
+
+	start := time.Now()
+	source, err := s.probeVideo(task.videoPath)
+	if err != nil {
+		fail(fmt.Errorf("read video stream: %w", err))
+		return
+	}
+	renditions := selectRenditions(s.renditions, source.Height)
+	log.Printf("Source video %dx%d, encoding renditions %v", source.Width, source.Height, renditions)
+
+	if output, err := s.runFFmpeg(dashArgs(task.videoPath, manifestPath, renditions, source.HasAudio)); err != nil {
+		log.Printf("FFmpeg output for job %s:\n%s", job.Id, output)
+		fail(fmt.Errorf("generate DASH content: %w", err))
+		return
+	}
+	totalFFmpegTime := time.Since(start)
+	log.Printf("FFmpeg transcoding time: %.3f ms", durationMilliseconds(totalFFmpegTime))
+
+	metadata := task.metadata
+	metadata.Duration = source.Duration
+	metadata.Width, metadata.Height = source.Width, source.Height
+	metadata.VideoCodec, metadata.AudioCodec = source.VideoCodec, source.AudioCodec
+	s.generateThumbnails(task.videoPath, dashDir, source, &metadata)
+
+	s.setJobState(&job, JobUploading, nil)
+
+	start = time.Now()
+	entries, err := os.ReadDir(dashDir)
+	if err != nil {
+		fail(fmt.Errorf("read DASH directory: %w", err))
+		return
+	}
+	totalScanTime := time.Since(start)
+	log.Printf("DASH files scan time: %.3f ms", durationMilliseconds(totalScanTime))
+
+	// From here on files may be stored, and a video that is never published
+	// has nobody else to remove them.
+	failStored := func(err error) {
+		if count, deleteErr := s.contentService.Delete(videoId); deleteErr != nil {
+			log.Printf("Remove stored files of video %s failed after %d files: %v", videoId, count, deleteErr)
+		}
+		fail(err)
+	}
+
+	start = time.Now()
+	fileCount, expectedCount, uploadErr := s.storeDASHFiles(videoId, dashDir, entries)
+	if uploadErr != nil {
+		failStored(fmt.Errorf("store DASH files after %d/%d: %w", fileCount, expectedCount, uploadErr))
+		return
+	}
+	if expectedCount == 0 {
+		failStored(errors.New("no DASH files were generated"))
+		return
+	}
+	if fileCount != expectedCount {
+		failStored(fmt.Errorf("only %d/%d files were written to storage", fileCount, expectedCount))
+		return
+	}
+	totalWriteTime := time.Since(start)
+	log.Printf(
+		"DASH storage time: video=%s files=%d duration=%.3f ms",
+		videoId,
+		fileCount,
+		durationMilliseconds(totalWriteTime),
+	)
+
+	// The record is only published while it is still reserved, so a deletion
+	// that started meanwhile is never overwritten.
+	start = time.Now()
+	metadata.Status = VideoReady
+	metadata.UploadedAt = time.Now()
+	err = s.metadataService.UpdateIfStatus(metadata, VideoProcessing)
+	if errors.Is(err, ErrVideoNotFound) || errors.Is(err, ErrVideoStatusChanged) {
+		failStored(errors.New("video was deleted while it was being processed"))
+		return
+	}
+	if err != nil {
+		failStored(fmt.Errorf("save metadata: %w", err))
+		return
+	}
+	totalMetadataTime := time.Since(start)
+	log.Printf("Metadata publish time: %.3f ms", durationMilliseconds(totalMetadataTime))
+
+	s.setJobState(&job, JobReady, nil)
+	log.Printf("Video %s is ready", videoId)
+}
+
+// generateThumbnails writes the poster and thumbnail sprite into dashDir, so
+// they are stored with the video's other files, and records them in
+// metadata. Images are optional; a failure is logged and the video is
+// published without them.
+func (s *server) generateThumbnails(videoPath, dashDir string, source sourceVideo, metadata *VideoMetadata) {
+	start := time.Now()
+	if output, err := s.runFFmpeg(posterArgs(videoPath, filepath.Join(dashDir, posterFilename))); err != nil {
+		log.Printf("Poster generation for %s failed: %v\n%s", metadata.Id, err, output)
+	} else {
+		metadata.Poster = posterFilename
+	}
+
+	if sprite, ok := planThumbnailSprite(source); ok {
+		if output, err := s.runFFmpeg(spriteArgs(videoPath, filepath.Join(dashDir, sprite.Filename), sprite)); err != nil {
+			log.Printf("Thumbnail sprite generation for %s failed: %v\n%s", metadata.Id, err, output)
+		} else {
+			metadata.Thumbnails = &sprite
+		}
+	}
+	log.Printf("Thumbnail generation time: %.3f ms", durationMilliseconds(time.Since(start)))
+}
+
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobId := r.URL.Path[len("/jobs/"):]
+	job, err := s.metadataService.ReadJob(jobId)
+	if err != nil {
+		http.Error(w, "Failed to read job", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Job not found: "+jobId, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
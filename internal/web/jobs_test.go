@@ -0,0 +1,645 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memoryMetadataService struct {
+	mu     sync.Mutex
+	videos map[string]VideoMetadata
+	jobs   map[string]TranscodeJob
+}
+
+func newMemoryMetadataService() *memoryMetadataService {
+	return &memoryMetadataService{
+		videos: make(map[string]VideoMetadata),
+		jobs:   make(map[string]TranscodeJob),
+	}
+}
+
+func (service *memoryMetadataService) Read(id string) (*VideoMetadata, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if metadata, ok := service.videos[id]; ok {
+		return &metadata, nil
+	}
+	return nil, nil
+}
+
+func (service *memoryMetadataService) List() ([]VideoMetadata, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	videos := make([]VideoMetadata, 0, len(service.videos))
+	for _, metadata := range service.videos {
+		videos = append(videos, metadata)
+	}
+	return videos, nil
+}
+
+func (service *memoryMetadataService) Create(metadata VideoMetadata) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.videos[metadata.Id] = metadata
+	return nil
+}
+
+func (service *memoryMetadataService) CreateIfAbsent(metadata VideoMetadata) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if _, ok := service.videos[metadata.Id]; ok {
+		return ErrVideoExists
+	}
+	service.videos[metadata.Id] = metadata
+	return nil
+}
+
+func (service *memoryMetadataService) Update(metadata VideoMetadata) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if _, ok := service.videos[metadata.Id]; !ok {
+		return ErrVideoNotFound
+	}
+	service.videos[metadata.Id] = metadata
+	return nil
+}
+
+func (service *memoryMetadataService) UpdateIfStatus(metadata VideoMetadata, status VideoStatus) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	stored, ok := service.videos[metadata.Id]
+	if !ok {
+		return ErrVideoNotFound
+	}
+	if stored.Status != status {
+		return ErrVideoStatusChanged
+	}
+	service.videos[metadata.Id] = metadata
+	return nil
+}
+
+func (service *memoryMetadataService) Delete(id string) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	delete(service.videos, id)
+	return nil
+}
+
+func (service *memoryMetadataService) DeleteIfStatus(id string, status VideoStatus) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	stored, ok := service.videos[id]
+	if !ok {
+		return ErrVideoNotFound
+	}
+	if stored.Status != status {
+		return ErrVideoStatusChanged
+	}
+	delete(service.videos, id)
+	return nil
+}
+
+func (service *memoryMetadataService) SaveJob(job TranscodeJob) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.jobs[job.Id] = job
+	return nil
+}
+
+func (service *memoryMetadataService) ReadJob(jobId string) (*TranscodeJob, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if job, ok := service.jobs[jobId]; ok {
+		return &job, nil
+	}
+	return nil, nil
+}
+
+func (service *memoryMetadataService) ListUnfinishedJobs() ([]TranscodeJob, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	jobs := make([]TranscodeJob, 0, len(service.jobs))
+	for _, job := range service.jobs {
+		if !job.Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (service *memoryMetadataService) ListJobs() ([]TranscodeJob, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	jobs := make([]TranscodeJob, 0, len(service.jobs))
+	for _, job := range service.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// newJobTestServer returns a server whose probe and ffmpeg steps are replaced
+// by fakes. The fake ffmpeg writes a manifest and one segment.
+func newJobTestServer(t *testing.T, ffmpeg func([]string) ([]byte, error)) (*server, *memoryMetadataService, *recordingContentService) {
+	t.Helper()
+
+	metadata := newMemoryMetadataService()
+	content := &recordingContentService{files: make(map[string][]byte)}
+	s := NewServer(metadata, content, WithTranscodeWorkers(1))
+	s.probeVideo = func(string) (sourceVideo, error) {
+		return sourceVideo{
+			Width: 1280, Height: 720, HasAudio: true, VideoCodec: "h264", AudioCodec: "aac", Duration: 95,
+		}, nil
+	}
+	if ffmpeg == nil {
+		ffmpeg = func(args []string) ([]byte, error) {
+			output := args[len(args)-1]
+			if filepath.Base(output) != "manifest.mpd" {
+				return nil, os.WriteFile(output, []byte(filepath.Base(output)), 0644)
+			}
+			for _, name := range []string{"manifest.mpd", "master.m3u8", "chunk-0-00001.m4s"} {
+				if err := os.WriteFile(filepath.Join(filepath.Dir(output), name), []byte(name), 0644); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}
+	}
+	s.runFFmpeg = ffmpeg
+	t.Cleanup(func() { s.Shutdown(t.Context()) })
+	return s, metadata, content
+}
+
+func uploadRequest(t *testing.T, filename string) *http.Request {
+	t.Helper()
+	return uploadFormRequest(t, filename, nil)
+}
+
+// uploadFormRequest builds an upload with extra form fields.
+func uploadFormRequest(t *testing.T, filename string, fields url.Values) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, values := range fields {
+		for _, value := range values {
+			writer.WriteField(name, value)
+		}
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write([]byte("mp4 data"))
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("Accept", "application/json")
+	return request
+}
+
+func waitForJobState(t *testing.T, metadata *memoryMetadataService, jobId string, states ...JobState) TranscodeJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, _ := metadata.ReadJob(jobId)
+		if job != nil {
+			for _, state := range states {
+				if job.State == state {
+					return *job
+				}
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := metadata.ReadJob(jobId)
+	t.Fatalf("job %s did not reach %v: %+v", jobId, states, job)
+	return TranscodeJob{}
+}
+
+func TestUploadQueuesJobAndReturnsImmediately(t *testing.T) {
+	release := make(chan struct{})
+	s, metadata, content := newJobTestServer(t, nil)
+	transcode := s.runFFmpeg
+	s.runFFmpeg = func(args []string) ([]byte, error) {
+		<-release
+		return transcode(args)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadRequest(t, "clip.mp4"))
+
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body)
+	}
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if job.Id == "" || job.VideoId == "" || job.Filename != "clip.mp4" || job.State != JobQueued {
+		t.Fatalf("job = %+v, want a queued job for clip.mp4", job)
+	}
+	if location := recorder.Header().Get("Location"); location != "/jobs/"+job.Id {
+		t.Fatalf("Location = %q, want /jobs/%s", location, job.Id)
+	}
+
+	waitForJobState(t, metadata, job.Id, JobTranscoding)
+	close(release)
+	ready := waitForJobState(t, metadata, job.Id, JobReady, JobFailed)
+	if ready.State != JobReady {
+		t.Fatalf("job finished as %s: %s", ready.State, ready.Error)
+	}
+
+	video, _ := metadata.Read(job.VideoId)
+	if video == nil {
+		t.Fatal("metadata was not created for the finished job")
+	}
+	if data, _ := content.Read(job.VideoId, "manifest.mpd"); string(data) != "manifest.mpd" {
+		t.Fatalf("stored manifest = %q", data)
+	}
+
+	if video.Poster != posterFilename || video.Thumbnails == nil {
+		t.Fatalf("metadata images = %q, %+v; want poster and thumbnails", video.Poster, video.Thumbnails)
+	}
+	for _, name := range []string{posterFilename, video.Thumbnails.Filename} {
+		if data, _ := content.Read(job.VideoId, name); string(data) != name {
+			t.Fatalf("stored %s = %q", name, data)
+		}
+	}
+}
+
+func TestUploadRecordsFormAndProbeMetadata(t *testing.T) {
+	s, metadata, _ := newJobTestServer(t, nil)
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadFormRequest(t, "clip.mp4", url.Values{
+		"title":       {"  Lecture 1  "},
+		"description": {"Consistent hashing"},
+		"tags":        {"cse124, systems,,Systems"},
+		"uploader":    {"ana"},
+	}))
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("upload status = %d: %s", recorder.Code, recorder.Body)
+	}
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if finished := waitForJobState(t, metadata, job.Id, JobReady, JobFailed); finished.State != JobReady {
+		t.Fatalf("job finished as %s: %s", finished.State, finished.Error)
+	}
+
+	video, _ := metadata.Read(job.VideoId)
+	want := VideoMetadata{
+		Id:               job.VideoId,
+		UploadedAt:       video.UploadedAt,
+		OriginalFilename: "clip.mp4",
+		Slug:             "lecture-1",
+		Title:            "Lecture 1",
+		Description:      "Consistent hashing",
+		Tags:             []string{"cse124", "systems"},
+		Uploader:         "ana",
+		Duration:         95,
+		Width:            1280,
+		Height:           720,
+		VideoCodec:       "h264",
+		AudioCodec:       "aac",
+		Size:             int64(len("mp4 data")),
+		Status:           VideoReady,
+		Poster:           video.Poster,
+		Thumbnails:       video.Thumbnails,
+	}
+	if !reflect.DeepEqual(*video, want) {
+		t.Fatalf("metadata = %+v\nwant %+v", *video, want)
+	}
+}
+
+func TestUploadRejectsInvalidDetails(t *testing.T) {
+	s, _, _ := newJobTestServer(t, nil)
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadFormRequest(t, "clip.mp4", url.Values{
+		"title": {strings.Repeat("x", maxTitleLength+1)},
+	}))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	if videos, _ := s.metadataService.List(); len(videos) != 0 {
+		t.Fatalf("rejected upload reserved a video ID: %+v", videos)
+	}
+}
+
+func TestThumbnailFailureDoesNotFailJob(t *testing.T) {
+	s, metadata, _ := newJobTestServer(t, nil)
+	transcode := s.runFFmpeg
+	s.runFFmpeg = func(args []string) ([]byte, error) {
+		if filepath.Base(args[len(args)-1]) != "manifest.mpd" {
+			return nil, errors.New("no frames")
+		}
+		return transcode(args)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadRequest(t, "clip.mp4"))
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+
+	if finished := waitForJobState(t, metadata, job.Id, JobReady, JobFailed); finished.State != JobReady {
+		t.Fatalf("job finished as %s: %s", finished.State, finished.Error)
+	}
+	video, _ := metadata.Read(job.VideoId)
+	if video == nil || video.Poster != "" || video.Thumbnails != nil {
+		t.Fatalf("metadata = %+v, want a video without images", video)
+	}
+}
+
+func TestUploadsOfSameFilenameGetDistinctIDs(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	s, metadata, _ := newJobTestServer(t, func([]string) ([]byte, error) {
+		<-release
+		return nil, errors.New("cancelled")
+	})
+
+	ids := make([]string, 0, 2)
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		s.handleUpload(recorder, uploadRequest(t, "video.mp4"))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("upload status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body)
+		}
+		var job TranscodeJob
+		if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+			t.Fatalf("decode job: %v", err)
+		}
+		ids = append(ids, job.VideoId)
+	}
+	if ids[0] == ids[1] {
+		t.Fatalf("both uploads of video.mp4 got video ID %s", ids[0])
+	}
+	for _, id := range ids {
+		video, _ := metadata.Read(id)
+		if video == nil || video.Status != VideoProcessing || video.OriginalFilename != "video.mp4" {
+			t.Fatalf("reserved record %s = %+v, want a processing record for video.mp4", id, video)
+		}
+	}
+}
+
+func TestUploadGeneratesURLSafeIDForAnyFilename(t *testing.T) {
+	s, metadata, _ := newJobTestServer(t, nil)
+	safe := regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+	for filename, slug := range map[string]string{
+		"Ünïcode vidéo!.mp4": "unicode-video",
+		"..":                 "",
+		"日本語.mp4":            "",
+		"a b/c.mp4":          "c",
+	} {
+		recorder := httptest.NewRecorder()
+		s.handleUpload(recorder, uploadRequest(t, filename))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("upload of %q status = %d: %s", filename, recorder.Code, recorder.Body)
+		}
+		var job TranscodeJob
+		if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+			t.Fatalf("decode job: %v", err)
+		}
+		if !safe.MatchString(job.VideoId) {
+			t.Fatalf("upload of %q got video ID %q", filename, job.VideoId)
+		}
+		if finished := waitForJobState(t, metadata, job.Id, JobReady, JobFailed); finished.State != JobReady {
+			t.Fatalf("job for %q finished as %s: %s", filename, finished.State, finished.Error)
+		}
+		video, _ := metadata.Read(job.VideoId)
+		if video.Slug != slug {
+			t.Fatalf("slug of %q = %q, want %q", filename, video.Slug, slug)
+		}
+	}
+}
+
+func TestUploadReservesVideoIDAcrossServers(t *testing.T) {
+	release := make(chan struct{})
+	blocked := func(args []string) ([]byte, error) {
+		<-release
+		return nil, errors.New("cancelled")
+	}
+	first, metadata, _ := newJobTestServer(t, blocked)
+	second, _, _ := newJobTestServer(t, blocked)
+	// Both web instances share one metadata store, as they share etcd, and
+	// both draw "clip" first, so one of them has to pick another ID.
+	second.metadataService = metadata
+	var generated atomic.Int32
+	generate := func() string {
+		if count := generated.Add(1); count > 2 {
+			return fmt.Sprintf("video%d", count)
+		}
+		return "clip"
+	}
+	first.newVideoID, second.newVideoID = generate, generate
+	defer close(release)
+
+	recorders := []*httptest.ResponseRecorder{httptest.NewRecorder(), httptest.NewRecorder()}
+	var uploads sync.WaitGroup
+	for index, s := range []*server{first, second} {
+		uploads.Add(1)
+		go func() {
+			defer uploads.Done()
+			s.handleUpload(recorders[index], uploadRequest(t, "clip.mp4"))
+		}()
+	}
+	uploads.Wait()
+
+	ids := make([]string, 0, 2)
+	for _, recorder := range recorders {
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("upload status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body)
+		}
+		var job TranscodeJob
+		if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+			t.Fatalf("decode job: %v", err)
+		}
+		ids = append(ids, job.VideoId)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"clip", "video3"}) {
+		t.Fatalf("concurrent uploads got video IDs %v, want clip and video3", ids)
+	}
+
+	video, _ := metadata.Read("clip")
+	if video == nil || video.Status != VideoProcessing {
+		t.Fatalf("reserved record = %+v, want a processing record", video)
+	}
+	page := httptest.NewRecorder()
+	first.handleVideo(page, httptest.NewRequest(http.MethodGet, "/videos/clip", nil))
+	if page.Code != http.StatusNotFound {
+		t.Fatalf("video page of a processing upload status = %d, want %d", page.Code, http.StatusNotFound)
+	}
+}
+
+func TestReleaseVideoKeepsRecordPublishedMeanwhile(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{Id: "clip", Status: VideoReady})
+	metadata.Create(VideoMetadata{Id: "reserved", Status: VideoProcessing})
+	s := &server{metadataService: metadata}
+
+	s.releaseVideo("clip")
+	s.releaseVideo("reserved")
+	if video, _ := metadata.Read("clip"); video == nil || video.Status != VideoReady {
+		t.Fatalf("published record after release = %+v, want it kept", video)
+	}
+	if video, _ := metadata.Read("reserved"); video != nil {
+		t.Fatalf("reserved record after release = %+v, want it removed", video)
+	}
+}
+
+func TestFailedJobRecordsErrorAndReleasesVideo(t *testing.T) {
+	s, metadata, _ := newJobTestServer(t, func([]string) ([]byte, error) {
+		return []byte("invalid data"), errors.New("exit status 1")
+	})
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadRequest(t, "broken.mp4"))
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+
+	failed := waitForJobState(t, metadata, job.Id, JobFailed)
+	if failed.Error == "" {
+		t.Fatal("failed job has no error message")
+	}
+	if video, _ := metadata.Read(job.VideoId); video != nil {
+		t.Fatalf("failed job did not release its video ID: %+v", video)
+	}
+
+	retry := httptest.NewRecorder()
+	s.handleUpload(retry, uploadRequest(t, "broken.mp4"))
+	if retry.Code != http.StatusAccepted {
+		t.Fatalf("upload after a failed job status = %d, want %d: %s", retry.Code, http.StatusAccepted, retry.Body)
+	}
+}
+
+func TestVideoDeletedDuringJobIsNotPublished(t *testing.T) {
+	release := make(chan struct{})
+	s, metadata, content := newJobTestServer(t, nil)
+	transcode := s.runFFmpeg
+	s.runFFmpeg = func(args []string) ([]byte, error) {
+		<-release
+		return transcode(args)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleUpload(recorder, uploadRequest(t, "clip.mp4"))
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	waitForJobState(t, metadata, job.Id, JobTranscoding)
+	if _, err := s.deleteVideo(job.VideoId); err != nil {
+		t.Fatalf("deleteVideo failed: %v", err)
+	}
+	close(release)
+
+	failed := waitForJobState(t, metadata, job.Id, JobReady, JobFailed)
+	if failed.State != JobFailed {
+		t.Fatalf("job of a deleted video finished as %s", failed.State)
+	}
+	if video, _ := metadata.Read(job.VideoId); video != nil {
+		t.Fatalf("deleted video was published: %+v", video)
+	}
+	if count, _ := content.Delete(job.VideoId); count != 0 {
+		t.Fatalf("failed job left %d stored files", count)
+	}
+}
+
+func TestRecoverJobsFailsOnlyThisInstancesUnfinishedJobs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	s, metadata, content := newJobTestServer(t, nil)
+	s.instance = "web-1"
+
+	jobs := []TranscodeJob{
+		{Id: "queued", VideoId: "a", Owner: "web-1", State: JobQueued},
+		{Id: "uploading", VideoId: "b", Owner: "web-1", State: JobUploading},
+		{Id: "ready", VideoId: "c", Owner: "web-1", State: JobReady},
+		{Id: "elsewhere", VideoId: "d", Owner: "web-2", State: JobTranscoding},
+	}
+	for _, job := range jobs {
+		metadata.SaveJob(job)
+		status := VideoProcessing
+		if job.State == JobReady {
+			status = VideoReady
+		}
+		metadata.Create(VideoMetadata{Id: job.VideoId, Status: status})
+		content.Write(job.VideoId, "manifest.mpd", []byte("manifest"))
+	}
+	if err := os.MkdirAll(jobWorkDir("queued"), 0755); err != nil {
+		t.Fatalf("create work directory: %v", err)
+	}
+
+	recovered, err := s.RecoverJobs()
+	if err != nil || recovered != 2 {
+		t.Fatalf("RecoverJobs = %d, %v; want 2", recovered, err)
+	}
+	for _, job := range jobs {
+		stored, _ := metadata.ReadJob(job.Id)
+		video, _ := metadata.Read(job.VideoId)
+		files, _ := content.Read(job.VideoId, "manifest.mpd")
+		if job.Owner == s.instance && job.State != JobReady {
+			if stored.State != JobFailed || stored.Error == "" || video != nil {
+				t.Errorf("job %s after recovery = %+v with video %+v; want failed and released", job.Id, stored, video)
+			}
+		} else if stored.State != job.State || video == nil {
+			t.Errorf("job %s after recovery = %+v with video %+v; want it untouched", job.Id, stored, video)
+		}
+		if wantFiles := job.State != JobUploading; (files != nil) != wantFiles {
+			t.Errorf("video %s has stored files %v after recovery, want %v", job.VideoId, files != nil, wantFiles)
+		}
+	}
+	if _, err := os.Stat(jobWorkDir("queued")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("work directory of a recovered job was left: %v", err)
+	}
+}
+
+func TestHandleJob(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.SaveJob(TranscodeJob{Id: "abc", VideoId: "clip", State: JobUploading})
+	s := &server{metadataService: metadata}
+
+	recorder := httptest.NewRecorder()
+	s.handleJob(recorder, httptest.NewRequest(http.MethodGet, "/jobs/abc", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var job TranscodeJob
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if job.State != JobUploading || job.VideoId != "clip" {
+		t.Fatalf("job = %+v", job)
+	}
+
+	missing := httptest.NewRecorder()
+	s.handleJob(missing, httptest.NewRequest(http.MethodGet, "/jobs/unknown", nil))
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("missing job status = %d, want %d", missing.Code, http.StatusNotFound)
+	}
+}
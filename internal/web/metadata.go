@@ -0,0 +1,203 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 5000
+	maxUploaderLength    = 100
+	maxTags              = 20
+	maxTagLength         = 50
+	maxFilenameLength    = 255
+	maxSlugLength        = 60
+)
+
+// slugify turns text into lowercase ASCII words joined by hyphens, at most
+// maxSlugLength bytes long. Accents are dropped from letters, apostrophes are
+// removed and every other character separates words. It returns "" when no
+// letter or digit is left.
+func slugify(text string) string {
+	var slug strings.Builder
+	separate := false
+	for _, r := range norm.NFKD.String(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r >= 'A' && r <= 'Z':
+			r += 'a' - 'A'
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			continue
+		default:
+			separate = slug.Len() > 0
+			continue
+		}
+		if separate {
+			slug.WriteByte('-')
+			separate = false
+		}
+		slug.WriteRune(r)
+	}
+	return strings.TrimRight(slug.String()[:min(slug.Len(), maxSlugLength)], "-")
+}
+
+// videoSlug returns the slug of a video's page URL, made from its title or,
+// for videos without one, from the name of the uploaded file.
+func videoSlug(metadata VideoMetadata) string {
+	if slug := slugify(metadata.Title); slug != "" {
+		return slug
+	}
+	return slugify(strings.TrimSuffix(metadata.OriginalFilename, filepath.Ext(metadata.OriginalFilename)))
+}
+
+// formText returns a trimmed form value and whether the form has the field.
+// Values longer than limit characters are rejected.
+func formText(form url.Values, name string, limit int) (string, bool, error) {
+	if !form.Has(name) {
+		return "", false, nil
+	}
+	value := strings.TrimSpace(form.Get(name))
+	if utf8.RuneCountInString(value) > limit {
+		return "", true, fmt.Errorf("%s is longer than %d characters", name, limit)
+	}
+	return value, true, nil
+}
+
+// parseTags splits a comma-separated tag list, dropping empty entries and
+// duplicates while keeping the original order.
+func parseTags(value string) ([]string, error) {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || containsTag(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	return tags, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyVideoDetails copies the editable fields present in form onto
+// metadata: title, description and a comma-separated tags list. Fields
+// missing from the form are left unchanged.
+func applyVideoDetails(form url.Values, metadata *VideoMetadata) error {
+	if title, ok, err := formText(form, "title", maxTitleLength); err != nil {
+		return err
+	} else if ok {
+		metadata.Title = title
+	}
+	if description, ok, err := formText(form, "description", maxDescriptionLength); err != nil {
+		return err
+	} else if ok {
+		metadata.Description = description
+	}
+	if form.Has("tags") {
+		tags, err := parseTags(form.Get("tags"))
+		if err != nil {
+			return err
+		}
+		metadata.Tags = tags
+	}
+	return nil
+}
+
+// handleVideoEdit updates the title, description and tags of a published
+// video from a form post. JSON clients get the updated record; browsers are
+// redirected back to the video page.
+func (s *server) handleVideoEdit(w http.ResponseWriter, r *http.Request, videoId string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := s.metadataService.Read(videoId)
+	if err != nil {
+		http.Error(w, "Failed to read video metadata", http.StatusInternalServerError)
+		return
+	}
+	if metadata == nil || metadata.Hidden() {
+		http.Error(w, "Video not found: "+videoId, http.StatusNotFound)
+		return
+	}
+
+	if err := applyVideoDetails(r.PostForm, metadata); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Page URLs with the old slug keep working and redirect to the new one.
+	metadata.Slug = videoSlug(*metadata)
+	// A deletion that marked the record since it was read must not be undone.
+	if err := s.metadataService.UpdateIfStatus(*metadata, metadata.Status); errors.Is(err, ErrVideoNotFound) {
+		http.Error(w, "Video not found: "+videoId, http.StatusNotFound)
+		return
+	} else if errors.Is(err, ErrVideoStatusChanged) {
+		http.Error(w, "Video changed while it was being edited: "+videoId, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Update metadata for %s failed: %v", videoId, err)
+		http.Error(w, "Failed to update video metadata", http.StatusInternalServerError)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, http.StatusOK, metadata)
+		return
+	}
+	http.Redirect(w, r, metadata.PageURL(), http.StatusSeeOther)
+}
+
+// formatDuration renders seconds as M:SS, or H:MM:SS for an hour or more.
+func formatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	total := int(seconds + 0.5)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// formatSize renders a byte count with a binary unit.
+func formatSize(size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, suffix := float64(size)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB", "TiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
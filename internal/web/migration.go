@@ -0,0 +1,305 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	"tritontube/internal/proto"
+)
+
+// WithMigrationStore records the progress of ring changes in store instead of
+// in memory, so a change that fails can be resumed or abandoned through any
+// web instance, including after a restart.
+func WithMigrationStore(store MigrationStore) NetworkOption {
+	return func(ns *NetworkVideoContentService) {
+		ns.migrations = store
+	}
+}
+
+// memoryMigrationStore keeps the pending migration for the lifetime of the
+// process. It is used when no shared store is configured.
+type memoryMigrationStore struct {
+	mu        sync.Mutex
+	migration *Migration
+	cleanups  map[string]PendingCleanup
+}
+
+func (store *memoryMigrationStore) LoadMigration(context.Context) (*Migration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.migration == nil {
+		return nil, nil
+	}
+	return cloneMigration(*store.migration), nil
+}
+
+func (store *memoryMigrationStore) CreateMigration(_ context.Context, migration Migration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.migration != nil {
+		return ErrMigrationPending
+	}
+	store.migration = cloneMigration(migration)
+	return nil
+}
+
+func (store *memoryMigrationStore) SaveMigration(_ context.Context, migration Migration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.migration = cloneMigration(migration)
+	return nil
+}
+
+func (store *memoryMigrationStore) DeleteMigration(context.Context) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.migration = nil
+	return nil
+}
+
+func (store *memoryMigrationStore) SaveCleanup(_ context.Context, cleanup PendingCleanup) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.cleanups == nil {
+		store.cleanups = make(map[string]PendingCleanup)
+	}
+	store.cleanups[cleanup.Id] = cleanup
+	return nil
+}
+
+func (store *memoryMigrationStore) ListCleanups(context.Context) ([]PendingCleanup, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return slices.Collect(maps.Values(store.cleanups)), nil
+}
+
+func (store *memoryMigrationStore) DeleteCleanup(_ context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.cleanups, id)
+	return nil
+}
+
+// cloneMigration copies the progress map, the only part of a migration that
+// changes after it is created.
+func cloneMigration(migration Migration) *Migration {
+	migration.Ranges = maps.Clone(migration.Ranges)
+	return &migration
+}
+
+// sameRing reports whether two memberships place keys on the same nodes.
+func sameRing(a, b RingMembership) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a.Nodes)), slices.Sorted(slices.Values(b.Nodes))) &&
+		maps.Equal(a.Weights, b.Weights) &&
+		a.VirtualNodes == b.VirtualNodes &&
+		a.ReplicationFactor == b.ReplicationFactor
+}
+
+func (ns *NetworkVideoContentService) currentRing() RingMembership {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return ns.membershipRecord(physicalNodes(ns.storageIds, ns.storageServers), maps.Clone(ns.nodeWeights))
+}
+
+// beginMigration records that a ring change is about to copy files. A record
+// left by a failed attempt at the same change is taken over instead, so the
+// ranges it finished are skipped; a record of any other change refuses the new
+// one until it is resumed or abandoned.
+func (ns *NetworkVideoContentService) beginMigration(ctx context.Context, migration Migration) (*Migration, error) {
+	pending, err := ns.migrations.LoadMigration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load migration: %w", err)
+	}
+	if pending != nil {
+		if pending.Operation != migration.Operation || pending.Node != migration.Node ||
+			!sameRing(pending.Base, migration.Base) || !sameRing(pending.Target, migration.Target) {
+			return nil, fmt.Errorf("%w: %s node %s; resume or abandon it first", ErrMigrationPending, pending.Operation, pending.Node)
+		}
+		if pending.Ranges == nil {
+			pending.Ranges = make(map[string]RangeProgress)
+		}
+		log.Printf("Resuming migration to %s node %s with %d ranges already started", pending.Operation, pending.Node, len(pending.Ranges))
+		return pending, nil
+	}
+
+	migration.Ranges = make(map[string]RangeProgress)
+	migration.StartedAt = time.Now()
+	if err := ns.migrations.CreateMigration(ctx, migration); err != nil {
+		return nil, fmt.Errorf("record migration: %w", err)
+	}
+	return &migration, nil
+}
+
+// saveMigration checkpoints a migration's progress. A checkpoint that cannot
+// be saved only means a later resume copies more again, so the copy goes on.
+func (ns *NetworkVideoContentService) saveMigration(ctx context.Context, migration *Migration) {
+	if err := ns.migrations.SaveMigration(ctx, *migration); err != nil {
+		log.Printf("Saving progress of migration to %s node %s failed: %v", migration.Operation, migration.Node, err)
+	}
+}
+
+// failMigration records why a migration stopped, even when it stopped because
+// the request was cancelled.
+func (ns *NetworkVideoContentService) failMigration(ctx context.Context, migration *Migration, cause error) {
+	migration.LastError = cause.Error()
+	ns.saveMigration(context.WithoutCancel(ctx), migration)
+}
+
+// finishMigration removes the record of a migration whose ring has been
+// published. If that fails, ResumeMigration finds the new ring in place and
+// only removes the record.
+func (ns *NetworkVideoContentService) finishMigration(ctx context.Context) {
+	if err := ns.migrations.DeleteMigration(context.WithoutCancel(ctx)); err != nil {
+		log.Printf("Removing finished migration record failed: %v", err)
+	}
+}
+
+// ResumeMigration repeats the pending ring change. Ranges recorded as copied
+// are skipped, and files the destination already holds with a matching size
+// and checksum are not copied again.
+func (ns *NetworkVideoContentService) ResumeMigration(ctx context.Context, req *proto.ResumeMigrationRequest) (*proto.ResumeMigrationResponse, error) {
+	pending, err := ns.migrations.LoadMigration(ctx)
+	if err != nil {
+		return &proto.ResumeMigrationResponse{}, fmt.Errorf("load migration: %w", err)
+	}
+	if pending == nil {
+		return &proto.ResumeMigrationResponse{}, ErrNoMigration
+	}
+	response := &proto.ResumeMigrationResponse{Operation: string(pending.Operation), NodeAddress: pending.Node}
+
+	current := ns.currentRing()
+	if sameRing(current, pending.Target) {
+		// The new ring was published but its record was not removed.
+		if err := ns.migrations.DeleteMigration(ctx); err != nil {
+			return response, fmt.Errorf("remove finished migration: %w", err)
+		}
+		return response, nil
+	}
+	if !sameRing(current, pending.Base) {
+		return response, errors.New("the ring has changed since the migration started; abandon it instead")
+	}
+
+	switch pending.Operation {
+	case MigrationAdd:
+		result, err := ns.AddNode(ctx, &proto.AddNodeRequest{NodeAddress: pending.Node, Weight: pending.Weight})
+		response.MigratedFileCount = result.GetMigratedFileCount()
+		return response, err
+	case MigrationRemove:
+		result, err := ns.RemoveNode(ctx, &proto.RemoveNodeRequest{NodeAddress: pending.Node})
+		response.MigratedFileCount = result.GetMigratedFileCount()
+		return response, err
+	case MigrationReweight:
+		result, err := ns.ReweightNode(ctx, &proto.ReweightNodeRequest{NodeAddress: pending.Node, Weight: pending.Weight})
+		response.MigratedFileCount = result.GetMigratedFileCount()
+		return response, err
+	default:
+		return response, fmt.Errorf("unknown migration operation %q", pending.Operation)
+	}
+}
+
+// AbandonMigration drops the pending ring change. The ring was never switched,
+// so every copy the change made is on a node that does not own it; those are
+// deleted from each range's destinations before the record is removed.
+func (ns *NetworkVideoContentService) AbandonMigration(ctx context.Context, req *proto.AbandonMigrationRequest) (*proto.AbandonMigrationResponse, error) {
+	ns.membershipMu.Lock()
+	defer ns.membershipMu.Unlock()
+
+	pending, err := ns.migrations.LoadMigration(ctx)
+	if err != nil {
+		return &proto.AbandonMigrationResponse{}, fmt.Errorf("load migration: %w", err)
+	}
+	if pending == nil {
+		return &proto.AbandonMigrationResponse{}, ErrNoMigration
+	}
+	response := &proto.AbandonMigrationResponse{Operation: string(pending.Operation), NodeAddress: pending.Node}
+
+	if !sameRing(ns.currentRing(), pending.Target) {
+		baseIDs, baseServers := buildRing(pending.Base.Nodes, pending.Base.Weights, ns.virtualNodes)
+		targetIDs, targetServers := buildRing(pending.Target.Nodes, pending.Target.Weights, ns.virtualNodes)
+		moves := planRangeMoves(baseIDs, baseServers, targetIDs, targetServers, ns.replicationFactor)
+		copies := make([]rangeDrop, 0, len(moves))
+		for _, move := range moves {
+			copies = append(copies, rangeDrop{start: move.start, end: move.end, nodes: move.targets})
+		}
+
+		count, err := ns.deleteUnownedCopies(ctx, copies, ns.FindStorageAddrs)
+		response.DeletedFileCount = int32(count)
+		if err != nil {
+			return response, err
+		}
+		log.Printf("Abandoned migration to %s node %s and deleted %d copies", pending.Operation, pending.Node, count)
+	}
+
+	if err := ns.migrations.DeleteMigration(ctx); err != nil {
+		return response, fmt.Errorf("remove migration: %w", err)
+	}
+	return response, nil
+}
+
+// missingFiles returns the files of page that destination does not already
+// hold with the size and checksum they have on source, so a resumed
+// migration does not copy them again. Files without a recorded checksum are
+// always copied.
+func missingFiles(ctx context.Context, source, destination storageRPCClient, page []*proto.FileEntry) ([]*proto.FileEntry, error) {
+	held, err := statFiles(ctx, destination, page)
+	if err != nil {
+		return nil, err
+	}
+	if len(held) == 0 {
+		return page, nil
+	}
+	candidates := make([]*proto.FileEntry, 0, len(held))
+	for _, entry := range page {
+		if held[entry.VideoId+"/"+entry.Filename] != nil {
+			candidates = append(candidates, entry)
+		}
+	}
+	original, err := statFiles(ctx, source, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]*proto.FileEntry, 0, len(page))
+	for _, entry := range page {
+		key := entry.VideoId + "/" + entry.Filename
+		copied, stored := held[key], original[key]
+		if copied != nil && stored != nil && copied.Size == stored.Size &&
+			len(stored.Sha256) > 0 && bytes.Equal(copied.Sha256, stored.Sha256) {
+			continue
+		}
+		missing = append(missing, entry)
+	}
+	return missing, nil
+}
+
+// statFiles returns the size and checksum a node has for each of entries
+// that it stores, keyed by "videoId/filename".
+func statFiles(ctx context.Context, client storageRPCClient, entries []*proto.FileEntry) (map[string]*proto.FileEntry, error) {
+	requests := make([]*proto.ReadRequest, 0, len(entries))
+	for _, entry := range entries {
+		requests = append(requests, &proto.ReadRequest{VideoId: entry.VideoId, Filename: entry.Filename})
+	}
+	response, err := client.StatFiles(ctx, &proto.BatchReadRequest{Requests: requests})
+	if err != nil {
+		return nil, fmt.Errorf("stat files: %w", err)
+	}
+	stats := make(map[string]*proto.FileEntry, len(response.GetEntries()))
+	for _, entry := range response.GetEntries() {
+		stats[entry.VideoId+"/"+entry.Filename] = entry
+	}
+	return stats, nil
+}
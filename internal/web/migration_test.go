@@ -0,0 +1,218 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"tritontube/internal/proto"
+
+	"google.golang.org/grpc"
+)
+
+// interruptingClient passes calls to a storage node but fails WriteFiles
+// once failAfter files have been written, and records the ranges listed.
+type interruptingClient struct {
+	storageRPCClient
+	failAfter int
+	written   int
+	listed    []string
+}
+
+func (client *interruptingClient) WriteFiles(
+	ctx context.Context,
+	request *proto.BatchWriteRequest,
+	options ...grpc.CallOption,
+) (*proto.BatchWriteResponse, error) {
+	if client.failAfter >= 0 && client.written >= client.failAfter {
+		return nil, errors.New("connection reset")
+	}
+	response, err := client.storageRPCClient.WriteFiles(ctx, request, options...)
+	client.written += int(response.GetCnt())
+	return response, err
+}
+
+func (client *interruptingClient) ListFilesInRange(
+	ctx context.Context,
+	request *proto.ListFilesInRangeRequest,
+	options ...grpc.CallOption,
+) (*proto.ListFilesResponse, error) {
+	client.listed = append(client.listed, rangeMove{start: request.Start - 1, end: request.End - 1}.id())
+	return client.storageRPCClient.ListFilesInRange(ctx, request, options...)
+}
+
+// startInterruptedAddNode adds a second real storage node to a ring holding
+// 100 files and fails the migration once failAfter files have reached it.
+func startInterruptedAddNode(t *testing.T, failAfter int) (*NetworkVideoContentService, []ContentFile, *interruptingClient, *interruptingClient) {
+	t.Helper()
+
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	// Many small ranges make sure some finish before the failure.
+	service := NewNetworkVideoContentService([]string{first}, WithVirtualNodes(32))
+	t.Cleanup(func() { service.Close() })
+
+	files := make([]ContentFile, 0, 100)
+	for index := range 100 {
+		files = append(files, ContentFile{
+			VideoID: "video", Filename: fmt.Sprintf("chunk-%05d.m4s", index), Data: []byte(fmt.Sprint(index)),
+		})
+	}
+	if _, err := service.WriteBatch(files); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+
+	clients := map[string]*interruptingClient{first: {failAfter: -1}, second: {failAfter: failAfter}}
+	service.dialStorageNode = func(address string) (storageRPCClient, func() error, error) {
+		// The pool does not keep connections to nodes outside the ring, so
+		// connect again on every dial as dialNode does.
+		conn, pooled, err := service.pool.get(address)
+		if err != nil {
+			return nil, nil, err
+		}
+		client := clients[address]
+		client.storageRPCClient = proto.NewVideoContentStorageServiceClient(conn)
+		if !pooled {
+			return client, conn.Close, nil
+		}
+		return client, func() error { return nil }, nil
+	}
+
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second})
+	if err == nil {
+		t.Fatal("AddNode succeeded although writes to the new node failed")
+	}
+	// Batches do not span ranges, so the last one may end past failAfter.
+	if int(response.MigratedFileCount) < failAfter || int(response.MigratedFileCount) != clients[second].written {
+		t.Fatalf("AddNode migrated %d files before failing, want the %d written", response.MigratedFileCount, clients[second].written)
+	}
+	return service, files, clients[first], clients[second]
+}
+
+func storedFileCount(t *testing.T, service *NetworkVideoContentService, address string) int {
+	t.Helper()
+
+	client, closeNode, err := service.dialNode(t.Context(), address)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", address, err)
+	}
+	defer closeNode()
+	count := 0
+	err = forEachFilePage("", func(cursor string) (*proto.ListFilesResponse, error) {
+		return client.ListFilesPage(t.Context(), &proto.ListFilesRequest{Cursor: cursor})
+	}, func(page []*proto.FileEntry, _ string) error {
+		count += len(page)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("listing files failed: %v", err)
+	}
+	return count
+}
+
+func TestResumeMigrationSkipsCopiedRangesAndFiles(t *testing.T) {
+	service, files, source, destination := startInterruptedAddNode(t, 20)
+
+	pending, err := service.migrations.LoadMigration(t.Context())
+	if err != nil || pending == nil {
+		t.Fatalf("LoadMigration after failure = %v, %v; want the interrupted migration", pending, err)
+	}
+	if pending.LastError == "" {
+		t.Fatal("interrupted migration does not record why it stopped")
+	}
+	done := make(map[string]bool)
+	for id, progress := range pending.Ranges {
+		if progress.Done {
+			done[id] = true
+		}
+	}
+	if len(done) == 0 {
+		t.Fatal("no range was recorded as copied before the failure")
+	}
+
+	_, err = service.ReweightNode(t.Context(), &proto.ReweightNodeRequest{NodeAddress: pending.Base.Nodes[0], Weight: 2})
+	if !errors.Is(err, ErrMigrationPending) {
+		t.Fatalf("ReweightNode during a pending migration = %v, want ErrMigrationPending", err)
+	}
+
+	copied := destination.written
+	destination.failAfter = -1
+	source.listed = nil
+	response, err := service.ResumeMigration(t.Context(), &proto.ResumeMigrationRequest{})
+	if err != nil {
+		t.Fatalf("ResumeMigration failed: %v", err)
+	}
+	if response.Operation != string(MigrationAdd) || response.NodeAddress != pending.Node {
+		t.Fatalf("ResumeMigration resumed %s %s, want add %s", response.Operation, response.NodeAddress, pending.Node)
+	}
+	for _, id := range source.listed {
+		if done[id] {
+			t.Fatalf("resumed migration listed range %s again although it was copied", id)
+		}
+	}
+
+	moved := 0
+	for _, file := range files {
+		if service.FindStorageAddr(file.VideoID+"/"+file.Filename) == pending.Node {
+			moved++
+		}
+	}
+	if copied+int(response.MigratedFileCount) != moved || destination.written != moved {
+		t.Fatalf("migrated %d then %d files with %d writes, want %d files each written once",
+			copied, response.MigratedFileCount, destination.written, moved)
+	}
+	for _, file := range files {
+		data, err := service.Read(file.VideoID, file.Filename)
+		if err != nil || !bytes.Equal(data, file.Data) {
+			t.Fatalf("Read(%s) after ResumeMigration = %q, %v", file.Filename, data, err)
+		}
+	}
+	if pending, err := service.migrations.LoadMigration(t.Context()); err != nil || pending != nil {
+		t.Fatalf("LoadMigration after resuming = %v, %v; want none", pending, err)
+	}
+	if _, err := service.ResumeMigration(t.Context(), &proto.ResumeMigrationRequest{}); !errors.Is(err, ErrNoMigration) {
+		t.Fatalf("second ResumeMigration = %v, want ErrNoMigration", err)
+	}
+}
+
+func TestAbandonMigrationDeletesCopiesOnNonOwners(t *testing.T) {
+	service, files, _, destination := startInterruptedAddNode(t, 20)
+	pending, err := service.migrations.LoadMigration(t.Context())
+	if err != nil || pending == nil {
+		t.Fatalf("LoadMigration after failure = %v, %v; want the interrupted migration", pending, err)
+	}
+
+	response, err := service.AbandonMigration(t.Context(), &proto.AbandonMigrationRequest{})
+	if err != nil {
+		t.Fatalf("AbandonMigration failed: %v", err)
+	}
+	if int(response.DeletedFileCount) != destination.written {
+		t.Fatalf("AbandonMigration deleted %d copies, want the %d written", response.DeletedFileCount, destination.written)
+	}
+	if count := storedFileCount(t, service, pending.Node); count != 0 {
+		t.Fatalf("abandoned destination still holds %d files", count)
+	}
+	if count := storedFileCount(t, service, pending.Base.Nodes[0]); count != len(files) {
+		t.Fatalf("source holds %d files after abandoning, want all %d", count, len(files))
+	}
+	if pending, err := service.migrations.LoadMigration(t.Context()); err != nil || pending != nil {
+		t.Fatalf("LoadMigration after abandoning = %v, %v; want none", pending, err)
+	}
+
+	// A fresh attempt starts over rather than resuming.
+	destination.failAfter = -1
+	destination.written = 0
+	nodes, err := service.ListNodes(t.Context(), &proto.ListNodesRequest{})
+	if err != nil || len(nodes.Nodes) != 1 {
+		t.Fatalf("ListNodes after abandoning = %v, %v; want the original node only", nodes, err)
+	}
+	added, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: response.NodeAddress})
+	if err != nil {
+		t.Fatalf("AddNode after abandoning failed: %v", err)
+	}
+	if int(added.MigratedFileCount) != destination.written || storedFileCount(t, service, pending.Node) != destination.written {
+		t.Fatalf("AddNode after abandoning migrated %d files but wrote %d", added.MigratedFileCount, destination.written)
+	}
+}
@@ -1,20 +1,20 @@
 package web
 
 import (
+	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"sort"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 	"tritontube/internal/proto"
+	"tritontube/internal/storage"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // NetworkVideoContentService implements VideoContentService using a network of nodes.
@@ -26,45 +26,119 @@ type NetworkVideoContentService struct {
 	storageServers map[uint64]string
 	pendingWrites  map[string][]*proto.FileEntry
 
+	// replicationFactor is the number of distinct storage nodes that hold a
+	// copy of every file. It is fixed for the lifetime of the service.
+	replicationFactor int
+	// virtualNodes is the number of ring tokens owned by each storage node
+	// per unit of weight. nodeWeights holds weights other than the default of
+	// one and is guarded by mu.
+	virtualNodes int
+	nodeWeights  map[string]uint32
+
+	// membership persists the ring when set. membershipRevision is the stored
+	// revision the in-memory ring reflects and is guarded by mu.
+	membership         MembershipStore
+	membershipRevision int64
+	// migrations holds the progress of the ring change being migrated so a
+	// failed change can be resumed or abandoned.
+	migrations MigrationStore
+
+	// cleanupGracePeriod delays deleting the copies a ring change leaves on
+	// nodes that lost ranges. cleanups tracks scheduled cleanups, which stop
+	// when background is cancelled by Close.
+	cleanupGracePeriod time.Duration
+	cleanups           sync.WaitGroup
+	background         context.Context
+	stopBackground     context.CancelFunc
+
+	pool            *storagePool
 	dialStorageNode func(string) (storageRPCClient, func() error, error)
 }
 
+// NetworkOption configures a NetworkVideoContentService at construction time.
+type NetworkOption func(*NetworkVideoContentService)
+
+// WithReplicationFactor stores every file on the given number of distinct
+// successor nodes on the ring. Values below one are treated as one.
+func WithReplicationFactor(replicas int) NetworkOption {
+	return func(ns *NetworkVideoContentService) {
+		ns.replicationFactor = max(replicas, 1)
+	}
+}
+
+// WithMembershipStore persists ring changes to store and lets LoadMembership
+// and WatchMembership keep the ring in sync with other web instances.
+func WithMembershipStore(store MembershipStore) NetworkOption {
+	return func(ns *NetworkVideoContentService) {
+		ns.membership = store
+	}
+}
+
 type storageRPCClient interface {
-	ListFiles(context.Context, *proto.BatchReadRequest, ...grpc.CallOption) (*proto.BatchReadResponse, error)
+	ListFilesPage(context.Context, *proto.ListFilesRequest, ...grpc.CallOption) (*proto.ListFilesResponse, error)
+	ListFilesInRange(context.Context, *proto.ListFilesInRangeRequest, ...grpc.CallOption) (*proto.ListFilesResponse, error)
 	ReadFile(context.Context, *proto.ReadRequest, ...grpc.CallOption) (*proto.ReadResponse, error)
+	ReadFileRange(context.Context, *proto.ReadRangeRequest, ...grpc.CallOption) (*proto.ReadRangeResponse, error)
+	ReadFileStream(context.Context, *proto.ReadRangeRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[proto.FileChunk], error)
+	WriteFileStream(context.Context, ...grpc.CallOption) (grpc.ClientStreamingClient[proto.FileChunk, proto.WriteResponse], error)
 	WriteFile(context.Context, *proto.WriteRequest, ...grpc.CallOption) (*proto.WriteResponse, error)
 	ReadFiles(context.Context, *proto.BatchReadRequest, ...grpc.CallOption) (*proto.BatchReadResponse, error)
+	StatFiles(context.Context, *proto.BatchReadRequest, ...grpc.CallOption) (*proto.BatchReadResponse, error)
 	WriteFiles(context.Context, *proto.BatchWriteRequest, ...grpc.CallOption) (*proto.BatchWriteResponse, error)
+	DeleteFile(context.Context, *proto.DeleteFileRequest, ...grpc.CallOption) (*proto.DeleteFileResponse, error)
 }
 
-const storageBatchSize = 4
+const (
+	storageBatchSize   = 4
+	storageDialTimeout = 5 * time.Second
+	// listPageSize is the number of file identifiers requested per listing
+	// page.
+	listPageSize = 1000
+)
 
-var _ VideoContentService = (*NetworkVideoContentService)(nil)
+// storageCallTimeout bounds each request-response call to a storage node and
+// each wait for a stream to move, so a node that accepts connections but stops
+// answering fails like an unreachable one.
+var storageCallTimeout = 30 * time.Second
 
-func NewNetworkVideoContentService(storageServers []string) *NetworkVideoContentService {
+var _ VideoContentService = (*NetworkVideoContentService)(nil)
 
-	storageIds := make([]uint64, 0, len(storageServers))
-	servers := make(map[uint64]string, len(storageServers))
-	for _, addr := range storageServers {
-		id := HashStringToUint64(addr)
-		storageIds = append(storageIds, id)
-		servers[id] = addr
+func NewNetworkVideoContentService(storageServers []string, options ...NetworkOption) *NetworkVideoContentService {
+	ns := &NetworkVideoContentService{
+		pendingWrites:      make(map[string][]*proto.FileEntry),
+		replicationFactor:  1,
+		virtualNodes:       1,
+		migrations:         &memoryMigrationStore{},
+		cleanupGracePeriod: defaultCleanupGracePeriod,
 	}
-
-	sort.Slice(storageIds, func(i int, j int) bool {
-		return storageIds[i] < storageIds[j]
-	})
-
-	return &NetworkVideoContentService{
-		storageIds:     storageIds,
-		storageServers: servers,
-		pendingWrites:  make(map[string][]*proto.FileEntry),
+	for _, option := range options {
+		option(ns)
+	}
+	ns.background, ns.stopBackground = context.WithCancel(context.Background())
+
+	ns.nodeWeights = make(map[string]uint32)
+	ns.storageIds, ns.storageServers = buildRing(storageServers, ns.nodeWeights, ns.virtualNodes)
+	// Connections to departed nodes stay open as long as their copies do, but
+	// not for good when the copies are kept for good.
+	retireAfter := ns.cleanupGracePeriod
+	if retireAfter < 0 {
+		retireAfter = defaultCleanupGracePeriod
 	}
+	ns.pool = newStoragePool(retireAfter)
+	ns.pool.update(physicalNodes(ns.storageIds, ns.storageServers))
+	return ns
+}
+
+// Close cancels cleanups that have not finished and closes the pooled
+// connections to every storage node.
+func (ns *NetworkVideoContentService) Close() error {
+	ns.stopBackground()
+	ns.cleanups.Wait()
+	return ns.pool.close()
 }
 
 func HashStringToUint64(key string) uint64 {
-	sum := sha256.Sum256([]byte(key))
-	return binary.BigEndian.Uint64(sum[:8])
+	return storage.RingHash(key)
 }
 
 func (ns *NetworkVideoContentService) FindStorageAddr(str string) string {
@@ -74,44 +148,158 @@ func (ns *NetworkVideoContentService) FindStorageAddr(str string) string {
 	return findStorageAddr(str, ns.storageIds, ns.storageServers)
 }
 
+// FindStorageAddrs returns the replica set for a key in ring order. The first
+// address is the primary owner reported by FindStorageAddr.
+func (ns *NetworkVideoContentService) FindStorageAddrs(str string) []string {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return findStorageAddrs(str, ns.storageIds, ns.storageServers, ns.replicationFactor)
+}
+
+// Read returns a whole file after checking it against the checksum stored
+// with it. A replica whose copy fails the check is skipped like one that
+// cannot be reached.
 func (ns *NetworkVideoContentService) Read(videoId string, filename string) ([]byte, error) {
+	key := videoId + "/" + filename
+	replicas := ns.FindStorageAddrs(key)
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no valid storage address found for %s", key)
+	}
+
+	var lastErr error
+	for _, storageAddr := range replicas {
+		data, err := ns.readFromNode(storageAddr, videoId, filename)
+		if err == nil {
+			return data, nil
+		}
+		log.Printf("Read %s from %s failed: %v", key, storageAddr, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("read %s from %d replicas: %w", key, len(replicas), lastErr)
+}
+
+func (ns *NetworkVideoContentService) readFromNode(storageAddr, videoId, filename string) ([]byte, error) {
+	reader, _, _, err := ns.openRangeOnNode(storageAddr, videoId, filename, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// openRangeOnNode verifies whole files, so a corrupted copy fails here.
+	return io.ReadAll(reader)
+}
+
+// OpenRange returns a reader over part of a file along with the size and
+// stored checksum of the whole file. Up to largeFileThreshold bytes come back
+// in one ReadFileRange call, which covers typical segments; the rest of a
+// larger range is streamed so files of any size can be served without
+// buffering them. Reads of a whole file are checked against its checksum: a
+// corrupted copy returned in one call fails over to the next replica, and a
+// streamed one fails the final read, so the file is never delivered in full.
+func (ns *NetworkVideoContentService) OpenRange(videoId string, filename string, offset, length int64) (io.ReadCloser, int64, []byte, error) {
 	filepath := videoId + "/" + filename
 
 	start := time.Now()
-	storageAddr := ns.FindStorageAddr(filepath)
-	if storageAddr == "" {
-		return nil, fmt.Errorf("no valid storage address found for %s", filepath)
+	replicas := ns.FindStorageAddrs(filepath)
+	if len(replicas) == 0 {
+		return nil, 0, nil, fmt.Errorf("no valid storage address found for %s", filepath)
 	}
 	hashLookupTime := time.Since(start)
 	log.Printf("Consistent hash lookup time: %.3f ms", durationMilliseconds(hashLookupTime))
 
-	conn, err := grpc.NewClient(
-		storageAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(proto.MaxMessageSize),
-			grpc.MaxCallSendMsgSize(proto.MaxMessageSize),
-		),
-	)
+	// Replicas are tried in ring order so the primary serves every read while
+	// it is healthy and later replicas only absorb its failures.
+	var lastErr error
+	for _, storageAddr := range replicas {
+		reader, size, checksum, err := ns.openRangeOnNode(storageAddr, videoId, filename, offset, length)
+		if err == nil {
+			return reader, size, checksum, nil
+		}
+		log.Printf("Read %s from %s failed: %v", filepath, storageAddr, err)
+		lastErr = err
+	}
+	return nil, 0, nil, fmt.Errorf("read %s from %d replicas: %w", filepath, len(replicas), lastErr)
+}
+
+// openRangeOnNode reads a range from one storage node. Besides the reader and
+// the file size it returns the file's stored checksum, if any. A whole file
+// is verified against that checksum: at once when it arrives in one response,
+// and otherwise as the returned reader is consumed.
+func (ns *NetworkVideoContentService) openRangeOnNode(
+	storageAddr, videoId, filename string,
+	offset, length int64,
+) (io.ReadCloser, int64, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storageDialTimeout)
+	defer cancel()
+
+	client, closeClient, err := ns.dialNode(ctx, storageAddr)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, fmt.Errorf("connect to storage node %s: %w", storageAddr, err)
 	}
-	defer conn.Close()
+	// A stream keeps using the connection after this returns, so closing it
+	// passes to the reader.
+	streaming := false
+	defer func() {
+		if !streaming {
+			closeClient()
+		}
+	}()
 
-	client := proto.NewVideoContentStorageServiceClient(conn)
+	firstLength := int64(largeFileThreshold)
+	if length >= 0 {
+		firstLength = min(length, largeFileThreshold)
+	}
 
-	start = time.Now()
-	response, err := client.ReadFile(context.Background(), &proto.ReadRequest{
+	start := time.Now()
+	callCtx, cancelCall := context.WithTimeout(context.Background(), storageCallTimeout)
+	response, err := client.ReadFileRange(callCtx, &proto.ReadRangeRequest{
 		VideoId:  videoId,
 		Filename: filename,
+		Offset:   offset,
+		Length:   firstLength,
 	})
+	cancelCall()
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
+	}
+	if response == nil {
+		return nil, 0, nil, fmt.Errorf("storage node %s returned an empty response", storageAddr)
 	}
 	grpcTime := time.Since(start)
 	log.Printf("gRPC read file time: %.3f ms", durationMilliseconds(grpcTime))
 
-	return response.Data, nil
+	first, count := storage.ResolveRange(offset, length, response.Size)
+	received := int64(len(response.Data))
+	whole := first == 0 && count == response.Size && len(response.Sha256) > 0
+	if received >= count {
+		if whole {
+			if err := storage.VerifyChecksum(response.Data, response.Sha256); err != nil {
+				return nil, 0, nil, fmt.Errorf("%w in %s/%s received from %s", err, videoId, filename, storageAddr)
+			}
+		}
+		return io.NopCloser(bytes.NewReader(response.Data)), response.Size, response.Sha256, nil
+	}
+
+	rest, err := openFileStream(context.Background(), client, &proto.ReadRangeRequest{
+		VideoId:  videoId,
+		Filename: filename,
+		Offset:   first + received,
+		Length:   count - received,
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	streaming = true
+	rest.release = closeClient
+	var reader io.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(response.Data), rest), rest}
+	if whole {
+		reader = newChecksumReader(reader, count, response.Sha256, fmt.Sprintf("%s/%s received from %s", videoId, filename, storageAddr))
+	}
+	return reader, response.Size, response.Sha256, nil
 }
 
 func (ns *NetworkVideoContentService) Write(videoId string, filename string, data []byte) error {
@@ -122,43 +310,166 @@ func (ns *NetworkVideoContentService) Write(videoId string, filename string, dat
 	return err
 }
 
+// WriteBatch sends every file to each node in its replica set. Each file
+// carries its SHA-256 so storage nodes reject copies damaged in transit. The
+// returned count only includes files acknowledged by all of their replicas.
 func (ns *NetworkVideoContentService) WriteBatch(files []ContentFile) (int, error) {
 	grouped := make(map[string][]*proto.FileEntry)
-	for _, file := range files {
+	fileIndexes := make(map[string][]int)
+	required := make([]int, len(files))
+	largeFiles := make(map[int][]string)
+	checksums := make([][]byte, len(files))
+	for index, file := range files {
+		checksums[index] = storage.Checksum(file.Data)
 		key := file.VideoID + "/" + file.Filename
-		storageAddr := ns.FindStorageAddr(key)
-		if storageAddr == "" {
+		replicas := ns.FindStorageAddrs(key)
+		if len(replicas) == 0 {
 			return 0, fmt.Errorf("no valid storage address found for %s", key)
 		}
-		grouped[storageAddr] = append(grouped[storageAddr], &proto.FileEntry{
-			VideoId: file.VideoID, Filename: file.Filename, Data: file.Data,
-		})
+		required[index] = len(replicas)
+		if len(file.Data) > largeFileThreshold {
+			largeFiles[index] = replicas
+			continue
+		}
+		for _, storageAddr := range replicas {
+			grouped[storageAddr] = append(grouped[storageAddr], &proto.FileEntry{
+				VideoId: file.VideoID, Filename: file.Filename, Data: file.Data, Sha256: checksums[index],
+			})
+			fileIndexes[storageAddr] = append(fileIndexes[storageAddr], index)
+		}
+	}
+
+	acknowledged := make([]int, len(files))
+	written := func() int {
+		count := 0
+		for index, acks := range acknowledged {
+			if acks == required[index] {
+				count++
+			}
+		}
+		return count
 	}
 
-	written := 0
 	for storageAddr, entries := range grouped {
-		client, closeClient, err := ns.dialNode(context.Background(), storageAddr)
+		dialCtx, cancel := context.WithTimeout(context.Background(), storageDialTimeout)
+		client, closeClient, err := ns.dialNode(dialCtx, storageAddr)
+		cancel()
 		if err != nil {
-			return written, fmt.Errorf("connect to storage node %s: %w", storageAddr, err)
+			return written(), fmt.Errorf("connect to storage node %s: %w", storageAddr, err)
 		}
 		for start := 0; start < len(entries); start += storageBatchSize {
 			end := min(start+storageBatchSize, len(entries))
-			response, writeErr := client.WriteFiles(context.Background(), &proto.BatchWriteRequest{Entries: entries[start:end]})
+			callCtx, cancelCall := context.WithTimeout(context.Background(), storageCallTimeout)
+			response, writeErr := client.WriteFiles(callCtx, &proto.BatchWriteRequest{Entries: entries[start:end]})
+			cancelCall()
 			if writeErr != nil {
 				closeClient()
-				return written, fmt.Errorf("batch write to %s: %w", storageAddr, writeErr)
+				return written(), fmt.Errorf("batch write to %s: %w", storageAddr, writeErr)
 			}
 			if response == nil || response.Cnt != uint32(end-start) {
 				closeClient()
-				return written, fmt.Errorf("batch write to %s wrote %d of %d files", storageAddr, response.GetCnt(), end-start)
+				return written(), fmt.Errorf("batch write to %s wrote %d of %d files", storageAddr, response.GetCnt(), end-start)
+			}
+			for _, index := range fileIndexes[storageAddr][start:end] {
+				acknowledged[index]++
 			}
-			written += int(response.Cnt)
 		}
 		if err := closeClient(); err != nil {
-			return written, err
+			return written(), err
+		}
+	}
+
+	// Large files would not fit in a batch message, so each one is streamed to
+	// its replicas on its own.
+	for index, replicas := range largeFiles {
+		file := files[index]
+		for _, storageAddr := range replicas {
+			if err := ns.streamToNode(storageAddr, file, checksums[index]); err != nil {
+				return written(), fmt.Errorf("stream %s/%s to %s: %w", file.VideoID, file.Filename, storageAddr, err)
+			}
+			acknowledged[index]++
+		}
+	}
+	return written(), nil
+}
+
+func (ns *NetworkVideoContentService) streamToNode(storageAddr string, file ContentFile, checksum []byte) error {
+	dialCtx, cancel := context.WithTimeout(context.Background(), storageDialTimeout)
+	client, closeClient, err := ns.dialNode(dialCtx, storageAddr)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("connect to storage node %s: %w", storageAddr, err)
+	}
+	defer closeClient()
+
+	return writeFileStream(context.Background(), client, file.VideoID, file.Filename, checksum, bytes.NewReader(file.Data))
+}
+
+// Delete removes a video's files from every node in the ring rather than only
+// from the current replica sets, so copies left behind by earlier membership
+// changes are removed too. Every node is attempted even when one fails. It
+// holds membershipMu so a concurrent migration cannot copy a file onto a node
+// after that node was cleaned.
+func (ns *NetworkVideoContentService) Delete(videoId string) (int, error) {
+	ns.membershipMu.Lock()
+	defer ns.membershipMu.Unlock()
+
+	ns.mu.RLock()
+	nodes := physicalNodes(ns.storageIds, ns.storageServers)
+	ns.mu.RUnlock()
+
+	count := 0
+	var errs []error
+	for _, storageAddr := range nodes {
+		deleted, err := ns.deleteFromNode(storageAddr, videoId)
+		count += deleted
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete %s from %s: %w", videoId, storageAddr, err))
 		}
 	}
-	return written, nil
+	log.Printf("Deleted %d files of video %s from %d nodes", count, videoId, len(nodes))
+	return count, errors.Join(errs...)
+}
+
+func (ns *NetworkVideoContentService) deleteFromNode(storageAddr, videoId string) (int, error) {
+	dialCtx, cancel := context.WithTimeout(context.Background(), storageDialTimeout)
+	client, closeClient, err := ns.dialNode(dialCtx, storageAddr)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("connect to storage node %s: %w", storageAddr, err)
+	}
+	defer closeClient()
+
+	// The prefix also matches longer IDs that start with videoId, whose files
+	// are skipped. Deleting behind the cursor does not disturb the listing.
+	count := 0
+	err = forEachFilePage("", func(cursor string) (*proto.ListFilesResponse, error) {
+		callCtx, cancelCall := context.WithTimeout(context.Background(), storageCallTimeout)
+		defer cancelCall()
+		return client.ListFilesPage(callCtx, &proto.ListFilesRequest{
+			Cursor: cursor, Limit: listPageSize, VideoIdPrefix: videoId,
+		})
+	}, func(page []*proto.FileEntry, _ string) error {
+		for _, entry := range page {
+			if entry.VideoId != videoId {
+				continue
+			}
+			callCtx, cancelCall := context.WithTimeout(context.Background(), storageCallTimeout)
+			response, err := client.DeleteFile(callCtx, &proto.DeleteFileRequest{
+				VideoId:  entry.VideoId,
+				Filename: entry.Filename,
+			})
+			cancelCall()
+			if err != nil {
+				return err
+			}
+			if response.GetDeleted() {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
 }
 
 func (ns *NetworkVideoContentService) AddNode(ctx context.Context, req *proto.AddNodeRequest) (*proto.AddNodeResponse, error) {
@@ -173,33 +484,31 @@ func (ns *NetworkVideoContentService) AddNode(ctx context.Context, req *proto.Ad
 	if req.NodeAddress == "" {
 		return &proto.AddNodeResponse{}, errors.New("storage node address must not be empty")
 	}
+	if req.Weight > maxNodeWeight {
+		return &proto.AddNodeResponse{}, fmt.Errorf("storage node weight must be at most %d", maxNodeWeight)
+	}
 
-	newNodeId := HashStringToUint64(req.NodeAddress)
 	ns.mu.RLock()
-	_, exists := ns.storageServers[newNodeId]
 	currentIDs := append([]uint64(nil), ns.storageIds...)
 	currentServers := cloneStorageServers(ns.storageServers)
+	proposedWeights := maps.Clone(ns.nodeWeights)
 	ns.mu.RUnlock()
 
-	if exists {
+	currentNodes := physicalNodes(currentIDs, currentServers)
+	base := ns.membershipRecord(currentNodes, maps.Clone(proposedWeights))
+	if slices.Contains(currentNodes, req.NodeAddress) {
 		return &proto.AddNodeResponse{MigratedFileCount: 0}, fmt.Errorf("storage node already exists: %s", req.NodeAddress)
 	}
 
-	start := time.Now()
-	peerAddr := findStorageAddr(req.NodeAddress, currentIDs, currentServers)
-	peerTime := time.Since(start)
-	log.Printf("Peer look up time: %.3f ms\n", durationMilliseconds(peerTime))
-
-	proposedIDs := append([]uint64(nil), currentIDs...)
-	proposedIDs = append(proposedIDs, newNodeId)
-	sort.Slice(proposedIDs, func(i, j int) bool { return proposedIDs[i] < proposedIDs[j] })
-
-	proposedServers := cloneStorageServers(currentServers)
-	proposedServers[newNodeId] = req.NodeAddress
+	if req.Weight > 1 {
+		proposedWeights[req.NodeAddress] = req.Weight
+	}
+	proposedNodes := append(slices.Clone(currentNodes), req.NodeAddress)
+	proposedIDs, proposedServers := buildRing(proposedNodes, proposedWeights, ns.virtualNodes)
 
 	// Storage processes are managed outside the web service. Verify that the
 	// destination is already running before changing membership.
-	destClient, closeDestination, err := ns.dialNode(ctx, req.NodeAddress)
+	_, closeDestination, err := ns.dialNode(ctx, req.NodeAddress)
 	if err != nil {
 		return &proto.AddNodeResponse{MigratedFileCount: 0}, fmt.Errorf(
 			"connect to destination node %s: %w",
@@ -207,64 +516,37 @@ func (ns *NetworkVideoContentService) AddNode(ctx context.Context, req *proto.Ad
 			err,
 		)
 	}
-	defer closeDestination()
-
-	// The first node has no existing peer to migrate files from.
-	if peerAddr == "" {
-		ns.mu.Lock()
-		ns.storageIds = proposedIDs
-		ns.storageServers = proposedServers
-		ns.mu.Unlock()
-		return &proto.AddNodeResponse{MigratedFileCount: 0}, nil
-	}
+	closeDestination()
 
-	srcClient, closeSource, err := ns.dialNode(ctx, peerAddr)
-	if err != nil {
-		return &proto.AddNodeResponse{MigratedFileCount: 0}, fmt.Errorf("connect to source node %s: %w", peerAddr, err)
-	}
-	defer closeSource()
-
-	start = time.Now()
-	listResponse, err := srcClient.ListFiles(ctx, &proto.BatchReadRequest{})
+	start := time.Now()
+	moves := planRangeMoves(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor)
+	log.Printf("AddNode planned %d ranges in %.3f ms", len(moves), durationMilliseconds(time.Since(start)))
+
+	progress, err := ns.beginMigration(ctx, Migration{
+		Operation: MigrationAdd,
+		Node:      req.NodeAddress,
+		Weight:    req.Weight,
+		Base:      base,
+		Target:    ns.membershipRecord(proposedNodes, proposedWeights),
+	})
 	if err != nil {
-		return &proto.AddNodeResponse{MigratedFileCount: 0}, fmt.Errorf("list files on source node %s: %w", peerAddr, err)
-	}
-	if listResponse == nil {
-		return &proto.AddNodeResponse{MigratedFileCount: 0}, fmt.Errorf("source node %s returned an empty response", peerAddr)
-	}
-	listFilesTime := time.Since(start)
-	log.Printf("AddNode ListFiles time: %.3f ms", durationMilliseconds(listFilesTime))
-	log.Printf("Number of files in Node %v: %v\n", peerAddr, len(listResponse.Entries))
-
-	filesToMigrate := make([]*proto.FileEntry, 0)
-	for _, entry := range listResponse.Entries {
-		filePath := entry.VideoId + "/" + entry.Filename
-
-		target := findStorageAddr(filePath, proposedIDs, proposedServers)
-		if target == req.NodeAddress {
-			filesToMigrate = append(filesToMigrate, &proto.FileEntry{
-				VideoId:  entry.VideoId,
-				Filename: entry.Filename,
-			})
-		}
+		return &proto.AddNodeResponse{MigratedFileCount: 0}, err
 	}
 
-	count := len(filesToMigrate)
 	start = time.Now()
-
-	written, readTime, writeTime, err := migrateFilesBatch(ctx, srcClient, destClient, filesToMigrate)
-	log.Printf("AddNode batch ReadFiles time: %.3f ms", durationMilliseconds(readTime))
-	log.Printf("AddNode batch WriteFiles time: %.3f ms", durationMilliseconds(writeTime))
+	count, err := ns.migrateRanges(ctx, "AddNode", moves, "", progress)
 	if err != nil {
-		return &proto.AddNodeResponse{MigratedFileCount: int32(written)}, err
+		ns.failMigration(ctx, progress, err)
+		return &proto.AddNodeResponse{MigratedFileCount: int32(count)}, err
 	}
-
 	end := time.Since(start)
 
-	ns.mu.Lock()
-	ns.storageIds = proposedIDs
-	ns.storageServers = proposedServers
-	ns.mu.Unlock()
+	if err := ns.publishRing(ctx, proposedNodes, proposedWeights, proposedIDs, proposedServers); err != nil {
+		ns.failMigration(ctx, progress, err)
+		return &proto.AddNodeResponse{MigratedFileCount: int32(count)}, err
+	}
+	ns.finishMigration(ctx)
+	ns.scheduleCleanup("AddNode", planRangeDrops(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor))
 	log.Printf("Added %d files to Node %s\n", count, req.NodeAddress)
 	log.Printf("AddNode: Time taken to migrate files: %.3f ms", durationMilliseconds(end))
 	if count > 0 {
@@ -286,79 +568,425 @@ func (ns *NetworkVideoContentService) RemoveNode(ctx context.Context, req *proto
 	ns.mu.RLock()
 	currentIDs := append([]uint64(nil), ns.storageIds...)
 	currentServers := cloneStorageServers(ns.storageServers)
+	proposedWeights := maps.Clone(ns.nodeWeights)
 	ns.mu.RUnlock()
 
-	if len(currentIDs) <= 1 {
+	currentNodes := physicalNodes(currentIDs, currentServers)
+	if len(currentNodes) <= 1 {
 		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, errors.New("cannot remove the last storage node")
 	}
-
-	removeNodeId := HashStringToUint64(req.NodeAddress)
-	if _, exists := currentServers[removeNodeId]; !exists {
+	if !slices.Contains(currentNodes, req.NodeAddress) {
 		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, fmt.Errorf("storage node does not exist: %s", req.NodeAddress)
 	}
 
-	proposedIDs := make([]uint64, 0, len(currentIDs)-1)
-	for _, id := range currentIDs {
-		if id != removeNodeId {
-			proposedIDs = append(proposedIDs, id)
-		}
-	}
-	proposedServers := cloneStorageServers(currentServers)
-	delete(proposedServers, removeNodeId)
+	base := ns.membershipRecord(slices.Clone(currentNodes), maps.Clone(proposedWeights))
+	remainingNodes := slices.DeleteFunc(currentNodes, func(address string) bool { return address == req.NodeAddress })
+	delete(proposedWeights, req.NodeAddress)
+	proposedIDs, proposedServers := buildRing(remainingNodes, proposedWeights, ns.virtualNodes)
 
 	start := time.Now()
-	peerAddr := findStorageAddr(req.NodeAddress, proposedIDs, proposedServers)
-	peerTime := time.Since(start)
-	log.Printf("Peer look up time: %.3f ms\n", durationMilliseconds(peerTime))
-
-	srcClient, closeSource, err := ns.dialNode(ctx, req.NodeAddress)
+	moves := planRangeMoves(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor)
+	log.Printf("RemoveNode planned %d ranges in %.3f ms", len(moves), durationMilliseconds(time.Since(start)))
+
+	progress, err := ns.beginMigration(ctx, Migration{
+		Operation: MigrationRemove,
+		Node:      req.NodeAddress,
+		Base:      base,
+		Target:    ns.membershipRecord(remainingNodes, proposedWeights),
+	})
 	if err != nil {
 		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, err
 	}
-	defer closeSource()
 
-	dstClient, closeDestination, err := ns.dialNode(ctx, peerAddr)
+	// Files are read from the departing node whenever it is a replica so the
+	// remaining nodes only receive writes during the drain.
+	start = time.Now()
+	count, err := ns.migrateRanges(ctx, "RemoveNode", moves, req.NodeAddress, progress)
 	if err != nil {
-		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, err
+		ns.failMigration(ctx, progress, err)
+		return &proto.RemoveNodeResponse{MigratedFileCount: int32(count)}, err
 	}
-	defer closeDestination()
+	end := time.Since(start)
 
-	start = time.Now()
-	response, err := srcClient.ListFiles(ctx, &proto.BatchReadRequest{})
+	log.Printf("RemoveNode: Time taken to migrate files: %.3f ms\n", durationMilliseconds(end))
+	if count > 0 {
+		log.Printf(
+			"RemoveNode: Average time per file: %.3f ms",
+			durationMilliseconds(end)/float64(count),
+		)
+	}
+
+	if err := ns.publishRing(ctx, remainingNodes, proposedWeights, proposedIDs, proposedServers); err != nil {
+		ns.failMigration(ctx, progress, err)
+		return &proto.RemoveNodeResponse{MigratedFileCount: int32(count)}, err
+	}
+	ns.finishMigration(ctx)
+	// The removed node is outside the new ring, so every range it held is
+	// dropped and its copies are deleted if it can still be reached.
+	ns.scheduleCleanup("RemoveNode", planRangeDrops(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor))
+
+	return &proto.RemoveNodeResponse{MigratedFileCount: int32(count)}, nil
+}
+
+// ReweightNode changes a node's share of the ring. Only the ranges gained or
+// lost by its tokens change replica sets, so only those files are copied.
+func (ns *NetworkVideoContentService) ReweightNode(ctx context.Context, req *proto.ReweightNodeRequest) (*proto.ReweightNodeResponse, error) {
+	operationStart := time.Now()
+	defer func() {
+		log.Printf("ReweightNode total time: %.3f ms", durationMilliseconds(time.Since(operationStart)))
+	}()
+
+	ns.membershipMu.Lock()
+	defer ns.membershipMu.Unlock()
+
+	if req.Weight == 0 || req.Weight > maxNodeWeight {
+		return &proto.ReweightNodeResponse{}, fmt.Errorf("storage node weight must be between 1 and %d", maxNodeWeight)
+	}
+
+	ns.mu.RLock()
+	currentIDs := append([]uint64(nil), ns.storageIds...)
+	currentServers := cloneStorageServers(ns.storageServers)
+	proposedWeights := maps.Clone(ns.nodeWeights)
+	ns.mu.RUnlock()
+
+	currentNodes := physicalNodes(currentIDs, currentServers)
+	if !slices.Contains(currentNodes, req.NodeAddress) {
+		return &proto.ReweightNodeResponse{}, fmt.Errorf("storage node does not exist: %s", req.NodeAddress)
+	}
+	if nodeWeight(proposedWeights, req.NodeAddress) == int(req.Weight) {
+		return &proto.ReweightNodeResponse{MigratedFileCount: 0}, nil
+	}
+
+	base := ns.membershipRecord(currentNodes, maps.Clone(proposedWeights))
+	if req.Weight == 1 {
+		delete(proposedWeights, req.NodeAddress)
+	} else {
+		proposedWeights[req.NodeAddress] = req.Weight
+	}
+	proposedIDs, proposedServers := buildRing(currentNodes, proposedWeights, ns.virtualNodes)
+
+	moves := planRangeMoves(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor)
+	log.Printf("ReweightNode planned %d ranges for %s", len(moves), req.NodeAddress)
+
+	progress, err := ns.beginMigration(ctx, Migration{
+		Operation: MigrationReweight,
+		Node:      req.NodeAddress,
+		Weight:    req.Weight,
+		Base:      base,
+		Target:    ns.membershipRecord(currentNodes, proposedWeights),
+	})
 	if err != nil {
-		log.Printf("ListFiles RPC failed: %v\n", err)
-		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, err
+		return &proto.ReweightNodeResponse{MigratedFileCount: 0}, err
 	}
-	if response == nil {
-		return &proto.RemoveNodeResponse{MigratedFileCount: 0}, errors.New("source node returned an empty response")
+
+	count, err := ns.migrateRanges(ctx, "ReweightNode", moves, "", progress)
+	if err != nil {
+		ns.failMigration(ctx, progress, err)
+		return &proto.ReweightNodeResponse{MigratedFileCount: int32(count)}, err
+	}
+	if err := ns.publishRing(ctx, currentNodes, proposedWeights, proposedIDs, proposedServers); err != nil {
+		ns.failMigration(ctx, progress, err)
+		return &proto.ReweightNodeResponse{MigratedFileCount: int32(count)}, err
 	}
-	listFilesTime := time.Since(start)
-	log.Printf("RemoveNode ListFiles time: %.3f ms", durationMilliseconds(listFilesTime))
-	log.Printf("Number of files: %v\n", len(response.Entries))
+	ns.finishMigration(ctx)
+	ns.scheduleCleanup("ReweightNode", planRangeDrops(currentIDs, currentServers, proposedIDs, proposedServers, ns.replicationFactor))
+	log.Printf("Reweighted node %s to %d and migrated %d files", req.NodeAddress, req.Weight, count)
 
-	start = time.Now()
-	written, readTime, writeTime, err := migrateFilesBatch(ctx, srcClient, dstClient, response.Entries)
-	log.Printf("RemoveNode batch ReadFiles time: %.3f ms", durationMilliseconds(readTime))
-	log.Printf("RemoveNode batch WriteFiles time: %.3f ms", durationMilliseconds(writeTime))
+	return &proto.ReweightNodeResponse{MigratedFileCount: int32(count)}, nil
+}
+
+// publishRing saves the new membership, when a store is configured, and then
+// switches reads and writes to the new ring. A concurrent change made through
+// another web instance fails the save and leaves the current ring in place.
+func (ns *NetworkVideoContentService) publishRing(
+	ctx context.Context,
+	nodes []string,
+	weights map[string]uint32,
+	storageIDs []uint64,
+	storageServers map[uint64]string,
+) error {
+	ns.mu.RLock()
+	revision := ns.membershipRevision
+	ns.mu.RUnlock()
+
+	if ns.membership != nil {
+		newRevision, err := ns.membership.Save(ctx, ns.membershipRecord(nodes, weights), revision)
+		if err != nil {
+			return fmt.Errorf("save ring membership: %w", err)
+		}
+		revision = newRevision
+	}
+
+	ns.mu.Lock()
+	ns.storageIds = storageIDs
+	ns.storageServers = storageServers
+	ns.nodeWeights = weights
+	ns.membershipRevision = max(ns.membershipRevision, revision)
+	ns.mu.Unlock()
+
+	ns.syncPool()
+	return nil
+}
+
+// syncPool keeps one pooled connection for every node in the current ring.
+func (ns *NetworkVideoContentService) syncPool() {
+	ns.mu.RLock()
+	nodes := physicalNodes(ns.storageIds, ns.storageServers)
+	ns.mu.RUnlock()
+
+	ns.pool.update(nodes)
+}
+
+func (ns *NetworkVideoContentService) membershipRecord(nodes []string, weights map[string]uint32) RingMembership {
+	return RingMembership{
+		Nodes:             nodes,
+		Weights:           weights,
+		VirtualNodes:      ns.virtualNodes,
+		ReplicationFactor: ns.replicationFactor,
+	}
+}
+
+// LoadMembership replaces the ring built from the constructor arguments with
+// the stored membership. On first start the store is empty and is seeded with
+// the constructor's nodes instead.
+func (ns *NetworkVideoContentService) LoadMembership(ctx context.Context) error {
+	if ns.membership == nil {
+		return nil
+	}
+
+	ns.membershipMu.Lock()
+	defer ns.membershipMu.Unlock()
+
+	stored, err := ns.membership.Load(ctx)
 	if err != nil {
-		return &proto.RemoveNodeResponse{MigratedFileCount: int32(written)}, err
+		return fmt.Errorf("load ring membership: %w", err)
 	}
-	end := time.Since(start)
+	if stored == nil {
+		ns.mu.RLock()
+		nodes := physicalNodes(ns.storageIds, ns.storageServers)
+		weights := maps.Clone(ns.nodeWeights)
+		ns.mu.RUnlock()
 
-	log.Printf("RemoveNode: Time taken to migrate files: %.3f ms\n", durationMilliseconds(end))
-	if len(response.Entries) > 0 {
-		log.Printf(
-			"RemoveNode: Average time per file: %.3f ms",
-			durationMilliseconds(end)/float64(len(response.Entries)),
+		revision, err := ns.membership.Save(ctx, ns.membershipRecord(nodes, weights), 0)
+		if err != nil {
+			return fmt.Errorf("seed ring membership: %w", err)
+		}
+		ns.mu.Lock()
+		ns.membershipRevision = revision
+		ns.mu.Unlock()
+		log.Printf("Seeded ring membership revision %d: %v", revision, nodes)
+		return nil
+	}
+
+	if err := ns.checkMembershipSettings(*stored); err != nil {
+		return err
+	}
+	ns.applyMembership(*stored)
+	return nil
+}
+
+// WatchMembership applies ring changes saved by other web instances until ctx
+// is done.
+func (ns *NetworkVideoContentService) WatchMembership(ctx context.Context) {
+	if ns.membership == nil {
+		return
+	}
+
+	ns.mu.RLock()
+	revision := ns.membershipRevision
+	ns.mu.RUnlock()
+
+	ns.membership.Watch(ctx, revision, func(membership RingMembership) {
+		if err := ns.checkMembershipSettings(membership); err != nil {
+			log.Printf("Ignoring ring membership revision %d: %v", membership.Revision, err)
+			return
+		}
+		ns.applyMembership(membership)
+	})
+}
+
+func (ns *NetworkVideoContentService) checkMembershipSettings(membership RingMembership) error {
+	if membership.VirtualNodes != ns.virtualNodes || membership.ReplicationFactor != ns.replicationFactor {
+		return fmt.Errorf(
+			"stored ring uses %d virtual nodes and replication factor %d, but this service uses %d and %d",
+			membership.VirtualNodes,
+			membership.ReplicationFactor,
+			ns.virtualNodes,
+			ns.replicationFactor,
 		)
 	}
+	return nil
+}
+
+func (ns *NetworkVideoContentService) applyMembership(membership RingMembership) {
+	weights := maps.Clone(membership.Weights)
+	if weights == nil {
+		weights = make(map[string]uint32)
+	}
+	storageIDs, storageServers := buildRing(membership.Nodes, weights, ns.virtualNodes)
 
 	ns.mu.Lock()
-	ns.storageIds = proposedIDs
-	ns.storageServers = proposedServers
+	if membership.Revision <= ns.membershipRevision {
+		ns.mu.Unlock()
+		return
+	}
+	ns.storageIds = storageIDs
+	ns.storageServers = storageServers
+	ns.nodeWeights = weights
+	ns.membershipRevision = membership.Revision
 	ns.mu.Unlock()
 
-	return &proto.RemoveNodeResponse{MigratedFileCount: int32(len(response.Entries))}, nil
+	ns.pool.update(physicalNodes(storageIDs, storageServers))
+	log.Printf("Applied ring membership revision %d: %v", membership.Revision, membership.Nodes)
+}
+
+// migrateRanges copies every file in the planned ranges from one current
+// replica to the nodes joining its replica set. Each source is listed once and
+// its files are grouped by destination, so a membership change touches every
+// affected range rather than a single neighbouring node. Progress is recorded
+// in progress after every page, and ranges it marks done are skipped. It
+// returns the number of file copies written.
+func (ns *NetworkVideoContentService) migrateRanges(
+	ctx context.Context,
+	operation string,
+	moves []rangeMove,
+	preferredSource string,
+	progress *Migration,
+) (int, error) {
+	movesBySource := make(map[string][]rangeMove)
+	for _, move := range moves {
+		source := move.sources[0]
+		if slices.Contains(move.sources, preferredSource) {
+			source = preferredSource
+		}
+		movesBySource[source] = append(movesBySource[source], move)
+	}
+
+	count := 0
+	for sourceAddr, sourceMoves := range movesBySource {
+		srcClient, closeSource, err := ns.dialNode(ctx, sourceAddr)
+		if err != nil {
+			return count, fmt.Errorf("connect to source node %s: %w", sourceAddr, err)
+		}
+
+		written, err := ns.migrateFromSource(ctx, operation, sourceAddr, srcClient, sourceMoves, progress)
+		closeSource()
+		count += written
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (ns *NetworkVideoContentService) migrateFromSource(
+	ctx context.Context,
+	operation string,
+	sourceAddr string,
+	srcClient storageRPCClient,
+	moves []rangeMove,
+	progress *Migration,
+) (int, error) {
+	start := time.Now()
+	listed := 0
+	count := 0
+	for _, move := range moves {
+		id := move.id()
+		if progress.Ranges[id].Done {
+			continue
+		}
+		// Ring arcs are (start, end]; the storage node takes [start, end).
+		request := &proto.ListFilesInRangeRequest{Start: move.start + 1, End: move.end + 1, Limit: listPageSize}
+		err := forEachFilePage(progress.Ranges[id].Cursor, func(cursor string) (*proto.ListFilesResponse, error) {
+			request.Cursor = cursor
+			return srcClient.ListFilesInRange(ctx, request)
+		}, func(page []*proto.FileEntry, next string) error {
+			listed += len(page)
+			written, err := ns.migratePage(ctx, operation, sourceAddr, srcClient, move.targets, page)
+			count += written
+			if err != nil {
+				return err
+			}
+			progress.Ranges[id] = RangeProgress{Cursor: next, Done: next == ""}
+			ns.saveMigration(ctx, progress)
+			return nil
+		})
+		if err != nil {
+			return count, fmt.Errorf("migrate from source node %s: %w", sourceAddr, err)
+		}
+	}
+	log.Printf("%s listed %d files in %d ranges on %s and copied %d in %.3f ms",
+		operation, listed, len(moves), sourceAddr, count, durationMilliseconds(time.Since(start)))
+	return count, nil
+}
+
+// forEachFilePage fetches a listing one page at a time through list, which is
+// given the cursor of the next page, and calls visit with each page in turn,
+// so the whole listing is never held at once. The listing starts at cursor,
+// and visit is also given the cursor that follows its page, which is empty
+// after the last page.
+func forEachFilePage(
+	cursor string,
+	list func(cursor string) (*proto.ListFilesResponse, error),
+	visit func(page []*proto.FileEntry, next string) error,
+) error {
+	for {
+		response, err := list(cursor)
+		if err != nil {
+			return fmt.Errorf("list files after %q: %w", cursor, err)
+		}
+		if response == nil {
+			return errors.New("list files returned an empty response")
+		}
+		if err := visit(response.Entries, response.NextCursor); err != nil {
+			return err
+		}
+		if response.NextCursor == "" {
+			return nil
+		}
+		if response.NextCursor == cursor {
+			return fmt.Errorf("list files did not advance past %q", cursor)
+		}
+		cursor = response.NextCursor
+	}
+}
+
+// migratePage copies one page of listed files to each of targets, skipping
+// the files a target already holds.
+func (ns *NetworkVideoContentService) migratePage(
+	ctx context.Context,
+	operation string,
+	sourceAddr string,
+	srcClient storageRPCClient,
+	targets []string,
+	page []*proto.FileEntry,
+) (int, error) {
+	if len(page) == 0 {
+		return 0, nil
+	}
+
+	count := 0
+	for _, destination := range targets {
+		dstClient, closeDestination, err := ns.dialNode(ctx, destination)
+		if err != nil {
+			return count, fmt.Errorf("connect to destination node %s: %w", destination, err)
+		}
+
+		missing, err := missingFiles(ctx, srcClient, dstClient, page)
+		if err != nil {
+			closeDestination()
+			return count, fmt.Errorf("check files on destination node %s: %w", destination, err)
+		}
+		written, readTime, writeTime, err := migrateFilesBatch(ctx, srcClient, dstClient, missing)
+		closeDestination()
+		count += written
+		log.Printf("%s batch ReadFiles time from %s: %.3f ms", operation, sourceAddr, durationMilliseconds(readTime))
+		log.Printf("%s batch WriteFiles time to %s: %.3f ms", operation, destination, durationMilliseconds(writeTime))
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
 }
 
 func migrateFilesBatch(
@@ -371,6 +999,24 @@ func migrateFilesBatch(
 	var totalWriteTime time.Duration
 	written := 0
 
+	// Files above largeFileThreshold are streamed one at a time; the rest are
+	// read and written in bounded batches.
+	batched := make([]*proto.FileEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Size <= largeFileThreshold {
+			batched = append(batched, entry)
+			continue
+		}
+		copyStart := time.Now()
+		err := copyFileStream(ctx, source, destination, entry)
+		totalWriteTime += time.Since(copyStart)
+		if err != nil {
+			return written, totalReadTime, totalWriteTime, fmt.Errorf("stream %s/%s: %w", entry.VideoId, entry.Filename, err)
+		}
+		written++
+	}
+	entries = batched
+
 	for start := 0; start < len(entries); start += storageBatchSize {
 		end := min(start+storageBatchSize, len(entries))
 		requests := make([]*proto.ReadRequest, 0, end-start)
@@ -387,6 +1033,13 @@ func migrateFilesBatch(
 		if readResponse == nil || len(readResponse.Entries) != end-start {
 			return written, totalReadTime, totalWriteTime, fmt.Errorf("read batch returned %d of %d files", len(readResponse.GetEntries()), end-start)
 		}
+		// The destination verifies the same checksums on write; checking here
+		// as well stops a corrupted copy before it is sent anywhere.
+		for _, entry := range readResponse.Entries {
+			if err := storage.VerifyChecksum(entry.Data, entry.Sha256); err != nil {
+				return written, totalReadTime, totalWriteTime, fmt.Errorf("%w in %s/%s read from source", err, entry.VideoId, entry.Filename)
+			}
+		}
 
 		writeStart := time.Now()
 		writeResponse, err := destination.WriteFiles(ctx, &proto.BatchWriteRequest{Entries: readResponse.Entries})
@@ -417,6 +1070,12 @@ func findStorageAddr(key string, storageIDs []uint64, storageServers map[uint64]
 	return storageServers[storageIDs[0]]
 }
 
+// findStorageAddrs returns up to count distinct storage addresses for a key,
+// starting with the primary owner.
+func findStorageAddrs(key string, storageIDs []uint64, storageServers map[uint64]string, count int) []string {
+	return replicasForHash(HashStringToUint64(key), storageIDs, storageServers, count)
+}
+
 func cloneStorageServers(source map[uint64]string) map[uint64]string {
 	result := make(map[uint64]string, len(source)+1)
 	for id, address := range source {
@@ -425,41 +1084,40 @@ func cloneStorageServers(source map[uint64]string) map[uint64]string {
 	return result
 }
 
+// dialNode returns a client backed by the pooled connection for address. The
+// returned close function is a no-op for pooled connections; they stay open
+// until the node leaves the ring. A node outside the ring, such as one whose
+// copies a cleanup deletes, gets its own connection that close shuts down, so
+// it does not rejoin the pool.
 func (ns *NetworkVideoContentService) dialNode(ctx context.Context, address string) (storageRPCClient, func() error, error) {
 	if ns.dialStorageNode != nil {
 		return ns.dialStorageNode(address)
 	}
 
-	conn, err := grpc.NewClient(
-		address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(proto.MaxMessageSize),
-			grpc.MaxCallSendMsgSize(proto.MaxMessageSize),
-		),
-	)
+	conn, pooled, err := ns.pool.get(address)
 	if err != nil {
 		return nil, nil, err
 	}
-	conn.Connect()
-	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
-		if !conn.WaitForStateChange(ctx, state) {
-			conn.Close()
-			return nil, nil, ctx.Err()
-		}
+	closeConn := func() error { return nil }
+	if !pooled {
+		closeConn = conn.Close
+	}
+	if err := waitForReady(ctx, conn); err != nil {
+		closeConn()
+		return nil, nil, err
 	}
-	return proto.NewVideoContentStorageServiceClient(conn), conn.Close, nil
+	return proto.NewVideoContentStorageServiceClient(conn), closeConn, nil
 }
 
 func (ns *NetworkVideoContentService) ListNodes(ctx context.Context, req *proto.ListNodesRequest) (*proto.ListNodesResponse, error) {
 	ns.mu.RLock()
 	defer ns.mu.RUnlock()
 
-	nodes := make([]string, 0, len(ns.storageIds))
-
-	for _, id := range ns.storageIds {
-		nodes = append(nodes, ns.storageServers[id])
+	nodes := physicalNodes(ns.storageIds, ns.storageServers)
+	weights := make(map[string]uint32, len(nodes))
+	for _, address := range nodes {
+		weights[address] = uint32(nodeWeight(ns.nodeWeights, address))
 	}
 
-	return &proto.ListNodesResponse{Nodes: nodes}, nil
+	return &proto.ListNodesResponse{Nodes: nodes, Weights: weights}, nil
 }
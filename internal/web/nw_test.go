@@ -1,14 +1,22 @@
 package web
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"tritontube/internal/proto"
+	"tritontube/internal/storage"
 
 	"google.golang.org/grpc"
 )
@@ -246,15 +254,82 @@ type fakeStorageRPCClient struct {
 	writeResponse *proto.BatchWriteResponse
 	writeErr      error
 
-	writeRequests []*proto.BatchWriteRequest
+	writeRequests  []*proto.BatchWriteRequest
+	streamedChunks int
+	deleteErr      error
+	listPageCalls  int
 }
 
-func (client *fakeStorageRPCClient) ListFiles(
-	context.Context,
-	*proto.BatchReadRequest,
-	...grpc.CallOption,
-) (*proto.BatchReadResponse, error) {
-	return client.readResponse, client.readErr
+func (client *fakeStorageRPCClient) ListFilesPage(
+	_ context.Context,
+	request *proto.ListFilesRequest,
+	_ ...grpc.CallOption,
+) (*proto.ListFilesResponse, error) {
+	client.listPageCalls++
+	if client.readErr != nil || client.readResponse == nil {
+		return nil, client.readErr
+	}
+	entries := slices.Clone(client.readResponse.Entries)
+	slices.SortFunc(entries, func(a, b *proto.FileEntry) int {
+		return cmp.Or(cmp.Compare(a.VideoId, b.VideoId), cmp.Compare(a.Filename, b.Filename))
+	})
+	entries = slices.DeleteFunc(entries, func(entry *proto.FileEntry) bool {
+		return !strings.HasPrefix(entry.VideoId, request.VideoIdPrefix) || entry.VideoId+"/"+entry.Filename <= request.Cursor
+	})
+	response := &proto.ListFilesResponse{Entries: entries}
+	if len(entries) > int(request.Limit) {
+		response.Entries = entries[:request.Limit]
+		last := response.Entries[len(response.Entries)-1]
+		response.NextCursor = last.VideoId + "/" + last.Filename
+	}
+	return response, nil
+}
+
+func (client *fakeStorageRPCClient) ListFilesInRange(
+	_ context.Context,
+	request *proto.ListFilesInRangeRequest,
+	_ ...grpc.CallOption,
+) (*proto.ListFilesResponse, error) {
+	client.listPageCalls++
+	if client.readErr != nil || client.readResponse == nil {
+		return nil, client.readErr
+	}
+	span := request.End - request.Start
+	entries := slices.DeleteFunc(slices.Clone(client.readResponse.Entries), func(entry *proto.FileEntry) bool {
+		return span != 0 && HashStringToUint64(entry.VideoId+"/"+entry.Filename)-request.Start >= span
+	})
+	slices.SortFunc(entries, func(a, b *proto.FileEntry) int {
+		return cmp.Compare(HashStringToUint64(a.VideoId+"/"+a.Filename)-request.Start, HashStringToUint64(b.VideoId+"/"+b.Filename)-request.Start)
+	})
+	// The fake's cursor is simply the number of entries already returned.
+	offset, _ := strconv.Atoi(request.Cursor)
+	entries = entries[min(offset, len(entries)):]
+	response := &proto.ListFilesResponse{Entries: entries}
+	if len(entries) > int(request.Limit) {
+		response.Entries = entries[:request.Limit]
+		response.NextCursor = strconv.Itoa(offset + int(request.Limit))
+	}
+	return response, nil
+}
+
+func (client *fakeStorageRPCClient) DeleteFile(
+	_ context.Context,
+	request *proto.DeleteFileRequest,
+	_ ...grpc.CallOption,
+) (*proto.DeleteFileResponse, error) {
+	if client.deleteErr != nil {
+		return nil, client.deleteErr
+	}
+	if client.readResponse == nil {
+		return &proto.DeleteFileResponse{}, nil
+	}
+	for index, entry := range client.readResponse.Entries {
+		if entry.VideoId == request.VideoId && entry.Filename == request.Filename {
+			client.readResponse.Entries = slices.Delete(client.readResponse.Entries, index, index+1)
+			return &proto.DeleteFileResponse{Deleted: true}, nil
+		}
+	}
+	return &proto.DeleteFileResponse{}, nil
 }
 
 func (client *fakeStorageRPCClient) ReadFile(
@@ -270,12 +345,103 @@ func (client *fakeStorageRPCClient) ReadFile(
 	}
 	for _, entry := range client.readResponse.Entries {
 		if entry.VideoId == request.VideoId && entry.Filename == request.Filename {
-			return &proto.ReadResponse{Data: entry.Data}, nil
+			return &proto.ReadResponse{Data: entry.Data, Sha256: entry.Sha256}, nil
 		}
 	}
 	return nil, fmt.Errorf("file not found: %s/%s", request.VideoId, request.Filename)
 }
 
+func (client *fakeStorageRPCClient) ReadFileRange(
+	ctx context.Context,
+	request *proto.ReadRangeRequest,
+	options ...grpc.CallOption,
+) (*proto.ReadRangeResponse, error) {
+	response, err := client.ReadFile(ctx, &proto.ReadRequest{VideoId: request.VideoId, Filename: request.Filename}, options...)
+	if err != nil || response == nil {
+		return nil, err
+	}
+	size := int64(len(response.Data))
+	offset := request.Offset
+	if offset < 0 {
+		offset = max(size+offset, 0)
+	}
+	if offset >= size {
+		return &proto.ReadRangeResponse{Size: size, Sha256: response.Sha256}, nil
+	}
+	end := size
+	if request.Length >= 0 {
+		end = min(end, offset+request.Length)
+	}
+	return &proto.ReadRangeResponse{Data: response.Data[offset:end], Size: size, Sha256: response.Sha256}, nil
+}
+
+func (client *fakeStorageRPCClient) ReadFileStream(
+	ctx context.Context,
+	request *proto.ReadRangeRequest,
+	options ...grpc.CallOption,
+) (grpc.ServerStreamingClient[proto.FileChunk], error) {
+	response, err := client.ReadFileRange(ctx, request, options...)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, errors.New("empty response")
+	}
+	chunks := []*proto.FileChunk{{VideoId: request.VideoId, Filename: request.Filename, Size: response.Size, Sha256: response.Sha256}}
+	for data := response.Data; len(data) > 0; data = data[min(len(data), proto.StreamChunkSize):] {
+		chunks = append(chunks, &proto.FileChunk{Data: data[:min(len(data), proto.StreamChunkSize)]})
+	}
+	return &fakeReadStream{chunks: chunks}, nil
+}
+
+func (client *fakeStorageRPCClient) WriteFileStream(
+	context.Context,
+	...grpc.CallOption,
+) (grpc.ClientStreamingClient[proto.FileChunk, proto.WriteResponse], error) {
+	return &fakeWriteStream{client: client}, nil
+}
+
+type fakeReadStream struct {
+	grpc.ClientStream
+	chunks []*proto.FileChunk
+}
+
+func (stream *fakeReadStream) Recv() (*proto.FileChunk, error) {
+	if len(stream.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := stream.chunks[0]
+	stream.chunks = stream.chunks[1:]
+	return chunk, nil
+}
+
+type fakeWriteStream struct {
+	grpc.ClientStream
+	client *fakeStorageRPCClient
+	entry  *proto.FileEntry
+	chunks int
+}
+
+func (stream *fakeWriteStream) Send(chunk *proto.FileChunk) error {
+	if stream.entry == nil {
+		stream.entry = &proto.FileEntry{VideoId: chunk.VideoId, Filename: chunk.Filename, Sha256: chunk.Sha256}
+	}
+	stream.entry.Data = append(stream.entry.Data, chunk.Data...)
+	stream.chunks++
+	return nil
+}
+
+func (stream *fakeWriteStream) CloseAndRecv() (*proto.WriteResponse, error) {
+	stream.client.streamedChunks += stream.chunks
+	stream.client.writeRequests = append(stream.client.writeRequests, &proto.BatchWriteRequest{
+		Entries: []*proto.FileEntry{stream.entry},
+	})
+	if stream.client.writeErr != nil {
+		return nil, stream.client.writeErr
+	}
+	return &proto.WriteResponse{}, nil
+}
+
 func (client *fakeStorageRPCClient) ReadFiles(
 	_ context.Context,
 	request *proto.BatchReadRequest,
@@ -296,13 +462,34 @@ func (client *fakeStorageRPCClient) ReadFiles(
 	return &proto.BatchReadResponse{Entries: entries}, nil
 }
 
+func (client *fakeStorageRPCClient) StatFiles(
+	ctx context.Context,
+	request *proto.BatchReadRequest,
+	options ...grpc.CallOption,
+) (*proto.BatchReadResponse, error) {
+	if len(request.Requests) == 0 {
+		return &proto.BatchReadResponse{}, nil
+	}
+	response, err := client.ReadFiles(ctx, request, options...)
+	if err != nil || response == nil {
+		return response, err
+	}
+	entries := make([]*proto.FileEntry, 0, len(response.Entries))
+	for _, entry := range response.Entries {
+		entries = append(entries, &proto.FileEntry{
+			VideoId: entry.VideoId, Filename: entry.Filename, Size: int64(len(entry.Data)), Sha256: entry.Sha256,
+		})
+	}
+	return &proto.BatchReadResponse{Entries: entries}, nil
+}
+
 func (client *fakeStorageRPCClient) WriteFile(
 	_ context.Context,
 	request *proto.WriteRequest,
 	_ ...grpc.CallOption,
 ) (*proto.WriteResponse, error) {
 	client.writeRequests = append(client.writeRequests, &proto.BatchWriteRequest{Entries: []*proto.FileEntry{{
-		VideoId: request.VideoId, Filename: request.Filename, Data: request.Data,
+		VideoId: request.VideoId, Filename: request.Filename, Data: request.Data, Sha256: request.Sha256,
 	}}})
 	if client.writeErr != nil {
 		return nil, client.writeErr
@@ -855,3 +1042,1054 @@ func TestConcurrentMembershipReadsAndChanges(t *testing.T) {
 		t.Fatalf("ListNodes returned %d nodes, want %d", len(response.Nodes), wantNodes)
 	}
 }
+
+func TestHashRingReplicasAreDistinctSuccessors(t *testing.T) {
+	ring := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+
+	for i := 0; i < 1_000; i++ {
+		key := testKey(i)
+		replicas := ring.FindStorageAddrs(key)
+		if len(replicas) != 2 {
+			t.Fatalf("FindStorageAddrs(%q) returned %d replicas, want 2", key, len(replicas))
+		}
+		if replicas[0] != ring.FindStorageAddr(key) {
+			t.Fatalf("first replica for %q = %q, want primary %q", key, replicas[0], ring.FindStorageAddr(key))
+		}
+		if replicas[0] == replicas[1] {
+			t.Fatalf("replicas for %q are not distinct: %v", key, replicas)
+		}
+	}
+}
+
+func TestHashRingReplicationFactorLargerThanRing(t *testing.T) {
+	ring := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(5))
+
+	replicas := ring.FindStorageAddrs("video/manifest.mpd")
+	if len(replicas) != len(testStorageNodes) {
+		t.Fatalf("FindStorageAddrs returned %v, want every node once", replicas)
+	}
+}
+
+func TestWriteBatchWritesEveryReplica(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{}
+	}
+	configureMigrationFakes(t, service, clients)
+
+	files := make([]ContentFile, 0, 10)
+	for index := range 10 {
+		files = append(files, ContentFile{
+			VideoID:  "video",
+			Filename: fmt.Sprintf("chunk-%05d.m4s", index),
+			Data:     []byte{byte(index)},
+		})
+	}
+
+	written, err := service.WriteBatch(files)
+	if err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+	if written != len(files) {
+		t.Fatalf("WriteBatch count = %d, want %d", written, len(files))
+	}
+
+	copies := make(map[string][]string)
+	for address, client := range clients {
+		for _, request := range client.writeRequests {
+			for _, entry := range request.Entries {
+				key := entry.VideoId + "/" + entry.Filename
+				copies[key] = append(copies[key], address)
+			}
+		}
+	}
+	for _, file := range files {
+		key := file.VideoID + "/" + file.Filename
+		want := service.FindStorageAddrs(key)
+		if len(copies[key]) != len(want) {
+			t.Fatalf("file %s written to %v, want %v", key, copies[key], want)
+		}
+		for _, address := range want {
+			if !slices.Contains(copies[key], address) {
+				t.Fatalf("file %s missing from replica %s", key, address)
+			}
+		}
+	}
+}
+
+func TestWriteBatchCountsOnlyFullyReplicatedFiles(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(3))
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{}
+	}
+	clients[testStorageNodes[1]].writeErr = errors.New("disk full")
+	configureMigrationFakes(t, service, clients)
+
+	written, err := service.WriteBatch([]ContentFile{{VideoID: "video", Filename: "manifest.mpd", Data: []byte("m")}})
+	if err == nil {
+		t.Fatal("WriteBatch expected a replica write error")
+	}
+	if written != 0 {
+		t.Fatalf("WriteBatch count = %d, want 0 when a replica fails", written)
+	}
+}
+
+func TestReadFallsBackToNextReplica(t *testing.T) {
+	const key = "video/chunk-00001.m4s"
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	replicas := service.FindStorageAddrs(key)
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readErr: errors.New("unexpected read")}
+	}
+	clients[replicas[0]] = &fakeStorageRPCClient{readErr: errors.New("node down")}
+	clients[replicas[1]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segment"),
+	}}}}
+	configureMigrationFakes(t, service, clients)
+
+	data, err := service.Read("video", "chunk-00001.m4s")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "segment" {
+		t.Fatalf("Read data = %q, want %q", data, "segment")
+	}
+}
+
+func TestOpenRangeFallsBackToNextReplica(t *testing.T) {
+	const key = "video/chunk-00001.m4s"
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	replicas := service.FindStorageAddrs(key)
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readErr: errors.New("unexpected read")}
+	}
+	clients[replicas[0]] = &fakeStorageRPCClient{readErr: errors.New("node down")}
+	clients[replicas[1]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segment"),
+	}}}}
+	configureMigrationFakes(t, service, clients)
+
+	reader, size, _, err := service.OpenRange("video", "chunk-00001.m4s", 2, 3)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read range: %v", err)
+	}
+	if string(data) != "gme" || size != int64(len("segment")) {
+		t.Fatalf("OpenRange = %q size %d, want %q size %d", data, size, "gme", len("segment"))
+	}
+}
+
+func TestReadFailsWhenEveryReplicaFails(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readErr: errors.New("node down")}
+	}
+	configureMigrationFakes(t, service, clients)
+
+	if _, err := service.Read("video", "manifest.mpd"); err == nil {
+		t.Fatal("Read expected an error when every replica fails")
+	}
+}
+
+func TestReadSkipsReplicaWithCorruptedCopy(t *testing.T) {
+	const key = "video/chunk-00001.m4s"
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	replicas := service.FindStorageAddrs(key)
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readErr: errors.New("unexpected read")}
+	}
+	checksum := storage.Checksum([]byte("segment"))
+	clients[replicas[0]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segmenT"), Sha256: checksum,
+	}}}}
+	clients[replicas[1]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segment"), Sha256: checksum,
+	}}}}
+	configureMigrationFakes(t, service, clients)
+
+	data, err := service.Read("video", "chunk-00001.m4s")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "segment" {
+		t.Fatalf("Read data = %q, want the intact copy %q", data, "segment")
+	}
+
+	clients[replicas[1]].readResponse.Entries[0].Data = []byte("Segment")
+	if _, err := service.Read("video", "chunk-00001.m4s"); !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("Read with every copy corrupted = %v, want %v", err, storage.ErrChecksumMismatch)
+	}
+}
+
+func TestOpenRangeVerifiesWholeFileReads(t *testing.T) {
+	const key = "video/chunk-00001.m4s"
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	replicas := service.FindStorageAddrs(key)
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readErr: errors.New("unexpected read")}
+	}
+	checksum := storage.Checksum([]byte("segment"))
+	clients[replicas[0]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segmenT"), Sha256: checksum,
+	}}}}
+	clients[replicas[1]] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "chunk-00001.m4s", Data: []byte("segment"), Sha256: checksum,
+	}}}}
+	configureMigrationFakes(t, service, clients)
+
+	reader, _, _, err := service.OpenRange("video", "chunk-00001.m4s", 0, -1)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "segment" {
+		t.Fatalf("OpenRange of whole file = %q, %v; want the intact copy %q", data, err, "segment")
+	}
+}
+
+func TestOpenRangeWithholdsEndOfCorruptedStream(t *testing.T) {
+	service := NewNetworkVideoContentService([]string{"node-a:9001"})
+	data := bytes.Repeat([]byte("x"), largeFileThreshold+proto.StreamChunkSize)
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)-1] = 'y'
+	client := &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{{
+		VideoId: "video", Filename: "original.mp4", Data: corrupted, Sha256: storage.Checksum(data),
+	}}}}
+	configureMigrationFakes(t, service, map[string]*fakeStorageRPCClient{"node-a:9001": client})
+
+	reader, size, _, err := service.OpenRange("video", "original.mp4", 0, -1)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	defer reader.Close()
+	received, err := io.ReadAll(reader)
+	if !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("reading corrupted stream = %v, want %v", err, storage.ErrChecksumMismatch)
+	}
+	if int64(len(received)) >= size {
+		t.Fatalf("received %d of %d bytes of a corrupted file, want it cut short", len(received), size)
+	}
+}
+
+func TestWritesCarryChecksums(t *testing.T) {
+	service := NewNetworkVideoContentService([]string{"node-a:9001"})
+	client := &fakeStorageRPCClient{}
+	configureMigrationFakes(t, service, map[string]*fakeStorageRPCClient{"node-a:9001": client})
+
+	large := bytes.Repeat([]byte("x"), largeFileThreshold+1)
+	files := []ContentFile{
+		{VideoID: "video", Filename: "manifest.mpd", Data: []byte("manifest")},
+		{VideoID: "video", Filename: "original.mp4", Data: large},
+	}
+	if _, err := service.WriteBatch(files); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+
+	sent := make(map[string][]byte)
+	for _, request := range client.writeRequests {
+		for _, entry := range request.Entries {
+			sent[entry.Filename] = entry.Sha256
+		}
+	}
+	for _, file := range files {
+		if want := storage.Checksum(file.Data); !bytes.Equal(sent[file.Filename], want) {
+			t.Errorf("%s sent with checksum %x, want %x", file.Filename, sent[file.Filename], want)
+		}
+	}
+}
+
+func TestMigrationRefusesCorruptedSourceFile(t *testing.T) {
+	source := &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{
+		{VideoId: "video", Filename: "manifest.mpd", Data: []byte("manifesT"), Sha256: storage.Checksum([]byte("manifest"))},
+	}}}
+	destination := &fakeStorageRPCClient{}
+
+	_, _, _, err := migrateFilesBatch(t.Context(), source, destination, []*proto.FileEntry{
+		{VideoId: "video", Filename: "manifest.mpd", Size: 8},
+	})
+	if !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("migrateFilesBatch = %v, want %v", err, storage.ErrChecksumMismatch)
+	}
+	if len(destination.writeRequests) != 0 {
+		t.Fatalf("destination received %d writes, want none", len(destination.writeRequests))
+	}
+}
+
+func TestAddNodeWithReplicationCopiesFilesGainingNewReplica(t *testing.T) {
+	const destinationAddress = "node-d:9004"
+
+	entries := make([]*proto.FileEntry, 0, 200)
+	for i := 0; i < 200; i++ {
+		entries = append(entries, &proto.FileEntry{
+			VideoId:  fmt.Sprintf("video-%d", i),
+			Filename: "segment.m4s",
+			Data:     []byte(fmt.Sprintf("data-%d", i)),
+		})
+	}
+
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	clients := map[string]*fakeStorageRPCClient{destinationAddress: {}}
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: entries}}
+	}
+	configureMigrationFakes(t, service, clients)
+
+	proposed := NewNetworkVideoContentService(appendCopy(testStorageNodes, destinationAddress), WithReplicationFactor(2))
+	expected := make(map[string]bool)
+	for _, entry := range entries {
+		key := entry.VideoId + "/" + entry.Filename
+		if slices.Contains(proposed.FindStorageAddrs(key), destinationAddress) {
+			expected[key] = true
+		}
+	}
+
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: destinationAddress})
+	if err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if response.MigratedFileCount != int32(len(expected)) {
+		t.Fatalf("migrated count = %d, want %d", response.MigratedFileCount, len(expected))
+	}
+	for _, request := range clients[destinationAddress].writeRequests {
+		for _, entry := range request.Entries {
+			key := entry.VideoId + "/" + entry.Filename
+			if !expected[key] {
+				t.Fatalf("AddNode copied file whose replica set excludes the destination: %s", key)
+			}
+			delete(expected, key)
+		}
+	}
+	if len(expected) != 0 {
+		t.Fatalf("AddNode omitted %d files that gained the new replica", len(expected))
+	}
+}
+
+func TestRemoveNodeWithReplicationRestoresReplicaCount(t *testing.T) {
+	const removedAddress = "node-b:9002"
+
+	entries := make([]*proto.FileEntry, 0, 200)
+	current := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	for i := 0; i < 200; i++ {
+		entry := &proto.FileEntry{
+			VideoId:  fmt.Sprintf("video-%d", i),
+			Filename: "segment.m4s",
+			Data:     []byte(fmt.Sprintf("data-%d", i)),
+		}
+		if slices.Contains(current.FindStorageAddrs(entry.VideoId+"/"+entry.Filename), removedAddress) {
+			entries = append(entries, entry)
+		}
+	}
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{}
+	}
+	clients[removedAddress].readResponse = &proto.BatchReadResponse{Entries: entries}
+	configureMigrationFakes(t, current, clients)
+
+	response, err := current.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: removedAddress})
+	if err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if response.MigratedFileCount != int32(len(entries)) {
+		t.Fatalf("migrated count = %d, want one new copy for each of %d files", response.MigratedFileCount, len(entries))
+	}
+
+	copies := make(map[string][]string)
+	for address, client := range clients {
+		for _, request := range client.writeRequests {
+			for _, entry := range request.Entries {
+				key := entry.VideoId + "/" + entry.Filename
+				copies[key] = append(copies[key], address)
+			}
+		}
+	}
+	for _, entry := range entries {
+		key := entry.VideoId + "/" + entry.Filename
+		replicas := current.FindStorageAddrs(key)
+		if len(replicas) != 2 || slices.Contains(replicas, removedAddress) {
+			t.Fatalf("replicas after removal for %s = %v", key, replicas)
+		}
+		if len(copies[key]) != 1 {
+			t.Fatalf("file %s copied to %v, want exactly one new replica", key, copies[key])
+		}
+	}
+}
+
+func TestHashRingVirtualNodesBalanceDistribution(t *testing.T) {
+	const keyCount = 30_000
+
+	ring := NewNetworkVideoContentService(testStorageNodes, WithVirtualNodes(128))
+	if len(ring.storageIds) != len(testStorageNodes)*128 {
+		t.Fatalf("ring has %d tokens, want %d", len(ring.storageIds), len(testStorageNodes)*128)
+	}
+
+	counts := make(map[string]int, len(testStorageNodes))
+	for i := 0; i < keyCount; i++ {
+		counts[ring.FindStorageAddr(testKey(i))]++
+	}
+
+	for _, node := range testStorageNodes {
+		share := percentage(counts[node], keyCount)
+		t.Logf("%s owns %d/%d keys (%.2f%%)", node, counts[node], keyCount, share)
+		if share < 25 || share > 42 {
+			t.Errorf("%s owns %.2f%% of keys, outside expected 25%%-42%% range", node, share)
+		}
+	}
+}
+
+func TestListNodesReportsPhysicalNodesWithVirtualNodes(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes, WithVirtualNodes(16))
+
+	response, err := service.ListNodes(t.Context(), &proto.ListNodesRequest{})
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	got := slices.Clone(response.Nodes)
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, testStorageNodes) {
+		t.Fatalf("ListNodes = %v, want %v", response.Nodes, testStorageNodes)
+	}
+}
+
+func TestAddNodeWithVirtualNodesMigratesFromEveryAffectedRange(t *testing.T) {
+	const destinationAddress = "node-d:9004"
+
+	service := NewNetworkVideoContentService(testStorageNodes, WithVirtualNodes(16))
+	proposed := NewNetworkVideoContentService(appendCopy(testStorageNodes, destinationAddress), WithVirtualNodes(16))
+
+	entriesByOwner := make(map[string][]*proto.FileEntry)
+	expected := make(map[string]string)
+	for i := 0; i < 2_000; i++ {
+		entry := &proto.FileEntry{VideoId: fmt.Sprintf("video-%d", i), Filename: "segment.m4s", Data: []byte("data")}
+		key := entry.VideoId + "/" + entry.Filename
+		owner := service.FindStorageAddr(key)
+		entriesByOwner[owner] = append(entriesByOwner[owner], entry)
+		if proposed.FindStorageAddr(key) == destinationAddress {
+			expected[key] = owner
+		}
+	}
+
+	sources := make(map[string]bool)
+	for _, owner := range expected {
+		sources[owner] = true
+	}
+	if len(sources) < 2 {
+		t.Fatalf("test data moves keys from %d nodes, want several", len(sources))
+	}
+
+	clients := map[string]*fakeStorageRPCClient{destinationAddress: {}}
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{
+			readResponse: &proto.BatchReadResponse{Entries: entriesByOwner[address]},
+		}
+	}
+	configureMigrationFakes(t, service, clients)
+
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: destinationAddress})
+	if err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if response.MigratedFileCount != int32(len(expected)) {
+		t.Fatalf("migrated count = %d, want %d", response.MigratedFileCount, len(expected))
+	}
+	for _, request := range clients[destinationAddress].writeRequests {
+		for _, entry := range request.Entries {
+			key := entry.VideoId + "/" + entry.Filename
+			if _, ok := expected[key]; !ok {
+				t.Fatalf("AddNode copied file not assigned to destination: %s", key)
+			}
+			delete(expected, key)
+		}
+	}
+	if len(expected) != 0 {
+		t.Fatalf("AddNode omitted %d reassigned files", len(expected))
+	}
+
+	nodes, err := service.ListNodes(t.Context(), &proto.ListNodesRequest{})
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if len(nodes.Nodes) != len(testStorageNodes)+1 {
+		t.Fatalf("ListNodes returned %v after adding %s", nodes.Nodes, destinationAddress)
+	}
+}
+
+func TestRemoveNodeWithVirtualNodesSpreadsFilesAcrossSuccessors(t *testing.T) {
+	const removedAddress = "node-b:9002"
+
+	service := NewNetworkVideoContentService(testStorageNodes, WithVirtualNodes(16))
+	entries := make([]*proto.FileEntry, 0)
+	for i := 0; i < 2_000; i++ {
+		entry := &proto.FileEntry{VideoId: fmt.Sprintf("video-%d", i), Filename: "segment.m4s", Data: []byte("data")}
+		if service.FindStorageAddr(entry.VideoId+"/"+entry.Filename) == removedAddress {
+			entries = append(entries, entry)
+		}
+	}
+
+	clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+	for _, address := range testStorageNodes {
+		clients[address] = &fakeStorageRPCClient{}
+	}
+	clients[removedAddress].readResponse = &proto.BatchReadResponse{Entries: entries}
+	configureMigrationFakes(t, service, clients)
+
+	response, err := service.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: removedAddress})
+	if err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if response.MigratedFileCount != int32(len(entries)) {
+		t.Fatalf("migrated count = %d, want %d", response.MigratedFileCount, len(entries))
+	}
+
+	for _, address := range testStorageNodes {
+		if address == removedAddress {
+			continue
+		}
+		if len(clients[address].writeRequests) == 0 {
+			t.Fatalf("no files from %s moved to successor %s", removedAddress, address)
+		}
+		for _, request := range clients[address].writeRequests {
+			for _, entry := range request.Entries {
+				key := entry.VideoId + "/" + entry.Filename
+				if owner := service.FindStorageAddr(key); owner != address {
+					t.Fatalf("file %s moved to %s, new owner is %s", key, address, owner)
+				}
+			}
+		}
+	}
+}
+
+func TestRangeMoveContainsWrapsPastZero(t *testing.T) {
+	move := rangeMove{start: 100, end: 10}
+
+	for _, hash := range []uint64{101, ^uint64(0), 0, 10} {
+		if !move.contains(hash) {
+			t.Fatalf("wrapping range (100, 10] does not contain %d", hash)
+		}
+	}
+	for _, hash := range []uint64{11, 50, 100} {
+		if move.contains(hash) {
+			t.Fatalf("wrapping range (100, 10] contains %d", hash)
+		}
+	}
+}
+
+type fakeMembershipStore struct {
+	mu       sync.Mutex
+	stored   *RingMembership
+	revision int64
+	saveErr  error
+}
+
+func (store *fakeMembershipStore) Load(context.Context) (*RingMembership, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.stored == nil {
+		return nil, nil
+	}
+	membership := *store.stored
+	return &membership, nil
+}
+
+func (store *fakeMembershipStore) Save(_ context.Context, membership RingMembership, expectedRevision int64) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.saveErr != nil {
+		return 0, store.saveErr
+	}
+	if expectedRevision != store.revision {
+		return 0, ErrMembershipConflict
+	}
+	store.revision++
+	membership.Revision = store.revision
+	store.stored = &membership
+	return store.revision, nil
+}
+
+func (store *fakeMembershipStore) Watch(context.Context, int64, func(RingMembership)) {}
+
+func TestLoadMembershipSeedsEmptyStore(t *testing.T) {
+	store := &fakeMembershipStore{}
+	service := NewNetworkVideoContentService(testStorageNodes, WithMembershipStore(store), WithReplicationFactor(2))
+
+	if err := service.LoadMembership(t.Context()); err != nil {
+		t.Fatalf("LoadMembership failed: %v", err)
+	}
+	if store.stored == nil {
+		t.Fatal("LoadMembership did not seed the empty store")
+	}
+	got := slices.Clone(store.stored.Nodes)
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, testStorageNodes) || store.stored.ReplicationFactor != 2 || store.stored.VirtualNodes != 1 {
+		t.Fatalf("seeded membership = %+v", store.stored)
+	}
+	if service.membershipRevision != store.revision {
+		t.Fatalf("service revision = %d, want %d", service.membershipRevision, store.revision)
+	}
+}
+
+func TestLoadMembershipReplacesCommandLineNodes(t *testing.T) {
+	store := &fakeMembershipStore{
+		stored:   &RingMembership{Nodes: []string{"node-x:9010", "node-y:9011"}, VirtualNodes: 1, ReplicationFactor: 1, Revision: 7},
+		revision: 7,
+	}
+	service := NewNetworkVideoContentService(testStorageNodes, WithMembershipStore(store))
+
+	if err := service.LoadMembership(t.Context()); err != nil {
+		t.Fatalf("LoadMembership failed: %v", err)
+	}
+	response, err := service.ListNodes(t.Context(), &proto.ListNodesRequest{})
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	got := slices.Clone(response.Nodes)
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, []string{"node-x:9010", "node-y:9011"}) {
+		t.Fatalf("ring after load = %v, want stored nodes", response.Nodes)
+	}
+}
+
+func TestLoadMembershipRejectsMismatchedRingSettings(t *testing.T) {
+	store := &fakeMembershipStore{
+		stored:   &RingMembership{Nodes: testStorageNodes, VirtualNodes: 64, ReplicationFactor: 1, Revision: 1},
+		revision: 1,
+	}
+	service := NewNetworkVideoContentService(testStorageNodes, WithMembershipStore(store))
+
+	if err := service.LoadMembership(t.Context()); err == nil {
+		t.Fatal("LoadMembership expected a virtual node mismatch error")
+	}
+}
+
+func TestAddNodePersistsMembership(t *testing.T) {
+	const sourceAddress = "node-a:9001"
+	const destinationAddress = "node-d:9004"
+
+	store := &fakeMembershipStore{}
+	service := NewNetworkVideoContentService([]string{sourceAddress}, WithMembershipStore(store))
+	if err := service.LoadMembership(t.Context()); err != nil {
+		t.Fatalf("LoadMembership failed: %v", err)
+	}
+	configureMigrationFakes(t, service, map[string]*fakeStorageRPCClient{
+		sourceAddress:      {readResponse: &proto.BatchReadResponse{}},
+		destinationAddress: {},
+	})
+
+	if _, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: destinationAddress}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if !reflect.DeepEqual(store.stored.Nodes, []string{sourceAddress, destinationAddress}) {
+		t.Fatalf("stored nodes = %v", store.stored.Nodes)
+	}
+
+	if _, err := service.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: sourceAddress}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if !reflect.DeepEqual(store.stored.Nodes, []string{destinationAddress}) {
+		t.Fatalf("stored nodes after removal = %v", store.stored.Nodes)
+	}
+}
+
+func TestAddNodeMembershipConflictKeepsOldRing(t *testing.T) {
+	const sourceAddress = "node-a:9001"
+	const destinationAddress = "node-d:9004"
+
+	store := &fakeMembershipStore{}
+	service := NewNetworkVideoContentService([]string{sourceAddress}, WithMembershipStore(store))
+	if err := service.LoadMembership(t.Context()); err != nil {
+		t.Fatalf("LoadMembership failed: %v", err)
+	}
+	configureMigrationFakes(t, service, map[string]*fakeStorageRPCClient{
+		sourceAddress:      {readResponse: &proto.BatchReadResponse{}},
+		destinationAddress: {},
+	})
+
+	// Another web instance changes the ring after this one loaded it.
+	if _, err := store.Save(t.Context(), RingMembership{Nodes: []string{"node-z:9999"}}, store.revision); err != nil {
+		t.Fatalf("concurrent save failed: %v", err)
+	}
+	oldIDs := append([]uint64(nil), service.storageIds...)
+
+	_, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: destinationAddress})
+	if !errors.Is(err, ErrMembershipConflict) {
+		t.Fatalf("AddNode error = %v, want ErrMembershipConflict", err)
+	}
+	if !reflect.DeepEqual(service.storageIds, oldIDs) {
+		t.Fatal("ring changed after a conflicting membership save")
+	}
+}
+
+func TestApplyMembershipIgnoresStaleRevisions(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes)
+
+	service.applyMembership(RingMembership{Nodes: []string{"node-x:9010"}, Revision: 5})
+	if got := service.FindStorageAddr("video/manifest.mpd"); got != "node-x:9010" {
+		t.Fatalf("owner after watch update = %q, want node-x:9010", got)
+	}
+
+	service.applyMembership(RingMembership{Nodes: []string{"node-y:9011"}, Revision: 4})
+	if got := service.FindStorageAddr("video/manifest.mpd"); got != "node-x:9010" {
+		t.Fatalf("stale revision replaced the ring: owner = %q", got)
+	}
+}
+
+func TestHashRingWeightsAllocateProportionalKeySpace(t *testing.T) {
+	const keyCount = 30_000
+
+	storageIDs, storageServers := buildRing(testStorageNodes, map[string]uint32{"node-a:9001": 2}, 128)
+	counts := make(map[string]int, len(testStorageNodes))
+	for i := 0; i < keyCount; i++ {
+		counts[findStorageAddr(testKey(i), storageIDs, storageServers)]++
+	}
+
+	heavyShare := percentage(counts["node-a:9001"], keyCount)
+	t.Logf("node-a:9001 with weight 2 owns %.2f%% of keys", heavyShare)
+	if heavyShare < 42 || heavyShare > 58 {
+		t.Fatalf("weight-2 node owns %.2f%% of keys, want about 50%%", heavyShare)
+	}
+	for _, node := range testStorageNodes[1:] {
+		if share := percentage(counts[node], keyCount); share < 18 || share > 32 {
+			t.Errorf("%s owns %.2f%% of keys, want about 25%%", node, share)
+		}
+	}
+}
+
+func TestAddNodeWithWeight(t *testing.T) {
+	const sourceAddress = "node-a:9001"
+	const destinationAddress = "node-d:9004"
+
+	service := NewNetworkVideoContentService([]string{sourceAddress}, WithVirtualNodes(4))
+	configureMigrationFakes(t, service, map[string]*fakeStorageRPCClient{
+		sourceAddress:      {readResponse: &proto.BatchReadResponse{}},
+		destinationAddress: {},
+	})
+
+	if _, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: destinationAddress, Weight: 3}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if len(service.storageIds) != 4+12 {
+		t.Fatalf("ring has %d tokens, want 16", len(service.storageIds))
+	}
+	response, err := service.ListNodes(t.Context(), &proto.ListNodesRequest{})
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if response.Weights[destinationAddress] != 3 || response.Weights[sourceAddress] != 1 {
+		t.Fatalf("ListNodes weights = %v", response.Weights)
+	}
+}
+
+func TestReweightNodeMigratesOnlyKeysWhoseOwnerChanges(t *testing.T) {
+	const reweighted = "node-a:9001"
+
+	for _, weight := range []uint32{3, 1} {
+		t.Run(fmt.Sprintf("weight %d", weight), func(t *testing.T) {
+			service := NewNetworkVideoContentService(testStorageNodes, WithVirtualNodes(8))
+			service.nodeWeights[reweighted] = 2
+			service.storageIds, service.storageServers = buildRing(testStorageNodes, service.nodeWeights, 8)
+
+			proposedIDs, proposedServers := buildRing(testStorageNodes, map[string]uint32{reweighted: weight}, 8)
+
+			entriesByOwner := make(map[string][]*proto.FileEntry)
+			expected := make(map[string]string)
+			for i := 0; i < 2_000; i++ {
+				entry := &proto.FileEntry{VideoId: fmt.Sprintf("video-%d", i), Filename: "segment.m4s", Data: []byte("data")}
+				key := entry.VideoId + "/" + entry.Filename
+				owner := service.FindStorageAddr(key)
+				entriesByOwner[owner] = append(entriesByOwner[owner], entry)
+				if newOwner := findStorageAddr(key, proposedIDs, proposedServers); newOwner != owner {
+					expected[key] = newOwner
+				}
+			}
+			if len(expected) == 0 {
+				t.Fatal("test data produced no keys whose owner changes")
+			}
+
+			clients := make(map[string]*fakeStorageRPCClient, len(testStorageNodes))
+			for _, address := range testStorageNodes {
+				clients[address] = &fakeStorageRPCClient{
+					readResponse: &proto.BatchReadResponse{Entries: entriesByOwner[address]},
+				}
+			}
+			configureMigrationFakes(t, service, clients)
+
+			response, err := service.ReweightNode(t.Context(), &proto.ReweightNodeRequest{NodeAddress: reweighted, Weight: weight})
+			if err != nil {
+				t.Fatalf("ReweightNode failed: %v", err)
+			}
+			if response.MigratedFileCount != int32(len(expected)) {
+				t.Fatalf("migrated count = %d, want %d", response.MigratedFileCount, len(expected))
+			}
+			for address, client := range clients {
+				for _, request := range client.writeRequests {
+					for _, entry := range request.Entries {
+						key := entry.VideoId + "/" + entry.Filename
+						if expected[key] != address {
+							t.Fatalf("file %s copied to %s, want only keys moving to it", key, address)
+						}
+						delete(expected, key)
+					}
+				}
+			}
+			if len(expected) != 0 {
+				t.Fatalf("ReweightNode omitted %d files whose owner changed", len(expected))
+			}
+			if !reflect.DeepEqual(service.storageIds, proposedIDs) {
+				t.Fatal("ReweightNode did not publish the reweighted ring")
+			}
+		})
+	}
+}
+
+func TestReweightNodeValidation(t *testing.T) {
+	service := NewNetworkVideoContentService(testStorageNodes)
+
+	if _, err := service.ReweightNode(t.Context(), &proto.ReweightNodeRequest{NodeAddress: "node-a:9001"}); err == nil {
+		t.Fatal("ReweightNode expected a zero-weight error")
+	}
+	if _, err := service.ReweightNode(t.Context(), &proto.ReweightNodeRequest{NodeAddress: "unknown:9009", Weight: 2}); err == nil {
+		t.Fatal("ReweightNode expected an unknown-node error")
+	}
+}
+
+func TestLargeFilesRoundTripThroughStreams(t *testing.T) {
+	address := startStorageNode(t)
+	service := NewNetworkVideoContentService([]string{address})
+	t.Cleanup(func() { service.Close() })
+
+	// The file is larger than proto.MaxMessageSize, so neither a batch write
+	// nor a single unary read could carry it.
+	data := make([]byte, proto.MaxMessageSize+proto.StreamChunkSize/2)
+	for index := range data {
+		data[index] = byte(index % 251)
+	}
+
+	if err := service.Write("video", "original.mp4", data); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	read, err := service.Read("video", "original.mp4")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Fatalf("Read returned %d bytes, want %d", len(read), len(data))
+	}
+
+	reader, size, _, err := service.OpenRange("video", "original.mp4", -(largeFileThreshold + 10), -1)
+	if err != nil {
+		t.Fatalf("OpenRange failed: %v", err)
+	}
+	defer reader.Close()
+	suffix, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read range: %v", err)
+	}
+	if size != int64(len(data)) || !bytes.Equal(suffix, data[len(data)-largeFileThreshold-10:]) {
+		t.Fatalf("OpenRange returned %d bytes of size %d", len(suffix), size)
+	}
+}
+
+func TestMigrationStreamsLargeFiles(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), largeFileThreshold+proto.StreamChunkSize)
+	source := &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{
+		{VideoId: "video", Filename: "original.mp4", Data: large, Size: int64(len(large))},
+		{VideoId: "video", Filename: "manifest.mpd", Data: []byte("manifest"), Size: 8},
+	}}}
+	destination := &fakeStorageRPCClient{}
+
+	written, _, _, err := migrateFilesBatch(t.Context(), source, destination, []*proto.FileEntry{
+		{VideoId: "video", Filename: "original.mp4", Size: int64(len(large))},
+		{VideoId: "video", Filename: "manifest.mpd", Size: 8},
+	})
+	if err != nil {
+		t.Fatalf("migrateFilesBatch failed: %v", err)
+	}
+	if written != 2 {
+		t.Fatalf("written = %d, want 2", written)
+	}
+	if destination.streamedChunks < 2 {
+		t.Fatalf("large file sent in %d chunks, want several", destination.streamedChunks)
+	}
+	if len(destination.writeRequests) != 2 {
+		t.Fatalf("destination received %d writes, want one stream and one batch", len(destination.writeRequests))
+	}
+	if got := destination.writeRequests[0].Entries[0]; got.Filename != "original.mp4" || !bytes.Equal(got.Data, large) {
+		t.Fatalf("streamed file = %s with %d bytes, want original.mp4 with %d", got.Filename, len(got.Data), len(large))
+	}
+}
+
+func TestMigrationListsSourceInPages(t *testing.T) {
+	const fileCount = 2*listPageSize + 10
+	entries := make([]*proto.FileEntry, 0, fileCount)
+	for index := range fileCount {
+		entries = append(entries, &proto.FileEntry{VideoId: "video", Filename: fmt.Sprintf("chunk-%05d.m4s", index)})
+	}
+	source := &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: entries}}
+
+	seen := make(map[string]bool, fileCount)
+	err := forEachFilePage("", func(cursor string) (*proto.ListFilesResponse, error) {
+		return source.ListFilesPage(t.Context(), &proto.ListFilesRequest{Cursor: cursor, Limit: listPageSize})
+	}, func(page []*proto.FileEntry, _ string) error {
+		if len(page) > listPageSize {
+			t.Fatalf("page has %d entries, want at most %d", len(page), listPageSize)
+		}
+		for _, entry := range page {
+			if seen[entry.Filename] {
+				t.Fatalf("%s listed twice", entry.Filename)
+			}
+			seen[entry.Filename] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachFilePage failed: %v", err)
+	}
+	if len(seen) != fileCount || source.listPageCalls != 3 {
+		t.Fatalf("listed %d files in %d calls, want %d files in 3 calls", len(seen), source.listPageCalls, fileCount)
+	}
+}
+
+func TestAddNodeCopiesRangesListedFromRealNodes(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	service := NewNetworkVideoContentService([]string{first}, WithVirtualNodes(8))
+	t.Cleanup(func() { service.Close() })
+
+	files := make([]ContentFile, 0, 100)
+	for index := range 100 {
+		files = append(files, ContentFile{
+			VideoID: "video", Filename: fmt.Sprintf("chunk-%05d.m4s", index), Data: []byte(fmt.Sprint(index)),
+		})
+	}
+	if _, err := service.WriteBatch(files); err != nil {
+		t.Fatalf("WriteBatch failed: %v", err)
+	}
+
+	response, err := service.AddNode(t.Context(), &proto.AddNodeRequest{NodeAddress: second})
+	if err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	moved := 0
+	for _, file := range files {
+		if service.FindStorageAddr(file.VideoID+"/"+file.Filename) == second {
+			moved++
+		}
+	}
+	if moved == 0 || int(response.MigratedFileCount) != moved {
+		t.Fatalf("AddNode migrated %d files, want the %d the new node owns", response.MigratedFileCount, moved)
+	}
+	for _, file := range files {
+		data, err := service.Read(file.VideoID, file.Filename)
+		if err != nil || !bytes.Equal(data, file.Data) {
+			t.Fatalf("Read(%s) after AddNode = %q, %v", file.Filename, data, err)
+		}
+	}
+}
+
+func TestDeleteRemovesVideoFromEveryNode(t *testing.T) {
+	nodes := []string{"node-a:8090", "node-b:8090", "node-c:8090"}
+	service := NewNetworkVideoContentService(nodes)
+	clients := make(map[string]*fakeStorageRPCClient)
+	for _, node := range nodes {
+		// Every node holds a copy, as if left behind by earlier migrations,
+		// alongside a file of another video.
+		clients[node] = &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{
+			{VideoId: "clip", Filename: "manifest.mpd"},
+			{VideoId: "clip", Filename: "chunk-0-00001.m4s"},
+			{VideoId: "other", Filename: "manifest.mpd"},
+		}}}
+	}
+	configureMigrationFakes(t, service, clients)
+
+	clients["node-b:8090"].deleteErr = errors.New("disk failure")
+	count, err := service.Delete("clip")
+	if err == nil {
+		t.Fatal("Delete succeeded although a node failed")
+	}
+	if count != 4 {
+		t.Fatalf("Delete removed %d files before failing, want 4 from the healthy nodes", count)
+	}
+
+	clients["node-b:8090"].deleteErr = nil
+	count, err = service.Delete("clip")
+	if err != nil {
+		t.Fatalf("retried Delete failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("retried Delete removed %d files, want the 2 left on node-b", count)
+	}
+	for node, client := range clients {
+		if len(client.readResponse.Entries) != 1 || client.readResponse.Entries[0].VideoId != "other" {
+			t.Fatalf("%s holds %v after delete, want only the other video", node, client.readResponse.Entries)
+		}
+	}
+}
+
+// hangingStorageRPCClient accepts calls and never answers them.
+type hangingStorageRPCClient struct {
+	fakeStorageRPCClient
+}
+
+func (client *hangingStorageRPCClient) ReadFileRange(
+	ctx context.Context,
+	_ *proto.ReadRangeRequest,
+	_ ...grpc.CallOption,
+) (*proto.ReadRangeResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestOpenRangeFailsOverFromHangingReplica(t *testing.T) {
+	defer func(timeout time.Duration) { storageCallTimeout = timeout }(storageCallTimeout)
+	storageCallTimeout = 50 * time.Millisecond
+
+	service := NewNetworkVideoContentService(testStorageNodes, WithReplicationFactor(2))
+	key := "video/manifest.mpd"
+	replicas := service.FindStorageAddrs(key)
+	data := []byte("manifest")
+	healthy := &fakeStorageRPCClient{readResponse: &proto.BatchReadResponse{Entries: []*proto.FileEntry{
+		{VideoId: "video", Filename: "manifest.mpd", Data: data, Sha256: storage.Checksum(data)},
+	}}}
+	service.dialStorageNode = func(address string) (storageRPCClient, func() error, error) {
+		if address == replicas[0] {
+			return &hangingStorageRPCClient{}, func() error { return nil }, nil
+		}
+		return healthy, func() error { return nil }, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader, _, _, err := service.OpenRange("video", "manifest.mpd", 0, -1)
+		if err != nil {
+			t.Errorf("OpenRange failed: %v", err)
+			return
+		}
+		defer reader.Close()
+		if got, _ := io.ReadAll(reader); !bytes.Equal(got, data) {
+			t.Errorf("OpenRange data = %q, want %q", got, data)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OpenRange waited on a replica that never answered")
+	}
+}
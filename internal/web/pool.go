@@ -0,0 +1,196 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+	"tritontube/internal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// storageReconnectBackoff caps reconnect delays well below gRPC's two-minute
+// default so a restarted storage node rejoins playback within seconds.
+var storageReconnectBackoff = backoff.Config{
+	BaseDelay:  100 * time.Millisecond,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   5 * time.Second,
+}
+
+var errStorageNodeUnavailable = errors.New("storage node is unavailable; reconnecting in the background")
+
+// storagePool keeps one long-lived gRPC connection per storage node. A
+// ClientConn multiplexes concurrent RPCs over a single HTTP/2 connection and
+// reconnects with backoff on its own, so segment reads, upload batches and
+// migrations all share it instead of dialing per request.
+//
+// A node that leaves the ring keeps its connection for retireAfter, so reads
+// and streams that started against the old ring can finish.
+type storagePool struct {
+	mu          sync.Mutex
+	members     []string
+	conns       map[string]*grpc.ClientConn
+	retiring    map[string]*retiredConn
+	retireAfter time.Duration
+}
+
+// retiredConn is the connection of a node that left the ring, closed by timer
+// once the pool's retireAfter has passed.
+type retiredConn struct {
+	conn  *grpc.ClientConn
+	timer *time.Timer
+}
+
+func newStoragePool(retireAfter time.Duration) *storagePool {
+	return &storagePool{
+		conns:       make(map[string]*grpc.ClientConn),
+		retiring:    make(map[string]*retiredConn),
+		retireAfter: retireAfter,
+	}
+}
+
+func newStorageConn(address string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(
+		address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           storageReconnectBackoff,
+			MinConnectTimeout: storageDialTimeout,
+		}),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(proto.MaxMessageSize),
+			grpc.MaxCallSendMsgSize(proto.MaxMessageSize),
+		),
+	)
+}
+
+// get returns the pooled connection for address, creating it if a ring member
+// has none yet or its previous connection was closed. A node that is not a
+// member, such as one being deleted from or migrated to, gets a new connection
+// that is not pooled; pooled reports which one was returned, and the caller
+// closes an unpooled connection when it is done.
+func (pool *storagePool) get(address string) (conn *grpc.ClientConn, pooled bool, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if conn, ok := pool.conns[address]; ok && conn.GetState() != connectivity.Shutdown {
+		return conn, true, nil
+	}
+	if retired, ok := pool.retiring[address]; ok && retired.conn.GetState() != connectivity.Shutdown {
+		return retired.conn, true, nil
+	}
+	conn, err = newStorageConn(address)
+	if err != nil {
+		return nil, false, err
+	}
+	if !slices.Contains(pool.members, address) {
+		return conn, false, nil
+	}
+	pool.conns[address] = conn
+	return conn, true, nil
+}
+
+// update opens connections for nodes that joined the ring and retires the
+// connections of nodes that are no longer members. A node that rejoins before
+// its connection is closed gets it back.
+func (pool *storagePool) update(addresses []string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.members = slices.Clone(addresses)
+	for address, conn := range pool.conns {
+		if !slices.Contains(addresses, address) {
+			pool.retire(address, conn)
+			delete(pool.conns, address)
+		}
+	}
+	for _, address := range addresses {
+		if _, ok := pool.conns[address]; ok {
+			continue
+		}
+		if retired, ok := pool.retiring[address]; ok {
+			delete(pool.retiring, address)
+			if retired.timer.Stop() {
+				pool.conns[address] = retired.conn
+				continue
+			}
+			// The timer already fired and is waiting for the lock.
+			closeStorageConn(address, retired.conn)
+		}
+		conn, err := newStorageConn(address)
+		if err != nil {
+			log.Printf("Create connection to storage node %s failed: %v", address, err)
+			continue
+		}
+		pool.conns[address] = conn
+	}
+}
+
+// retire closes conn once retireAfter has passed. The caller holds pool.mu.
+func (pool *storagePool) retire(address string, conn *grpc.ClientConn) {
+	if pool.retireAfter <= 0 {
+		closeStorageConn(address, conn)
+		return
+	}
+	retired := &retiredConn{conn: conn}
+	retired.timer = time.AfterFunc(pool.retireAfter, func() {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+
+		// The node rejoined or the pool was closed in the meantime.
+		if pool.retiring[address] != retired {
+			return
+		}
+		delete(pool.retiring, address)
+		closeStorageConn(address, conn)
+	})
+	pool.retiring[address] = retired
+}
+
+func closeStorageConn(address string, conn *grpc.ClientConn) {
+	if err := conn.Close(); err != nil {
+		log.Printf("Close connection to storage node %s failed: %v", address, err)
+	}
+}
+
+// close closes every connection, including retired ones still waiting out
+// their delay.
+func (pool *storagePool) close() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var errs []error
+	for address, conn := range pool.conns {
+		errs = append(errs, conn.Close())
+		delete(pool.conns, address)
+	}
+	for address, retired := range pool.retiring {
+		retired.timer.Stop()
+		errs = append(errs, retired.conn.Close())
+		delete(pool.retiring, address)
+	}
+	return errors.Join(errs...)
+}
+
+// waitForReady starts connecting if the connection is idle and waits until it
+// is ready. A connection in transient failure fails immediately so callers
+// can move on to another replica while gRPC retries in the background.
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return errStorageNodeUnavailable
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tritontube/internal/proto"
+	"tritontube/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func startStorageNode(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	proto.RegisterVideoContentStorageServiceServer(grpcServer, storage.NewStorageServer(t.TempDir()))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestStoragePoolReusesOneConnectionPerNode(t *testing.T) {
+	address := startStorageNode(t)
+	service := NewNetworkVideoContentService([]string{address})
+	t.Cleanup(func() { service.Close() })
+
+	pooled, _, err := service.pool.get(address)
+	if err != nil {
+		t.Fatalf("pool.get failed: %v", err)
+	}
+
+	if err := service.Write("video", "manifest.mpd", []byte("manifest")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for range 5 {
+		data, err := service.Read("video", "manifest.mpd")
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(data) != "manifest" {
+			t.Fatalf("Read data = %q, want manifest", data)
+		}
+	}
+
+	current, _, err := service.pool.get(address)
+	if err != nil {
+		t.Fatalf("pool.get failed: %v", err)
+	}
+	if current != pooled {
+		t.Fatal("Read and Write replaced the pooled connection")
+	}
+	if len(service.pool.conns) != 1 {
+		t.Fatalf("pool holds %d connections, want 1", len(service.pool.conns))
+	}
+}
+
+func TestStoragePoolClosesConnectionsOfRemovedNodesAfterGracePeriod(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	gracePeriod := 200 * time.Millisecond
+	service := NewNetworkVideoContentService([]string{first, second}, WithCleanupGracePeriod(gracePeriod))
+	t.Cleanup(func() { service.Close() })
+
+	conn, _, err := service.pool.get(second)
+	if err != nil {
+		t.Fatalf("pool.get failed: %v", err)
+	}
+
+	if _, err := service.RemoveNode(t.Context(), &proto.RemoveNodeRequest{NodeAddress: second}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if _, ok := service.pool.conns[second]; ok {
+		t.Fatal("pool still holds the removed node")
+	}
+	if _, ok := service.pool.conns[first]; !ok {
+		t.Fatal("pool dropped the remaining node")
+	}
+	// Reads that started against the old ring keep the connection.
+	client := proto.NewVideoContentStorageServiceClient(conn)
+	if _, err := client.ListFilesPage(t.Context(), &proto.ListFilesRequest{}); err != nil {
+		t.Fatalf("removed node connection failed within the grace period: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.GetState() != connectivity.Shutdown {
+		if time.Now().After(deadline) {
+			t.Fatalf("removed node connection state = %v after the grace period, want Shutdown", conn.GetState())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStoragePoolDoesNotKeepConnectionsOutsideTheRing(t *testing.T) {
+	member := startStorageNode(t)
+	outside := startStorageNode(t)
+	service := NewNetworkVideoContentService([]string{member})
+	t.Cleanup(func() { service.Close() })
+
+	client, closeNode, err := service.dialNode(t.Context(), outside)
+	if err != nil {
+		t.Fatalf("dialNode failed: %v", err)
+	}
+	if _, err := client.ListFilesPage(t.Context(), &proto.ListFilesRequest{}); err != nil {
+		t.Fatalf("ListFilesPage failed: %v", err)
+	}
+	if err := closeNode(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, ok := service.pool.conns[outside]; ok {
+		t.Fatal("pool holds a connection to a node outside the ring")
+	}
+}
+
+func TestStoragePoolCloseShutsRetiredConnections(t *testing.T) {
+	first := startStorageNode(t)
+	second := startStorageNode(t)
+	service := NewNetworkVideoContentService([]string{first, second})
+
+	conn, _, err := service.pool.get(second)
+	if err != nil {
+		t.Fatalf("pool.get failed: %v", err)
+	}
+	service.pool.update([]string{first})
+	if state := conn.GetState(); state == connectivity.Shutdown {
+		t.Fatal("removed node connection closed before the grace period")
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if state := conn.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("retired connection state = %v after Close, want Shutdown", state)
+	}
+}
+
+func TestReadFailsFastForUnreachableReplica(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	unreachable := listener.Addr().String()
+	listener.Close()
+
+	service := NewNetworkVideoContentService([]string{unreachable})
+	t.Cleanup(func() { service.Close() })
+
+	if _, err := service.Read("video", "manifest.mpd"); err == nil {
+		t.Fatal("Read expected an error for an unreachable node")
+	}
+}
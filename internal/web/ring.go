@@ -0,0 +1,201 @@
+package web
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// WithVirtualNodes places the given number of tokens on the ring for every
+// storage node. More tokens spread each node's key space across many small
+// ranges, which evens out load. Values below one are treated as one.
+func WithVirtualNodes(count int) NetworkOption {
+	return func(ns *NetworkVideoContentService) {
+		ns.virtualNodes = max(count, 1)
+	}
+}
+
+// virtualNodeID returns the ring position of a node's index-th token. The
+// first token is the plain address hash so single-token rings keep the
+// placement they had before virtual nodes existed.
+func virtualNodeID(address string, index int) uint64 {
+	if index == 0 {
+		return HashStringToUint64(address)
+	}
+	return HashStringToUint64(fmt.Sprintf("%s#%d", address, index))
+}
+
+// maxNodeWeight bounds the ring size a single reweight can create.
+const maxNodeWeight = 1024
+
+// nodeWeight returns a node's weight, defaulting to one for nodes without an
+// explicit weight.
+func nodeWeight(weights map[string]uint32, address string) int {
+	if weight := weights[address]; weight > 0 {
+		return int(weight)
+	}
+	return 1
+}
+
+// buildRing returns the sorted token list and token owners for the given
+// physical nodes. Each node receives virtualNodes tokens per unit of weight,
+// so a heavier node owns proportionally more key space. Token positions only
+// depend on the address and token index, which means changing a weight adds or
+// removes tokens without moving the node's other ranges. Duplicate addresses
+// are placed once.
+func buildRing(addresses []string, weights map[string]uint32, virtualNodes int) ([]uint64, map[uint64]string) {
+	storageIDs := make([]uint64, 0, len(addresses)*virtualNodes)
+	storageServers := make(map[uint64]string, len(addresses)*virtualNodes)
+	for _, address := range addresses {
+		tokens := virtualNodes * nodeWeight(weights, address)
+		for index := 0; index < tokens; index++ {
+			id := virtualNodeID(address, index)
+			if _, exists := storageServers[id]; exists {
+				continue
+			}
+			storageIDs = append(storageIDs, id)
+			storageServers[id] = address
+		}
+	}
+
+	slices.Sort(storageIDs)
+	return storageIDs, storageServers
+}
+
+// physicalNodes returns each storage address once, in the ring order of its
+// first token.
+func physicalNodes(storageIDs []uint64, storageServers map[uint64]string) []string {
+	nodes := make([]string, 0, len(storageIDs))
+	for _, id := range storageIDs {
+		if address := storageServers[id]; !slices.Contains(nodes, address) {
+			nodes = append(nodes, address)
+		}
+	}
+	return nodes
+}
+
+// rangeMove describes one arc of the ring whose replica set changes. Keys
+// with a hash in (start, end] move; the arc wraps past zero when start >= end.
+type rangeMove struct {
+	start   uint64
+	end     uint64
+	sources []string
+	targets []string
+}
+
+// id names the arc in migration checkpoints.
+func (move rangeMove) id() string {
+	return fmt.Sprintf("%016x-%016x", move.start, move.end)
+}
+
+func (move rangeMove) contains(hash uint64) bool {
+	if move.start < move.end {
+		return move.start < hash && hash <= move.end
+	}
+	return hash > move.start || hash <= move.end
+}
+
+// planRangeMoves compares two rings and returns every arc whose replica set
+// gains a node. Token positions from both rings split the key space into arcs
+// that have a single replica set before and after the change, so each arc is
+// copied from one of its current replicas to the nodes that join it.
+func planRangeMoves(
+	currentIDs []uint64,
+	currentServers map[uint64]string,
+	proposedIDs []uint64,
+	proposedServers map[uint64]string,
+	replicas int,
+) []rangeMove {
+	moves := make([]rangeMove, 0)
+	forEachChangedArc(currentIDs, currentServers, proposedIDs, proposedServers, replicas, func(start, end uint64, before, after []string) {
+		targets := withoutNodes(after, before)
+		if len(targets) == 0 {
+			return
+		}
+		moves = append(moves, rangeMove{start: start, end: end, sources: before, targets: targets})
+	})
+	return moves
+}
+
+// rangeDrop is an arc of the ring that nodes no longer hold a replica of once
+// a ring change is published.
+type rangeDrop struct {
+	start uint64
+	end   uint64
+	nodes []string
+}
+
+// planRangeDrops compares two rings and returns every arc whose replica set
+// loses a node, which is where a ring change leaves copies behind.
+func planRangeDrops(
+	currentIDs []uint64,
+	currentServers map[uint64]string,
+	proposedIDs []uint64,
+	proposedServers map[uint64]string,
+	replicas int,
+) []rangeDrop {
+	drops := make([]rangeDrop, 0)
+	forEachChangedArc(currentIDs, currentServers, proposedIDs, proposedServers, replicas, func(start, end uint64, before, after []string) {
+		if nodes := withoutNodes(before, after); len(nodes) > 0 {
+			drops = append(drops, rangeDrop{start: start, end: end, nodes: nodes})
+		}
+	})
+	return drops
+}
+
+// forEachChangedArc splits the key space at the token positions of both rings
+// and calls visit with each arc (start, end] and its replica sets before and
+// after the change.
+func forEachChangedArc(
+	currentIDs []uint64,
+	currentServers map[uint64]string,
+	proposedIDs []uint64,
+	proposedServers map[uint64]string,
+	replicas int,
+	visit func(start, end uint64, before, after []string),
+) {
+	if len(currentIDs) == 0 || len(proposedIDs) == 0 {
+		return
+	}
+
+	boundaries := append(append([]uint64(nil), currentIDs...), proposedIDs...)
+	slices.Sort(boundaries)
+	boundaries = slices.Compact(boundaries)
+
+	for index, end := range boundaries {
+		start := boundaries[(index+len(boundaries)-1)%len(boundaries)]
+		before := replicasForHash(end, currentIDs, currentServers, replicas)
+		after := replicasForHash(end, proposedIDs, proposedServers, replicas)
+		visit(start, end, before, after)
+	}
+}
+
+// withoutNodes returns the addresses in nodes that are not in excluded.
+func withoutNodes(nodes, excluded []string) []string {
+	result := make([]string, 0, len(nodes))
+	for _, address := range nodes {
+		if !slices.Contains(excluded, address) {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// replicasForHash walks the ring clockwise from a hash and returns up to count
+// distinct storage addresses, starting with the primary owner.
+func replicasForHash(objectID uint64, storageIDs []uint64, storageServers map[uint64]string, count int) []string {
+	if len(storageIDs) == 0 || count <= 0 {
+		return nil
+	}
+
+	first := sort.Search(len(storageIDs), func(i int) bool { return storageIDs[i] >= objectID })
+
+	addresses := make([]string, 0, count)
+	for offset := 0; offset < len(storageIDs) && len(addresses) < count; offset++ {
+		address := storageServers[storageIDs[(first+offset)%len(storageIDs)]]
+		if !slices.Contains(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -10,12 +11,15 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"tritontube/internal/storage"
+	"unicode/utf8"
 )
 
 const (
@@ -30,14 +34,56 @@ type server struct {
 	metadataService VideoMetadataService
 	contentService  VideoContentService
 
+	// renditions is the adaptive bitrate ladder used for uploads.
+	renditions []Rendition
+	probeVideo func(string) (sourceVideo, error)
+	runFFmpeg  func([]string) ([]byte, error)
+	newVideoID func() string
+
+	// Uploads are transcoded by transcodeWorkers goroutines reading jobs.
+	// Jobs are recorded as owned by instance.
+	instance         string
+	transcodeWorkers int
+	jobs             chan transcodeTask
+	workers          sync.WaitGroup
+	stopping         atomic.Bool
+
 	mux        *http.ServeMux
 	httpServer *http.Server
 }
 
+// ServerOption configures the web server at construction time.
+type ServerOption func(*server)
+
+// WithRenditionLadder encodes uploads at the given renditions instead of
+// DefaultRenditionLadder. Rungs taller than an upload's source are skipped.
+func WithRenditionLadder(ladder []Rendition) ServerOption {
+	return func(s *server) {
+		if len(ladder) > 0 {
+			s.renditions = slices.Clone(ladder)
+		}
+	}
+}
+
 type VideoData struct {
 	Id         string
-	EscapedId  string
+	PageURL    string
+	Title      string
+	Duration   string
+	Tags       []string
 	UploadTime string
+	// PosterURL and ThumbnailsURL are empty for videos without images.
+	PosterURL     string
+	ThumbnailsURL string
+	Thumbnails    *ThumbnailSprite
+}
+
+// contentURL returns the /content/ path of a file stored with a video.
+func contentURL(videoId, filename string) string {
+	if filename == "" {
+		return ""
+	}
+	return "/content/" + url.PathEscape(videoId) + "/" + url.PathEscape(filename)
 }
 
 func durationMilliseconds(duration time.Duration) float64 {
@@ -47,14 +93,26 @@ func durationMilliseconds(duration time.Duration) float64 {
 func NewServer(
 	metadataService VideoMetadataService,
 	contentService VideoContentService,
+	options ...ServerOption,
 ) *server {
 	mux := http.NewServeMux()
 	s := &server{
-		metadataService: metadataService,
-		contentService:  contentService,
-		mux:             mux,
-	}
+		metadataService:  metadataService,
+		contentService:   contentService,
+		renditions:       DefaultRenditionLadder,
+		probeVideo:       probeVideo,
+		runFFmpeg:        runFFmpeg,
+		newVideoID:       newVideoID,
+		instance:         defaultInstanceName(),
+		transcodeWorkers: defaultTranscodeWorkers,
+		mux:              mux,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.startTranscodeWorkers()
 	mux.HandleFunc("/upload", s.handleUpload)
+	mux.HandleFunc("/jobs/", s.handleJob)
 	mux.HandleFunc("/videos/", s.handleVideo)
 	mux.HandleFunc("/content/", s.handleVideoContent)
 	mux.HandleFunc("/", s.handleIndex)
@@ -68,8 +126,26 @@ func (s *server) Start(lis net.Listener) error {
 	return s.httpServer.Serve(lis)
 }
 
+// Shutdown stops accepting requests and waits for running transcode jobs.
+// Jobs still queued are marked failed instead of being started.
 func (s *server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+
+	s.stopping.Store(true)
+	close(s.jobs)
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
 }
 
 func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -81,12 +157,23 @@ func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	var videoList []VideoData
 	for _, video := range videos {
-		escapedId := url.PathEscape(video.Id)
-		videoList = append(videoList, VideoData{
+		if video.Hidden() {
+			continue
+		}
+		data := VideoData{
 			Id:         video.Id,
-			EscapedId:  escapedId,
+			PageURL:    video.PageURL(),
+			Title:      video.DisplayTitle(),
+			Duration:   formatDuration(video.Duration),
+			Tags:       video.Tags,
 			UploadTime: video.UploadedAt.Format("2006-01-02 15:04:05"),
-		})
+			PosterURL:  contentURL(video.Id, video.Poster),
+			Thumbnails: video.Thumbnails,
+		}
+		if video.Thumbnails != nil {
+			data.ThumbnailsURL = contentURL(video.Id, video.Thumbnails.Filename)
+		}
+		videoList = append(videoList, data)
 	}
 
 	tmpl, err := template.New("index").Parse(indexHTML)
@@ -105,11 +192,17 @@ func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleUpload reserves a generated video ID for the uploaded file, saves it
+// and queues it for transcoding. The filename is only kept as metadata and
+// for the page slug. The optional title, description, tags and uploader form fields are stored with
+// the video's metadata. It answers as soon as the job is queued; clients
+// follow progress at /jobs/{id}. JSON clients get 202 with the job, browsers are redirected to
+// the index page, which polls the job.
 func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	uploadStart := time.Now()
 	videoId := "unknown"
 	defer func() {
-		log.Printf("Upload total time: video=%s duration=%.3f ms", videoId, durationMilliseconds(time.Since(uploadStart)))
+		log.Printf("Upload accept time: video=%s duration=%.3f ms", videoId, durationMilliseconds(time.Since(uploadStart)))
 	}()
 
 	if r.Method != http.MethodPost {
@@ -124,131 +217,105 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	videoId = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+	filename := header.Filename
+	if utf8.RuneCountInString(filename) > maxFilenameLength {
+		http.Error(w, fmt.Sprintf("filename is longer than %d characters", maxFilenameLength), http.StatusBadRequest)
+		return
+	}
 
-	start := time.Now()
-	existingVideo, err := s.metadataService.Read(videoId)
-	if err != nil {
-		http.Error(w, "Error checking video ID availability", http.StatusInternalServerError)
+	details := VideoMetadata{OriginalFilename: filename}
+	if err := applyVideoDetails(r.PostForm, &details); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if existingVideo != nil {
-		http.Error(w, "Video ID already exists: "+videoId, http.StatusConflict)
+	details.Uploader, _, err = formText(r.PostForm, "uploader", maxUploaderLength)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	totalCheckTime := time.Since(start)
-	log.Printf("Metadata duplicate check time: %.3f ms", durationMilliseconds(totalCheckTime))
 
-	uploadDir := filepath.Join(os.TempDir(), "videos")
+	details.Slug = videoSlug(details)
 
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		http.Error(w, "Unable to create upload directory", http.StatusInternalServerError)
+	start := time.Now()
+	videoId, err = s.reserveVideo(details)
+	if err != nil {
+		log.Printf("Reserve video ID for %s failed: %v", filename, err)
+		http.Error(w, "Error reserving video ID", http.StatusInternalServerError)
 		return
 	}
-
-	videoPath := filepath.Join(uploadDir, header.Filename)
-	dest, err := os.Create(videoPath)
-	if err != nil {
-		http.Error(w, "Unable to save file", http.StatusInternalServerError)
+	details.Id = videoId
+	totalCheckTime := time.Since(start)
+	log.Printf("Video ID reservation time: %.3f ms", durationMilliseconds(totalCheckTime))
+
+	now := time.Now()
+	task := transcodeTask{
+		job: TranscodeJob{
+			Id:        newJobID(),
+			VideoId:   videoId,
+			Filename:  filename,
+			Owner:     s.instance,
+			State:     JobQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		metadata: details,
+	}
+	// Every job works in its own directory, so uploads never share files.
+	task.workDir = jobWorkDir(task.job.Id)
+	// The uploaded name only keeps its extension, which ffprobe may use.
+	task.videoPath = filepath.Join(task.workDir, "source"+filepath.Ext(filename))
+	discard := func() {
+		os.RemoveAll(task.workDir)
+		s.releaseVideo(videoId)
+	}
+
+	if err := os.MkdirAll(task.workDir, os.ModePerm); err != nil {
+		discard()
+		http.Error(w, "Unable to create upload directory", http.StatusInternalServerError)
 		return
 	}
-	defer dest.Close()
 
 	start = time.Now()
-	_, err = io.Copy(dest, file)
+	task.metadata.Size, err = saveUpload(task.videoPath, file)
 	if err != nil {
+		discard()
 		http.Error(w, "Error saving file", http.StatusInternalServerError)
 		return
 	}
 	totalCopyTime := time.Since(start)
 	log.Printf("MP4 file copy time: %.3f ms", durationMilliseconds(totalCopyTime))
 
-	dashDir := filepath.Join(uploadDir, videoId)
-
-	if err := os.MkdirAll(dashDir, os.ModePerm); err != nil {
-		http.Error(w, "Unable to create DASH directory", http.StatusInternalServerError)
+	if err := s.enqueueTranscode(task); errors.Is(err, errTranscodeQueueFull) {
+		http.Error(w, "Too many uploads are being processed; try again later", http.StatusServiceUnavailable)
 		return
-	}
-
-	manifestPath := filepath.Join(dashDir, "manifest.mpd")
-
-	start = time.Now()
-	cmd := exec.Command("ffmpeg",
-		"-i", videoPath, // input file
-		"-c:v", "libx264", // video codec
-		"-preset", "veryfast", // faster software encoding
-		"-threads", "2", // limit CPU usage on local machines
-		"-c:a", "aac", // audio codec
-		"-bf", "1", // max 1 B-frame
-		"-keyint_min", "120", // minimum keyframe interval
-		"-g", "120", // keyframe every 120 frames
-		"-sc_threshold", "0", // scene change threshold
-		"-b:v", "3000k", // video bitrate
-		"-b:a", "128k", // audio bitrate
-		"-f", "dash", // DASH format
-		"-use_timeline", "1", // use timeline
-		"-use_template", "1", // use template
-		"-init_seg_name", "init-$RepresentationID$.m4s", // init segment naming
-		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s", // media segment naming
-		"-seg_duration", "4", // segment duration in seconds
-		manifestPath, // output manifest file path
-	)
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		http.Error(w, "Error generating DASH content: "+err.Error()+"\n"+string(output), http.StatusInternalServerError)
+	} else if err != nil {
+		discard()
+		http.Error(w, "Error saving job: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	totalFFmpegTime := time.Since(start)
-	log.Printf("FFmpeg transcoding time: %.3f ms", durationMilliseconds(totalFFmpegTime))
+	log.Printf("Queued transcode job %s for %s as video %s", task.job.Id, filename, videoId)
 
-	start = time.Now()
-	entries, err := os.ReadDir(dashDir)
-	if err != nil {
-		http.Error(w, "Error reading DASH directory: "+err.Error(), http.StatusInternalServerError)
+	statusURL := "/jobs/" + url.PathEscape(task.job.Id)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Location", statusURL)
+		writeJSON(w, http.StatusAccepted, task.job)
 		return
 	}
-	totalScanTime := time.Since(start)
-	log.Printf("DASH files scan time: %.3f ms", durationMilliseconds(totalScanTime))
-
-	start = time.Now()
-	fileCount, expectedCount, uploadErr := s.storeDASHFiles(videoId, dashDir, entries)
+	http.Redirect(w, r, "/?job="+url.QueryEscape(task.job.Id), http.StatusSeeOther)
+}
 
-	if uploadErr != nil {
-		log.Printf("DASH upload failed after storing %d/%d files: %v", fileCount, expectedCount, uploadErr)
-		http.Error(w, "Failed to store DASH files", http.StatusInternalServerError)
-		return
-	}
-	if expectedCount == 0 {
-		http.Error(w, "No DASH files were generated", http.StatusInternalServerError)
-		return
-	}
-	if fileCount != expectedCount {
-		log.Printf("Only %d/%d files were written to storage", fileCount, expectedCount)
-		http.Error(w, "Failed to store all DASH files", http.StatusInternalServerError)
-		return
+// saveUpload writes the uploaded file to path and returns its size.
+func saveUpload(path string, file io.Reader) (int64, error) {
+	dest, err := os.Create(path)
+	if err != nil {
+		return 0, err
 	}
-
-	totalWriteTime := time.Since(start)
-	log.Printf(
-		"DASH storage time: video=%s files=%d duration=%.3f ms",
-		videoId,
-		fileCount,
-		durationMilliseconds(totalWriteTime),
-	)
-
-	start = time.Now()
-	err = s.metadataService.Create(videoId, time.Now())
+	size, err := io.Copy(dest, file)
 	if err != nil {
-		http.Error(w, "Error saving metadata: "+err.Error(), http.StatusInternalServerError)
-		return
+		dest.Close()
+		return 0, err
 	}
-	totalMetadataTime := time.Since(start)
-	log.Printf("Metadata create time: %.3f ms", durationMilliseconds(totalMetadataTime))
-
-	log.Printf("File successfully uploaded: %s\n", header.Filename)
-	log.Printf("DASH content generated at: %s\n", manifestPath)
-
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return size, dest.Close()
 }
 
 // storeDASHFiles uses a fixed-size worker pool. Each job reads a small group of
@@ -330,21 +397,52 @@ func (s *server) storeDASHFiles(videoID, dashDir string, entries []os.DirEntry)
 }
 
 func (s *server) handleVideo(w http.ResponseWriter, r *http.Request) {
-	videoId := r.URL.Path[len("/videos/"):]
+	// Pages are at /videos/{id} or /videos/{id}/{slug}; the slug is only
+	// for people reading the URL.
+	videoId, slug, hasSlug := strings.Cut(r.URL.Path[len("/videos/"):], "/")
 	log.Println("Video ID:", videoId)
 
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		s.handleVideoEdit(w, r, videoId)
+		return
+	case http.MethodDelete:
+		s.handleVideoDelete(w, videoId)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	metadata, err := s.metadataService.Read(videoId)
-	if err != nil || metadata == nil {
+	if err != nil || metadata == nil || metadata.Hidden() {
 		http.Error(w, "Video not found: "+videoId, http.StatusNotFound)
 		return
 	}
+	// The slug changes whenever the title is edited, so the redirect must not
+	// be cached the way a 301 would be.
+	if hasSlug && slug != metadata.Slug {
+		http.Redirect(w, r, metadata.PageURL(), http.StatusFound)
+		return
+	}
 
 	data := struct {
-		Id         string
+		VideoMetadata
+		Title      string
 		UploadedAt string
+		Duration   string
+		Size       string
+		Status     VideoStatus
+		PosterURL  string
 	}{
-		Id:         metadata.Id,
-		UploadedAt: metadata.UploadedAt.Format("2006-01-02 15:04:05"),
+		VideoMetadata: *metadata,
+		Title:         metadata.DisplayTitle(),
+		UploadedAt:    metadata.UploadedAt.Format("2006-01-02 15:04:05"),
+		Duration:      formatDuration(metadata.Duration),
+		Size:          formatSize(metadata.Size),
+		Status:        metadata.DisplayStatus(),
+		PosterURL:     contentURL(metadata.Id, metadata.Poster),
 	}
 
 	tmpl, err := template.New("video").Parse(videoHTML)
@@ -385,35 +483,87 @@ func (s *server) handleVideoContent(w http.ResponseWriter, r *http.Request) {
 	filename = parts[1]
 	log.Println("Video ID:", videoId, "Filename:", filename)
 
-	content, err := s.contentService.Read(videoId, filename)
-
-	if err != nil || content == nil || len(content) == 0 {
+	// Ranges are honoured for single-range requests. Multi-range requests are
+	// refused and malformed ones get the whole file, which RFC 9110 allows.
+	rangeHeader := r.Header.Get("Range")
+	requested, ranged := parseByteRange(rangeHeader)
+	offset, length := int64(0), int64(-1)
+	switch {
+	case multipleRanges(rangeHeader):
+		offset, length = 0, 0
+	case ranged:
+		offset, length = requested.storageRange()
+	}
+
+	content, size, checksum, err := s.contentService.OpenRange(videoId, filename, offset, length)
+	if err == nil && ranged && !ifRangeMatches(r.Header.Get("If-Range"), contentETag(checksum)) {
+		// The client's copy is stale, so it gets the current file in full.
+		content.Close()
+		ranged, offset, length = false, 0, -1
+		content, size, checksum, err = s.contentService.OpenRange(videoId, filename, offset, length)
+	}
+	if err != nil || (!ranged && length != 0 && size == 0) {
+		if content != nil {
+			content.Close()
+		}
 		log.Println("Video content not Found: " + filename)
 		http.Error(w, "Video content not Found", http.StatusInternalServerError)
 		return
 	}
+	defer content.Close()
 
+	if etag := contentETag(checksum); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if multipleRanges(rangeHeader) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "Multiple ranges are not supported", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	first, count := storage.ResolveRange(offset, length, size)
+	if ranged && count == 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "Requested range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	setContentHeaders(w, filename)
+	w.Header().Set("Content-Length", strconv.FormatInt(count, 10))
+	if ranged {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, first+count-1, size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
+	// Content is copied as it arrives from storage, so large files are never
+	// held in memory in full.
+	start := time.Now()
+	if _, err := io.CopyN(w, content, count); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+	httpTime := time.Since(start)
+	log.Printf("HTTP write time: %.3f ms", durationMilliseconds(httpTime))
+}
+
+func setContentHeaders(w http.ResponseWriter, filename string) {
 	var contentType string
 	switch {
 	case strings.HasSuffix(filename, ".mpd"):
 		contentType = "application/dash+xml"
+	case strings.HasSuffix(filename, ".m3u8"):
+		contentType = "application/vnd.apple.mpegurl"
 	case strings.HasSuffix(filename, ".m4s"), strings.HasSuffix(filename, ".mp4"):
 		contentType = "video/mp4"
+	case strings.HasSuffix(filename, ".jpg"):
+		contentType = "image/jpeg"
 	default:
 		contentType = "application/octet-stream"
 	}
 
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Range")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, ETag")
 	w.Header().Set("Accept-Ranges", "bytes")
-
-	start := time.Now()
-	if _, err := w.Write(content); err != nil {
-		log.Printf("Error writing response: %v", err)
-	}
-	httpTime := time.Since(start)
-	log.Printf("HTTP write time: %.3f ms", durationMilliseconds(httpTime))
 }
@@ -1,21 +1,45 @@
 package web
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"tritontube/internal/storage"
 )
 
 type recordingContentService struct {
 	mu         sync.Mutex
 	files      map[string][]byte
 	batchSizes []int
+	deleteErr  error
 }
 
-func (service *recordingContentService) Read(string, string) ([]byte, error) {
-	return nil, nil
+func (service *recordingContentService) Read(videoID, filename string) ([]byte, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return service.files[videoID+"/"+filename], nil
+}
+
+func (service *recordingContentService) OpenRange(videoID, filename string, offset, length int64) (io.ReadCloser, int64, []byte, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	data, ok := service.files[videoID+"/"+filename]
+	if !ok {
+		return nil, 0, nil, fmt.Errorf("file not found: %s/%s", videoID, filename)
+	}
+	size := int64(len(data))
+	first, count := storage.ResolveRange(offset, length, size)
+	return io.NopCloser(bytes.NewReader(data[first : first+count])), size, storage.Checksum(data), nil
 }
 
 func (service *recordingContentService) Write(videoID, filename string, data []byte) error {
@@ -33,6 +57,22 @@ func (service *recordingContentService) WriteBatch(files []ContentFile) (int, er
 	return len(files), nil
 }
 
+func (service *recordingContentService) Delete(videoID string) (int, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.deleteErr != nil {
+		return 0, service.deleteErr
+	}
+	count := 0
+	for key := range service.files {
+		if strings.HasPrefix(key, videoID+"/") {
+			delete(service.files, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
 func TestStoreDASHFilesUsesBoundedBatches(t *testing.T) {
 	dashDir := t.TempDir()
 	const fileCount = uploadWorkerLimit*uploadBatchSize + 7
@@ -65,3 +105,299 @@ func TestStoreDASHFilesUsesBoundedBatches(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleVideoContentRanges(t *testing.T) {
+	content := &recordingContentService{files: map[string][]byte{
+		"video/chunk.m4s": []byte("0123456789"),
+	}}
+	server := &server{contentService: content}
+	etag := contentETag(storage.Checksum([]byte("0123456789")))
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		ifRange      string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{name: "no range", status: http.StatusOK, body: "0123456789"},
+		{name: "bounded", rangeHeader: "bytes=2-4", status: http.StatusPartialContent, body: "234", contentRange: "bytes 2-4/10"},
+		{name: "open ended", rangeHeader: "bytes=7-", status: http.StatusPartialContent, body: "789", contentRange: "bytes 7-9/10"},
+		{name: "end past size", rangeHeader: "bytes=8-20", status: http.StatusPartialContent, body: "89", contentRange: "bytes 8-9/10"},
+		{name: "suffix", rangeHeader: "bytes=-3", status: http.StatusPartialContent, body: "789", contentRange: "bytes 7-9/10"},
+		{name: "suffix longer than file", rangeHeader: "bytes=-30", status: http.StatusPartialContent, body: "0123456789", contentRange: "bytes 0-9/10"},
+		{name: "start past size", rangeHeader: "bytes=10-", status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{name: "empty suffix", rangeHeader: "bytes=-0", status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{name: "multiple ranges", rangeHeader: "bytes=0-1,4-5", status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */10"},
+		{name: "malformed", rangeHeader: "bytes=5-2", status: http.StatusOK, body: "0123456789"},
+		{name: "other unit", rangeHeader: "items=0-1", status: http.StatusOK, body: "0123456789"},
+		{name: "if-range current", rangeHeader: "bytes=0-1", ifRange: etag, status: http.StatusPartialContent, body: "01", contentRange: "bytes 0-1/10"},
+		{name: "if-range stale", rangeHeader: "bytes=0-1", ifRange: `"etag"`, status: http.StatusOK, body: "0123456789"},
+		{name: "if-range weak", rangeHeader: "bytes=0-1", ifRange: "W/" + etag, status: http.StatusOK, body: "0123456789"},
+		{name: "if-range date", rangeHeader: "bytes=0-1", ifRange: "Wed, 21 Oct 2015 07:28:00 GMT", status: http.StatusOK, body: "0123456789"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/content/video/chunk.m4s", nil)
+			if test.rangeHeader != "" {
+				request.Header.Set("Range", test.rangeHeader)
+			}
+			if test.ifRange != "" {
+				request.Header.Set("If-Range", test.ifRange)
+			}
+			recorder := httptest.NewRecorder()
+
+			server.handleVideoContent(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.status)
+			}
+			if got := recorder.Header().Get("Content-Range"); got != test.contentRange {
+				t.Fatalf("Content-Range = %q, want %q", got, test.contentRange)
+			}
+			if got := recorder.Header().Get("ETag"); got != etag {
+				t.Fatalf("ETag = %q, want %q", got, etag)
+			}
+			if test.status == http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+			if got := recorder.Body.String(); got != test.body {
+				t.Fatalf("body = %q, want %q", got, test.body)
+			}
+			if got := recorder.Header().Get("Content-Length"); got != fmt.Sprint(len(test.body)) {
+				t.Fatalf("Content-Length = %q, want %d", got, len(test.body))
+			}
+		})
+	}
+}
+
+func TestHandleVideoContentTypes(t *testing.T) {
+	content := &recordingContentService{files: map[string][]byte{
+		"video/manifest.mpd":   []byte("<MPD/>"),
+		"video/master.m3u8":    []byte("#EXTM3U"),
+		"video/media_0.m3u8":   []byte("#EXTM3U"),
+		"video/init-0.m4s":     []byte("init"),
+		"video/chunk-0-1.m4s":  []byte("chunk"),
+		"video/thumbnail.data": []byte("data"),
+	}}
+	server := &server{contentService: content}
+
+	tests := map[string]string{
+		"manifest.mpd":   "application/dash+xml",
+		"master.m3u8":    "application/vnd.apple.mpegurl",
+		"media_0.m3u8":   "application/vnd.apple.mpegurl",
+		"init-0.m4s":     "video/mp4",
+		"chunk-0-1.m4s":  "video/mp4",
+		"thumbnail.data": "application/octet-stream",
+	}
+	for filename, want := range tests {
+		recorder := httptest.NewRecorder()
+		server.handleVideoContent(recorder, httptest.NewRequest(http.MethodGet, "/content/video/"+filename, nil))
+		if got := recorder.Header().Get("Content-Type"); got != want {
+			t.Fatalf("Content-Type for %s = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func TestIndexRendersPostersAndThumbnails(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{
+		Id:     "with images",
+		Poster: posterFilename,
+		Thumbnails: &ThumbnailSprite{
+			Filename: thumbnailSpriteFilename, Interval: 10, Count: 12, Columns: 10, Width: 160, Height: 90,
+		},
+	})
+	metadata.Create(VideoMetadata{Id: "plain"})
+	server := &server{metadataService: metadata}
+
+	recorder := httptest.NewRecorder()
+	server.handleIndex(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := recorder.Body.String()
+
+	for _, want := range []string{
+		`src="/content/with%20images/poster.jpg"`,
+		`data-sprite="/content/with%20images/thumbnails.jpg"`,
+		`data-count="12"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("index page does not contain %s:\n%s", want, body)
+		}
+	}
+	if strings.Count(body, "<img") != 1 {
+		t.Fatalf("index page renders %d images, want 1", strings.Count(body, "<img"))
+	}
+}
+
+func TestVideoMetadataDecodesLegacyRecords(t *testing.T) {
+	var metadata VideoMetadata
+	legacy := `{"video_id":"clip","uploaded_at":"2025-03-01T10:00:00Z"}`
+	if err := json.Unmarshal([]byte(legacy), &metadata); err != nil {
+		t.Fatalf("decode legacy record: %v", err)
+	}
+	if metadata.Id != "clip" || metadata.UploadedAt.IsZero() {
+		t.Fatalf("metadata = %+v", metadata)
+	}
+	if metadata.DisplayTitle() != "clip" || metadata.DisplayStatus() != VideoReady {
+		t.Fatalf("legacy record displays as %q, %q", metadata.DisplayTitle(), metadata.DisplayStatus())
+	}
+
+	encoded, err := json.Marshal(VideoMetadata{Id: "clip"})
+	if err != nil {
+		t.Fatalf("encode metadata: %v", err)
+	}
+	if string(encoded) != `{"video_id":"clip","uploaded_at":"0001-01-01T00:00:00Z"}` {
+		t.Fatalf("metadata without details encodes as %s", encoded)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags(" go, Distributed Systems ,go,, GO ")
+	if err != nil {
+		t.Fatalf("parseTags failed: %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"go", "Distributed Systems"}) {
+		t.Fatalf("tags = %q", tags)
+	}
+
+	tooMany := make([]string, maxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	if _, err := parseTags(strings.Join(tooMany, ",")); err == nil {
+		t.Fatalf("parseTags accepted %d tags", maxTags+1)
+	}
+	if _, err := parseTags(strings.Repeat("x", maxTagLength+1)); err == nil {
+		t.Fatal("parseTags accepted a tag above the length limit")
+	}
+}
+
+func TestHandleVideoEditUpdatesDetails(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{Id: "clip", Title: "Old", Description: "kept", Uploader: "ana", Width: 1280})
+	s := &server{metadataService: metadata}
+
+	request := httptest.NewRequest(http.MethodPost, "/videos/clip",
+		strings.NewReader(url.Values{"title": {"New title"}, "tags": {"a, b"}}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	s.handleVideo(recorder, request)
+
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/videos/clip/new-title" {
+		t.Fatalf("status = %d, Location = %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+	video, _ := metadata.Read("clip")
+	want := VideoMetadata{
+		Id: "clip", Slug: "new-title", Title: "New title", Description: "kept", Tags: []string{"a", "b"}, Uploader: "ana", Width: 1280,
+	}
+	if !reflect.DeepEqual(*video, want) {
+		t.Fatalf("metadata = %+v, want %+v", *video, want)
+	}
+
+	missing := httptest.NewRecorder()
+	s.handleVideo(missing, httptest.NewRequest(http.MethodPost, "/videos/unknown", nil))
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("edit of unknown video status = %d, want %d", missing.Code, http.StatusNotFound)
+	}
+}
+
+// interleavingMetadataService runs afterRead once, right after the first
+// Read, to stand in for another writer changing the record in between.
+type interleavingMetadataService struct {
+	*memoryMetadataService
+	afterRead func()
+}
+
+func (service *interleavingMetadataService) Read(id string) (*VideoMetadata, error) {
+	metadata, err := service.memoryMetadataService.Read(id)
+	if service.afterRead != nil {
+		service.afterRead()
+		service.afterRead = nil
+	}
+	return metadata, err
+}
+
+func TestHandleVideoEditDoesNotUndoConcurrentDelete(t *testing.T) {
+	metadata := &interleavingMetadataService{memoryMetadataService: newMemoryMetadataService()}
+	metadata.Create(VideoMetadata{Id: "clip", Title: "Old", Status: VideoReady})
+	metadata.afterRead = func() {
+		metadata.Update(VideoMetadata{Id: "clip", Title: "Old", Status: VideoDeleting})
+	}
+	s := &server{metadataService: metadata}
+
+	request := httptest.NewRequest(http.MethodPost, "/videos/clip",
+		strings.NewReader(url.Values{"title": {"New title"}}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	s.handleVideo(recorder, request)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusConflict)
+	}
+	if video, _ := metadata.Read("clip"); video.Status != VideoDeleting || video.Title != "Old" {
+		t.Fatalf("metadata = %+v, want the record marked as deleting", *video)
+	}
+}
+
+func TestHandleVideoAcceptsIDWithOrWithoutSlug(t *testing.T) {
+	metadata := newMemoryMetadataService()
+	metadata.Create(VideoMetadata{Id: "Xk3_9aQ-2bE", Slug: "lecture-1", Title: "Lecture 1", Status: VideoReady})
+	metadata.Create(VideoMetadata{Id: "old clip", Title: "Uploaded before slugs"})
+	s := &server{metadataService: metadata}
+
+	for path, want := range map[string]int{
+		"/videos/Xk3_9aQ-2bE":           http.StatusOK,
+		"/videos/Xk3_9aQ-2bE/lecture-1": http.StatusOK,
+		"/videos/old%20clip":            http.StatusOK,
+		"/videos/unknown/lecture-1":     http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		s.handleVideo(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, recorder.Code, want)
+		}
+	}
+
+	for path, location := range map[string]string{
+		"/videos/Xk3_9aQ-2bE/old-title": "/videos/Xk3_9aQ-2bE/lecture-1",
+		"/videos/Xk3_9aQ-2bE/a/b":       "/videos/Xk3_9aQ-2bE/lecture-1",
+		"/videos/old%20clip/anything":   "/videos/old%20clip",
+	} {
+		recorder := httptest.NewRecorder()
+		s.handleVideo(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != location {
+			t.Errorf("GET %s = %d to %q, want %d to %q",
+				path, recorder.Code, recorder.Header().Get("Location"), http.StatusFound, location)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for text, want := range map[string]string{
+		"Lecture 1: Consistent Hashing": "lecture-1-consistent-hashing",
+		"  Crème brûlée -- recipe!  ":   "creme-brulee-recipe",
+		"Don't stop":                    "dont-stop",
+		"ﬁle ２":                         "file-2",
+		"日本語":                           "",
+		"../../etc/passwd":              "etc-passwd",
+		strings.Repeat("ab ", 40):       strings.TrimSuffix(strings.Repeat("ab-", 20), "-"),
+	} {
+		if got := slugify(text); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFormatDurationAndSize(t *testing.T) {
+	for seconds, want := range map[float64]string{0: "", 5.4: "0:05", 95: "1:35", 3725: "1:02:05"} {
+		if got := formatDuration(seconds); got != want {
+			t.Fatalf("formatDuration(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+	for size, want := range map[int64]string{0: "", 512: "512 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := formatSize(size); got != want {
+			t.Fatalf("formatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+	"tritontube/internal/proto"
+	"tritontube/internal/storage"
+
+	"google.golang.org/grpc"
+)
+
+// largeFileThreshold is the size above which files move through the streaming
+// RPCs. It keeps a batch of storageBatchSize smaller files well below
+// proto.MaxMessageSize.
+const largeFileThreshold = 2 * 1024 * 1024
+
+// chunkReader exposes a ReadFileStream response as an io.ReadCloser. Close
+// cancels the stream so the storage node stops sending, then runs release if
+// set. size and checksum describe the whole file as reported in the first
+// chunk. A chunk that takes longer than storageCallTimeout to arrive fails
+// the stream.
+type chunkReader struct {
+	stream   grpc.ServerStreamingClient[proto.FileChunk]
+	cancel   context.CancelFunc
+	release  func() error
+	pending  []byte
+	size     int64
+	checksum []byte
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	for len(reader.pending) == 0 {
+		var chunk *proto.FileChunk
+		err := awaitStream(reader.cancel, func() (err error) {
+			chunk, err = reader.stream.Recv()
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		reader.pending = chunk.Data
+	}
+	n := copy(p, reader.pending)
+	reader.pending = reader.pending[n:]
+	return n, nil
+}
+
+func (reader *chunkReader) Close() error {
+	reader.cancel()
+	if reader.release != nil {
+		return reader.release()
+	}
+	return nil
+}
+
+// checksumReader hashes a whole file as it is read. The read that reaches
+// the end returns no data but ErrChecksumMismatch if the hash differs, so a
+// consumer copying a known length never receives the file in full.
+type checksumReader struct {
+	io.ReadCloser
+	hash      hash.Hash
+	expected  []byte
+	remaining int64
+	// name identifies the file and its source in errors.
+	name string
+}
+
+func newChecksumReader(reader io.ReadCloser, size int64, expected []byte, name string) *checksumReader {
+	return &checksumReader{ReadCloser: reader, hash: sha256.New(), expected: expected, remaining: size, name: name}
+}
+
+func (reader *checksumReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	reader.hash.Write(p[:n])
+	reader.remaining -= int64(n)
+	if n > 0 && reader.remaining == 0 && !bytes.Equal(reader.hash.Sum(nil), reader.expected) {
+		return 0, fmt.Errorf("%w in %s", storage.ErrChecksumMismatch, reader.name)
+	}
+	return n, err
+}
+
+// openFileStream starts a ReadFileStream and waits for its first chunk, so a
+// missing file fails here rather than on the first Read.
+func openFileStream(ctx context.Context, client storageRPCClient, request *proto.ReadRangeRequest) (*chunkReader, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.ReadFileStream(streamCtx, request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	var first *proto.FileChunk
+	err = awaitStream(cancel, func() (err error) {
+		first, err = stream.Recv()
+		return err
+	})
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("stream for %s/%s ended without data", request.VideoId, request.Filename)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &chunkReader{
+		stream:   stream,
+		cancel:   cancel,
+		pending:  first.Data,
+		size:     first.Size,
+		checksum: first.Sha256,
+	}, nil
+}
+
+// writeFileStream sends data to a storage node in chunks of at most
+// proto.StreamChunkSize bytes. The first chunk carries checksum, which the
+// node verifies before storing the file. A failure while reading data cancels
+// the stream, which makes the storage node discard the partial file.
+func writeFileStream(ctx context.Context, client storageRPCClient, videoID, filename string, checksum []byte, data io.Reader) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := client.WriteFileStream(streamCtx)
+	if err != nil {
+		return err
+	}
+
+	buffer := make([]byte, proto.StreamChunkSize)
+	chunk := &proto.FileChunk{VideoId: videoID, Filename: filename, Sha256: checksum}
+	for {
+		n, readErr := io.ReadFull(data, buffer)
+		if n > 0 || chunk.VideoId != "" {
+			chunk.Data = buffer[:n]
+			if err := awaitStream(cancel, func() error { return stream.Send(chunk) }); err != nil {
+				if errors.Is(err, io.EOF) {
+					// The node ended the stream; CloseAndRecv reports why.
+					_, err = stream.CloseAndRecv()
+				}
+				return err
+			}
+			chunk = &proto.FileChunk{}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	return awaitStream(cancel, func() error {
+		_, err := stream.CloseAndRecv()
+		return err
+	})
+}
+
+// awaitStream runs one send or receive on a stream and cancels the stream if
+// it does not return within storageCallTimeout, which makes it fail.
+func awaitStream(cancel context.CancelFunc, call func() error) error {
+	timer := time.AfterFunc(storageCallTimeout, cancel)
+	err := call()
+	if !timer.Stop() && err != nil {
+		return fmt.Errorf("storage node did not respond within %v: %w", storageCallTimeout, err)
+	}
+	return err
+}
+
+// copyFileStream streams one file from source to destination without holding
+// it in memory. The source's checksum travels with the file; the source fails
+// the stream if its copy is corrupted and the destination rejects data that
+// does not match, so a bad copy is never stored.
+func copyFileStream(ctx context.Context, source, destination storageRPCClient, entry *proto.FileEntry) error {
+	reader, err := openFileStream(ctx, source, &proto.ReadRangeRequest{
+		VideoId:  entry.VideoId,
+		Filename: entry.Filename,
+		Length:   -1,
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return writeFileStream(ctx, destination, entry.VideoId, entry.Filename, reader.checksum, reader)
+}
@@ -11,19 +11,100 @@ const indexHTML = `
     <h1>Welcome to TritonTube</h1>
     <h2>Upload an MP4 Video</h2>
     <form action="/upload" method="post" enctype="multipart/form-data">
-      <input type="file" name="file" accept="video/mp4" required />
+      <p><input type="file" name="file" accept="video/mp4" required /></p>
+      <p><input type="text" name="title" placeholder="Title" maxlength="200" /></p>
+      <p><textarea name="description" placeholder="Description" maxlength="5000"></textarea></p>
+      <p><input type="text" name="tags" placeholder="Tags, comma separated" /></p>
+      <p><input type="text" name="uploader" placeholder="Your name" maxlength="100" /></p>
       <input type="submit" value="Upload" />
     </form>
+    <p id="jobStatus" hidden></p>
+    <script>
+      // Uploads redirect here with ?job=ID while the video is transcoded in
+      // the background. Poll the job until it is ready or has failed.
+      var jobId = new URLSearchParams(window.location.search).get("job");
+      var jobStatus = document.querySelector("#jobStatus");
+      function pollJob() {
+        fetch("/jobs/" + encodeURIComponent(jobId))
+          .then(function (response) {
+            if (!response.ok) {
+              throw new Error("status " + response.status);
+            }
+            return response.json();
+          })
+          .then(function (job) {
+            var name = job.filename || job.video_id;
+            jobStatus.textContent = "Processing " + name + ": " + job.state;
+            if (job.state === "ready") {
+              window.location.replace("/");
+            } else if (job.state === "failed") {
+              jobStatus.textContent = "Upload of " + name + " failed: " + job.error;
+            } else {
+              setTimeout(pollJob, 2000);
+            }
+          })
+          .catch(function (err) {
+            jobStatus.textContent = "Could not check upload status: " + err.message;
+          });
+      }
+      if (jobId) {
+        jobStatus.hidden = false;
+        jobStatus.textContent = "Upload received, waiting to start...";
+        pollJob();
+      }
+    </script>
     <h2>Watchlist</h2>
     <ul>
       {{range .}}
       <li>
-        <a href="/videos/{{.EscapedId}}">{{.Id}} ({{.UploadTime}})</a>
+        <a href="{{.PageURL}}">
+          {{if .PosterURL}}
+          <img src="{{.PosterURL}}" alt="" height="90"
+            {{if .Thumbnails}}
+            class="preview" data-sprite="{{.ThumbnailsURL}}"
+            data-count="{{.Thumbnails.Count}}" data-columns="{{.Thumbnails.Columns}}"
+            data-width="{{.Thumbnails.Width}}" data-height="{{.Thumbnails.Height}}"
+            {{end}} />
+          {{end}}
+          {{.Title}}{{if .Duration}} [{{.Duration}}]{{end}} ({{.UploadTime}})
+        </a>
+        {{if .Tags}}<small>{{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</small>{{end}}
       </li>
       {{else}}
       <li>No videos uploaded yet.</li>
       {{end}}
     </ul>
+    <script>
+      // Hovering a poster cycles through the video's thumbnail sprite.
+      document.querySelectorAll("img.preview").forEach(function (poster) {
+        var data = poster.dataset;
+        var preview = document.createElement("span");
+        preview.style.display = "none";
+        preview.style.width = data.width + "px";
+        preview.style.height = data.height + "px";
+        preview.style.backgroundImage = "url('" + data.sprite + "')";
+        poster.after(preview);
+
+        var timer = null;
+        poster.parentElement.addEventListener("mouseenter", function () {
+          var frame = 0;
+          poster.style.display = "none";
+          preview.style.display = "inline-block";
+          timer = setInterval(function () {
+            var column = frame % data.columns;
+            var row = Math.floor(frame / data.columns);
+            preview.style.backgroundPosition =
+              -column * data.width + "px " + -row * data.height + "px";
+            frame = (frame + 1) % data.count;
+          }, 400);
+        });
+        poster.parentElement.addEventListener("mouseleave", function () {
+          clearInterval(timer);
+          preview.style.display = "none";
+          poster.style.display = "";
+        });
+      });
+    </script>
   </body>
 </html>
 `
@@ -33,18 +114,64 @@ const videoHTML = `
 <html>
   <head>
     <meta charset="UTF-8" />
-    <title>{{.Id}} - TritonTube</title>
+    <title>{{.Title}} - TritonTube</title>
     <script src="https://cdn.dashjs.org/latest/dash.all.min.js"></script>
   </head>
   <body>
-    <h1>{{.Id}}</h1>
-	  <p>Uploaded at: {{.UploadedAt}}</p>
+    <h1>{{.Title}}</h1>
+	  <p>Uploaded at: {{.UploadedAt}}{{if .Uploader}} by {{.Uploader}}{{end}}</p>
+
+    <video id="dashPlayer" controls style="width: 640px; height: 360px"
+      {{if .PosterURL}}poster="{{.PosterURL}}"{{end}}></video>
+    <p id="playbackError" hidden>This browser cannot play DASH or HLS video.</p>
+    <script>
+      var video = document.querySelector("#dashPlayer");
+      var contentUrl = "/content/{{.Id}}/";
+      // dash.js needs Media Source Extensions. Browsers without them, such
+      // as Safari on older iPhones, play the HLS playlist natively.
+      if (window.dashjs && dashjs.supportsMediaSource()) {
+        var player = dashjs.MediaPlayer().create();
+        player.initialize(video, contentUrl + "manifest.mpd", false);
+      } else if (video.canPlayType("application/vnd.apple.mpegurl")) {
+        video.src = contentUrl + "master.m3u8";
+      } else {
+        document.querySelector("#playbackError").hidden = false;
+      }
+    </script>
+
+    {{if .Description}}<p>{{.Description}}</p>{{end}}
+    <ul>
+      {{if .Tags}}<li>Tags: {{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</li>{{end}}
+      {{if .Duration}}<li>Duration: {{.Duration}}</li>{{end}}
+      {{if .Height}}<li>Source: {{.Width}}x{{.Height}}{{if .VideoCodec}} {{.VideoCodec}}{{end}}{{if .AudioCodec}}, {{.AudioCodec}} audio{{end}}</li>{{end}}
+      {{if .Size}}<li>File size: {{.Size}}</li>{{end}}
+      <li>Status: {{.Status}}</li>
+    </ul>
+
+    <details>
+      <summary>Edit details</summary>
+      <form action="/videos/{{.Id}}" method="post">
+        <p><input type="text" name="title" value="{{.VideoMetadata.Title}}" placeholder="Title" maxlength="200" /></p>
+        <p><textarea name="description" placeholder="Description" maxlength="5000">{{.Description}}</textarea></p>
+        <p><input type="text" name="tags" value="{{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}" placeholder="Tags, comma separated" /></p>
+        <input type="submit" value="Save" />
+      </form>
+    </details>
 
-    <video id="dashPlayer" controls style="width: 640px; height: 360px"></video>
+    <p><button id="deleteVideo" type="button">Delete video</button></p>
     <script>
-      var url = "/content/{{.Id}}/manifest.mpd";
-      var player = dashjs.MediaPlayer().create();
-      player.initialize(document.querySelector("#dashPlayer"), url, false);
+      document.querySelector("#deleteVideo").addEventListener("click", function () {
+        if (!confirm("Delete this video and all of its files?")) {
+          return;
+        }
+        fetch("/videos/{{.Id}}", { method: "DELETE" }).then(function (response) {
+          if (response.ok) {
+            window.location.replace("/");
+          } else {
+            alert("Delete failed; try again.");
+          }
+        });
+      });
     </script>
 
     <p><a href="/">Back to Home</a></p>
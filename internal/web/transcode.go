@@ -0,0 +1,263 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Rendition is one rung of the adaptive bitrate ladder. Every rendition becomes
+// a video Representation in the DASH manifest, scaled to Height lines and
+// encoded at VideoBitrate kbit/s.
+type Rendition struct {
+	Height       int
+	VideoBitrate int
+}
+
+func (rendition Rendition) String() string {
+	return fmt.Sprintf("%dp@%dk", rendition.Height, rendition.VideoBitrate)
+}
+
+// DefaultRenditionLadder lists the renditions produced when the server is not
+// configured with a ladder of its own.
+var DefaultRenditionLadder = []Rendition{
+	{Height: 1080, VideoBitrate: 5000},
+	{Height: 720, VideoBitrate: 3000},
+	{Height: 480, VideoBitrate: 1500},
+	{Height: 240, VideoBitrate: 400},
+}
+
+// ParseRenditionLadder parses a comma-separated list of HEIGHT:KBPS rungs such
+// as "1080:5000,720:3000".
+func ParseRenditionLadder(value string) ([]Rendition, error) {
+	ladder := make([]Rendition, 0)
+	for _, rung := range strings.Split(value, ",") {
+		heightValue, bitrateValue, ok := strings.Cut(strings.TrimSpace(rung), ":")
+		if !ok {
+			return nil, fmt.Errorf("rendition %q must be HEIGHT:KBPS", rung)
+		}
+		height, err := strconv.Atoi(strings.TrimSuffix(heightValue, "p"))
+		if err != nil || height <= 0 || height%2 != 0 {
+			return nil, fmt.Errorf("rendition %q needs a positive, even height", rung)
+		}
+		bitrate, err := strconv.Atoi(strings.TrimSuffix(bitrateValue, "k"))
+		if err != nil || bitrate <= 0 {
+			return nil, fmt.Errorf("rendition %q needs a positive bitrate", rung)
+		}
+		ladder = append(ladder, Rendition{Height: height, VideoBitrate: bitrate})
+	}
+	return ladder, nil
+}
+
+// sourceVideo describes the uploaded file as reported by ffprobe.
+type sourceVideo struct {
+	Width      int
+	Height     int
+	HasAudio   bool
+	VideoCodec string
+	AudioCodec string
+	// Duration is in seconds and is zero when the container does not report
+	// one.
+	Duration float64
+}
+
+// probeVideo reads the resolution and codec of the first video stream, the
+// codec of the first audio stream if there is one, and the duration.
+func probeVideo(path string) (sourceVideo, error) {
+	output, err := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "stream=codec_type,codec_name,width,height:format=duration",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return sourceVideo{}, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+	return parseProbeOutput(output)
+}
+
+func parseProbeOutput(output []byte) (sourceVideo, error) {
+	var probe struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return sourceVideo{}, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+
+	var source sourceVideo
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if source.Height == 0 {
+				source.Width, source.Height = stream.Width, stream.Height
+				source.VideoCodec = stream.CodecName
+			}
+		case "audio":
+			if !source.HasAudio {
+				source.HasAudio = true
+				source.AudioCodec = stream.CodecName
+			}
+		}
+	}
+	if source.Height <= 0 {
+		return sourceVideo{}, fmt.Errorf("no video stream found")
+	}
+	if duration, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil && duration > 0 {
+		source.Duration = duration
+	}
+	return source, nil
+}
+
+// selectRenditions drops rungs taller than the source so videos are never
+// upscaled. A source smaller than every rung is encoded once at its own
+// height with the lowest rung's bitrate.
+func selectRenditions(ladder []Rendition, sourceHeight int) []Rendition {
+	selected := make([]Rendition, 0, len(ladder))
+	lowest := ladder[0]
+	for _, rendition := range ladder {
+		if rendition.Height <= sourceHeight {
+			selected = append(selected, rendition)
+		}
+		if rendition.Height < lowest.Height {
+			lowest = rendition
+		}
+	}
+	if len(selected) == 0 {
+		// libx264 requires even dimensions, so the smallest height is 2.
+		selected = append(selected, Rendition{Height: max(sourceHeight&^1, 2), VideoBitrate: lowest.VideoBitrate})
+	}
+	return selected
+}
+
+// hlsMasterPlaylist is written next to manifest.mpd for players without
+// Media Source Extensions.
+const hlsMasterPlaylist = "master.m3u8"
+
+// dashArgs builds the ffmpeg arguments that encode every rendition into one
+// DASH manifest. Video renditions share one adaptation set so players can
+// switch between them; audio is encoded once in its own set. The muxer also
+// writes an HLS master playlist and one media playlist per stream that point
+// at the same CMAF segments, so no content is stored twice.
+func dashArgs(videoPath, manifestPath string, renditions []Rendition, hasAudio bool) []string {
+	args := []string{"-i", videoPath} // input file
+	for range renditions {
+		args = append(args, "-map", "0:v:0")
+	}
+	if hasAudio {
+		args = append(args, "-map", "0:a:0")
+	}
+
+	args = append(args,
+		"-c:v", "libx264", // video codec
+		"-preset", "veryfast", // faster software encoding
+		"-threads", "2", // limit CPU usage on local machines
+		"-bf", "1", // max 1 B-frame
+		"-keyint_min", "120", // minimum keyframe interval
+		"-g", "120", // keyframe every 120 frames
+		"-sc_threshold", "0", // scene change threshold
+	)
+	for index, rendition := range renditions {
+		stream := strconv.Itoa(index)
+		bitrate := strconv.Itoa(rendition.VideoBitrate) + "k"
+		args = append(args,
+			"-filter:v:"+stream, fmt.Sprintf("scale=-2:%d", rendition.Height), // keep aspect ratio, even width
+			"-b:v:"+stream, bitrate, // video bitrate
+			"-maxrate:v:"+stream, bitrate, // cap peaks for the advertised bandwidth
+			"-bufsize:v:"+stream, strconv.Itoa(rendition.VideoBitrate*2)+"k",
+		)
+	}
+
+	adaptationSets := "id=0,streams=v"
+	if hasAudio {
+		args = append(args,
+			"-c:a", "aac", // audio codec
+			"-b:a", "128k", // audio bitrate
+		)
+		adaptationSets += " id=1,streams=a"
+	}
+
+	return append(args,
+		"-f", "dash", // DASH format
+		"-use_timeline", "1", // use timeline
+		"-use_template", "1", // use template
+		"-adaptation_sets", adaptationSets, // one switchable video set
+		"-init_seg_name", "init-$RepresentationID$.m4s", // init segment naming
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s", // media segment naming
+		"-seg_duration", "4", // segment duration in seconds
+		"-hls_playlist", "1", // HLS playlists over the same fMP4 segments
+		"-hls_master_name", hlsMasterPlaylist, // HLS master playlist naming
+		manifestPath, // output manifest file path
+	)
+}
+
+const (
+	posterFilename          = "poster.jpg"
+	thumbnailSpriteFilename = "thumbnails.jpg"
+
+	posterHeight     = 480
+	thumbnailWidth   = 160
+	thumbnailColumns = 10
+	// maxThumbnails keeps the sprite sheet to a single image; long videos
+	// get a wider interval instead of more tiles.
+	maxThumbnails        = 100
+	minThumbnailInterval = 10.0
+)
+
+// posterArgs builds the ffmpeg arguments for the poster image. The thumbnail
+// filter picks a representative frame from the opening seconds, which avoids
+// black fade-in frames.
+func posterArgs(videoPath, posterPath string) []string {
+	return []string{
+		"-i", videoPath, // input file
+		"-vf", fmt.Sprintf("thumbnail,scale=-2:%d", posterHeight), // representative frame
+		"-frames:v", "1", // a single image
+		"-q:v", "3", // JPEG quality
+		"-y", posterPath, // output image path
+	}
+}
+
+// planThumbnailSprite lays out a sprite sheet that covers the whole video
+// with at most maxThumbnails tiles. It reports false when the duration is
+// unknown.
+func planThumbnailSprite(source sourceVideo) (ThumbnailSprite, bool) {
+	if source.Duration <= 0 || source.Width <= 0 {
+		return ThumbnailSprite{}, false
+	}
+
+	interval := max(minThumbnailInterval, math.Ceil(source.Duration/maxThumbnails))
+	count := max(int(math.Ceil(source.Duration/interval)), 1)
+	height := thumbnailWidth * source.Height / source.Width
+	return ThumbnailSprite{
+		Filename: thumbnailSpriteFilename,
+		Interval: interval,
+		Count:    count,
+		Columns:  min(count, thumbnailColumns),
+		Width:    thumbnailWidth,
+		Height:   max(height&^1, 2),
+	}, true
+}
+
+// spriteArgs builds the ffmpeg arguments that sample one frame every
+// sprite.Interval seconds and tile the frames into one image.
+func spriteArgs(videoPath, spritePath string, sprite ThumbnailSprite) []string {
+	rows := (sprite.Count + sprite.Columns - 1) / sprite.Columns
+	return []string{
+		"-i", videoPath, // input file
+		"-vf", fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d",
+			sprite.Interval, sprite.Width, sprite.Height, sprite.Columns, rows), // periodic frames in a grid
+		"-frames:v", "1", // a single sheet
+		"-q:v", "5", // JPEG quality
+		"-y", spritePath, // output image path
+	}
+}
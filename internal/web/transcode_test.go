@@ -0,0 +1,141 @@
+package web
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRenditionLadder(t *testing.T) {
+	ladder, err := ParseRenditionLadder("1080p:5000k, 720:3000,240:400")
+	if err != nil {
+		t.Fatalf("ParseRenditionLadder failed: %v", err)
+	}
+	want := []Rendition{
+		{Height: 1080, VideoBitrate: 5000},
+		{Height: 720, VideoBitrate: 3000},
+		{Height: 240, VideoBitrate: 400},
+	}
+	if !reflect.DeepEqual(ladder, want) {
+		t.Fatalf("ladder = %v, want %v", ladder, want)
+	}
+
+	for _, invalid := range []string{"", "720", "721:3000", "720:0", "abc:3000", "720:fast"} {
+		if _, err := ParseRenditionLadder(invalid); err == nil {
+			t.Fatalf("ParseRenditionLadder(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestSelectRenditionsSkipsRungsAboveSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		sourceHeight int
+		want         []Rendition
+	}{
+		{name: "full ladder", sourceHeight: 2160, want: DefaultRenditionLadder},
+		{name: "720p source", sourceHeight: 720, want: DefaultRenditionLadder[1:]},
+		{name: "between rungs", sourceHeight: 600, want: DefaultRenditionLadder[2:]},
+		{name: "below every rung", sourceHeight: 181, want: []Rendition{{Height: 180, VideoBitrate: 400}}},
+		{name: "one pixel high", sourceHeight: 1, want: []Rendition{{Height: 2, VideoBitrate: 400}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := selectRenditions(DefaultRenditionLadder, test.sourceHeight)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("selectRenditions = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDashArgsEncodesEveryRenditionIntoOneManifest(t *testing.T) {
+	renditions := []Rendition{{Height: 720, VideoBitrate: 3000}, {Height: 240, VideoBitrate: 400}}
+	args := dashArgs("in.mp4", "out/manifest.mpd", renditions, true)
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"-map 0:v:0 -map 0:v:0 -map 0:a:0",
+		"-filter:v:0 scale=-2:720 -b:v:0 3000k",
+		"-filter:v:1 scale=-2:240 -b:v:1 400k",
+		"-c:a aac",
+		"id=0,streams=v id=1,streams=a",
+		"-hls_playlist 1 -hls_master_name master.m3u8",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("ffmpeg args %q do not contain %q", joined, want)
+		}
+	}
+	if args[len(args)-1] != "out/manifest.mpd" {
+		t.Fatalf("last argument = %q, want the manifest path", args[len(args)-1])
+	}
+
+	silent := dashArgs("in.mp4", "out/manifest.mpd", renditions, false)
+	if slices.Contains(silent, "0:a:0") || slices.Contains(silent, "-c:a") {
+		t.Fatalf("ffmpeg args for a silent source map audio: %v", silent)
+	}
+	if !slices.Contains(silent, "id=0,streams=v") {
+		t.Fatalf("ffmpeg args for a silent source lack the video adaptation set: %v", silent)
+	}
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	source, err := parseProbeOutput([]byte(`{"streams":[
+		{"codec_type":"audio","codec_name":"aac"},
+		{"codec_type":"video","codec_name":"h264","width":1280,"height":720},
+		{"codec_type":"video","codec_name":"mjpeg","width":320,"height":180}
+	],"format":{"duration":"63.500000"}}`))
+	if err != nil {
+		t.Fatalf("parseProbeOutput failed: %v", err)
+	}
+	want := sourceVideo{
+		Width: 1280, Height: 720, HasAudio: true, VideoCodec: "h264", AudioCodec: "aac", Duration: 63.5,
+	}
+	if source != want {
+		t.Fatalf("source = %+v", source)
+	}
+
+	if _, err := parseProbeOutput([]byte(`{"streams":[{"codec_type":"audio"}]}`)); err == nil {
+		t.Fatal("parseProbeOutput expected an error without a video stream")
+	}
+}
+
+func TestPlanThumbnailSpriteCoversWholeVideo(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		interval float64
+		count    int
+		columns  int
+	}{
+		{name: "short", duration: 25, interval: 10, count: 3, columns: 3},
+		{name: "medium", duration: 600, interval: 10, count: 60, columns: 10},
+		{name: "long", duration: 3600, interval: 36, count: 100, columns: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sprite, ok := planThumbnailSprite(sourceVideo{Width: 1920, Height: 1080, Duration: test.duration})
+			if !ok {
+				t.Fatal("planThumbnailSprite reported no sprite")
+			}
+			if sprite.Interval != test.interval || sprite.Count != test.count || sprite.Columns != test.columns {
+				t.Fatalf("sprite = %+v, want interval %v count %d columns %d",
+					sprite, test.interval, test.count, test.columns)
+			}
+			if sprite.Width != thumbnailWidth || sprite.Height != 90 {
+				t.Fatalf("tile = %dx%d, want %dx90", sprite.Width, sprite.Height, thumbnailWidth)
+			}
+		})
+	}
+
+	if _, ok := planThumbnailSprite(sourceVideo{Width: 1920, Height: 1080}); ok {
+		t.Fatal("planThumbnailSprite planned a sprite without a duration")
+	}
+
+	sprite, _ := planThumbnailSprite(sourceVideo{Width: 1920, Height: 1080, Duration: 600})
+	args := strings.Join(spriteArgs("in.mp4", "out/thumbnails.jpg", sprite), " ")
+	if !strings.Contains(args, "fps=1/10,scale=160:90,tile=10x6") {
+		t.Fatalf("sprite args = %q", args)
+	}
+}